@@ -0,0 +1,187 @@
+// Package book implements Polyglot opening books.
+//
+// A Polyglot book is a sequence of 16-byte entries, sorted by key. Each entry
+// holds, in big-endian order, the Polyglot hash of a position, a move, the
+// move's weight and a learning value that is unused here.
+package book
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"slices"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/movegen"
+)
+
+// entrySize is the size of an encoded entry, in bytes.
+const entrySize = 16
+
+// An Entry is a book entry.
+type Entry struct {
+	Key    uint64 // Polyglot hash of the position.
+	Move   uint16 // Move in Polyglot encoding.
+	Weight uint16
+	Learn  uint32
+}
+
+// A Book is a Polyglot opening book.
+type Book struct {
+	entries []Entry // Sorted by key, then by descending weight.
+}
+
+// New returns a book with the given entries.
+func New(entries []Entry) *Book {
+	entries = slices.Clone(entries)
+	slices.SortStableFunc(entries, compareEntries)
+	return &Book{entries: entries}
+}
+
+// compareEntries orders entries by key, then by descending weight.
+func compareEntries(a, b Entry) int {
+	if c := cmp.Compare(a.Key, b.Key); c != 0 {
+		return c
+	}
+	return cmp.Compare(b.Weight, a.Weight)
+}
+
+// Read reads a book.
+func Read(r io.Reader) (*Book, error) {
+	var (
+		entries []Entry
+		buf     [entrySize]byte
+		br      = bufio.NewReader(r)
+	)
+	for {
+		_, err := io.ReadFull(br, buf[:])
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("truncated entry")
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			Key:    binary.BigEndian.Uint64(buf[0:8]),
+			Move:   binary.BigEndian.Uint16(buf[8:10]),
+			Weight: binary.BigEndian.Uint16(buf[10:12]),
+			Learn:  binary.BigEndian.Uint32(buf[12:16]),
+		})
+	}
+
+	// Books should already be sorted, but don't rely on it.
+	if !slices.IsSortedFunc(entries, compareEntries) {
+		slices.SortStableFunc(entries, compareEntries)
+	}
+	return &Book{entries: entries}, nil
+}
+
+// Open reads a book from a file.
+func Open(name string) (*Book, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return b, nil
+}
+
+// WriteTo writes the book to w.
+func (b *Book) WriteTo(w io.Writer) (int64, error) {
+	var (
+		bw  = bufio.NewWriter(w)
+		buf [entrySize]byte
+		n   int64
+	)
+	for _, e := range b.entries {
+		binary.BigEndian.PutUint64(buf[0:8], e.Key)
+		binary.BigEndian.PutUint16(buf[8:10], e.Move)
+		binary.BigEndian.PutUint16(buf[10:12], e.Weight)
+		binary.BigEndian.PutUint32(buf[12:16], e.Learn)
+		m, err := bw.Write(buf[:])
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
+
+// Len returns the number of entries in the book.
+func (b *Book) Len() int {
+	return len(b.entries)
+}
+
+// Entries returns the entries for a position, with the highest weights first.
+func (b *Book) Entries(p core.Position) []Entry {
+	key := p.PolyglotKey()
+	i, _ := slices.BinarySearchFunc(b.entries, key, func(e Entry, key uint64) int {
+		return cmp.Compare(e.Key, key)
+	})
+	j := i
+	for j < len(b.entries) && b.entries[j].Key == key {
+		j++
+	}
+	return slices.Clone(b.entries[i:j])
+}
+
+// A WeightedMove is a book move and its weight.
+type WeightedMove struct {
+	Move   core.Move
+	Weight int
+}
+
+// Moves returns the legal book moves for a position, with the highest
+// weights first. Entries with illegal moves, which can appear in corrupt
+// books or through hash collisions, are skipped.
+func (b *Book) Moves(p core.Position) []WeightedMove {
+	var (
+		moves []WeightedMove
+		legal = movegen.LegalMoves(p)
+	)
+	for _, e := range b.Entries(p) {
+		m := DecodeMove(p, e.Move)
+		if slices.Contains(legal, m) {
+			moves = append(moves, WeightedMove{Move: m, Weight: int(e.Weight)})
+		}
+	}
+	return moves
+}
+
+// Pick picks a book move for a position, at random in proportion to the
+// moves' weights. It returns false if the book has no moves with a positive
+// weight for the position.
+func (b *Book) Pick(p core.Position, r *rand.Rand) (core.Move, bool) {
+	var (
+		moves = b.Moves(p)
+		total int
+	)
+	for _, m := range moves {
+		total += m.Weight
+	}
+	if total == 0 {
+		return core.Move{}, false
+	}
+
+	n := r.IntN(total)
+	for _, m := range moves {
+		if n < m.Weight {
+			return m.Move, true
+		}
+		n -= m.Weight
+	}
+	panic("unreachable")
+}
@@ -0,0 +1,180 @@
+package book
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/movegen"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecodeMove(t *testing.T) {
+	cases := []struct {
+		in   string
+		v    uint16
+		want string
+	}{
+		{fen.Starting, 0x031c, "e2e4"},
+		{fen.Starting, 0x0195, "g1f3"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", 0x0107, "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", 0x0100, "e1c1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", 0x0f3f, "e8g8"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", 0x0f38, "e8c8"},
+		// A rook on e1 moving to h1 isn't castling.
+		{"4k3/8/8/8/8/8/8/4R1K1 w - - 0 1", 0x0107, "e1h1"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", 0x4c79, "b7b8q"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", 0x1c79, "b7b8n"},
+	}
+
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.in)
+		if got := pcn.Encode(DecodeMove(p, tc.v)); got != tc.want {
+			t.Errorf("%q, %#04x: got %s, want %s", tc.in, tc.v, got, tc.want)
+		}
+	}
+}
+
+func TestEncodeMove_RoundTrip(t *testing.T) {
+	for _, in := range []string{
+		fen.Starting,
+		"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+		"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1",
+		"r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1",
+	} {
+		p := fen.MustDecode(in)
+		for _, m := range movegen.LegalMoves(p) {
+			if got := DecodeMove(p, EncodeMove(p, m)); got != m {
+				t.Errorf("%q: %s round-tripped to %s", in, pcn.Encode(m), pcn.Encode(got))
+			}
+		}
+	}
+}
+
+// testBook returns a book for the starting position and the position after
+// 1. e4.
+func testBook() *Book {
+	p := core.NewPosition()
+	q := p
+	q.Make(pcn.MustDecode("e2e4"))
+
+	return New([]Entry{
+		{Key: q.PolyglotKey(), Move: EncodeMove(q, pcn.MustDecode("c7c5")), Weight: 5},
+		{Key: p.PolyglotKey(), Move: EncodeMove(p, pcn.MustDecode("d2d4")), Weight: 1},
+		{Key: p.PolyglotKey(), Move: EncodeMove(p, pcn.MustDecode("e2e4")), Weight: 3},
+		{Key: p.PolyglotKey(), Move: EncodeMove(p, pcn.MustDecode("a2a3")), Weight: 0},
+		{Key: p.PolyglotKey(), Move: EncodeMove(p, pcn.MustDecode("e2e5")), Weight: 100}, // Illegal.
+	})
+}
+
+func TestBook_Moves(t *testing.T) {
+	want := []WeightedMove{
+		{pcn.MustDecode("e2e4"), 3},
+		{pcn.MustDecode("d2d4"), 1},
+		{pcn.MustDecode("a2a3"), 0},
+	}
+	if diff := cmp.Diff(want, testBook().Moves(core.NewPosition())); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := testBook().Moves(fen.MustDecode("4k3/8/8/8/8/8/8/4K3 w - - 0 1")); len(got) != 0 {
+		t.Errorf("got %d moves for a position not in the book", len(got))
+	}
+}
+
+func TestBook_Pick(t *testing.T) {
+	var (
+		b      = testBook()
+		p      = core.NewPosition()
+		r      = rand.New(rand.NewPCG(1, 2))
+		counts = make(map[string]int)
+	)
+	for range 4000 {
+		m, ok := b.Pick(p, r)
+		if !ok {
+			t.Fatal("Pick() found no move")
+		}
+		counts[pcn.Encode(m)]++
+	}
+
+	// e2e4 should be picked about three times as often as d2d4, and a2a3
+	// never.
+	if counts["a2a3"] != 0 || counts["e2e5"] != 0 {
+		t.Errorf("picked moves with no weight: %v", counts)
+	}
+	if ratio := float64(counts["e2e4"]) / float64(counts["d2d4"]); ratio < 2.5 || ratio > 3.5 {
+		t.Errorf("got ratio %.2f, want about 3: %v", ratio, counts)
+	}
+
+	if _, ok := b.Pick(fen.MustDecode("4k3/8/8/8/8/8/8/4K3 w - - 0 1"), r); ok {
+		t.Error("Pick() found a move for a position not in the book")
+	}
+}
+
+func TestBook_PickSeeded(t *testing.T) {
+	pick := func(seed uint64) []core.Move {
+		var (
+			b     = testBook()
+			r     = rand.New(rand.NewPCG(seed, 0))
+			moves []core.Move
+		)
+		for range 20 {
+			m, _ := b.Pick(core.NewPosition(), r)
+			moves = append(moves, m)
+		}
+		return moves
+	}
+
+	if diff := cmp.Diff(pick(7), pick(7)); diff != "" {
+		t.Errorf("same seed, different picks (-first +second):\n%s", diff)
+	}
+}
+
+func TestRead(t *testing.T) {
+	want := testBook()
+
+	var buf bytes.Buffer
+	n, err := want.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo() error: %v", err)
+	}
+	if n != int64(want.Len()*entrySize) {
+		t.Errorf("wrote %d bytes, want %d", n, want.Len()*entrySize)
+	}
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+	if diff := cmp.Diff(want.entries, got.entries); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRead_Encoding(t *testing.T) {
+	// A single entry for 1. e4 from the starting position.
+	in := []byte{
+		0x46, 0x3b, 0x96, 0x18, 0x16, 0x91, 0xfc, 0x9c,
+		0x03, 0x1c,
+		0x00, 0x0a,
+		0x00, 0x00, 0x00, 0x01,
+	}
+	b, err := Read(bytes.NewReader(in))
+	if err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+
+	want := []Entry{{Key: 0x463b96181691fc9c, Move: 0x031c, Weight: 10, Learn: 1}}
+	if diff := cmp.Diff(want, b.Entries(core.NewPosition())); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRead_Truncated(t *testing.T) {
+	if _, err := Read(bytes.NewReader(make([]byte, entrySize+3))); err == nil {
+		t.Error("got no error for a truncated book")
+	}
+}
@@ -0,0 +1,138 @@
+package book
+
+import (
+	"cmp"
+	"io"
+	"math"
+	"slices"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/pgn"
+)
+
+// DefaultMaxPly is the default number of plies from each game added to a book.
+const DefaultMaxPly = 30
+
+// Points awarded to a move for the result of the game, from the point of view
+// of the side that played it.
+const (
+	winPoints  = 2
+	drawPoints = 1
+)
+
+// A Builder builds a book from games. Each move played in a game is weighted
+// by the game's result for the side that played it: 2 points for a win, 1 for
+// a draw and none for a loss. Games without a result are skipped.
+//
+// The zero value is ready to use.
+type Builder struct {
+	// MaxPly is the number of plies from the start of each game to add. If
+	// zero, DefaultMaxPly is used.
+	MaxPly int
+
+	// MinGames is the minimum number of games a move must appear in to be
+	// added to the book.
+	MinGames int
+
+	stats map[bookMove]*tally
+}
+
+type bookMove struct {
+	key  uint64
+	move uint16
+}
+
+type tally struct {
+	games  int
+	points int
+}
+
+// Add adds a game.
+func (b *Builder) Add(g *pgn.Game) {
+	var white int
+	switch g.Result {
+	case pgn.WhiteWins:
+		white = winPoints
+	case pgn.Draw:
+		white = drawPoints
+	case pgn.BlackWins:
+		white = 0
+	default:
+		return
+	}
+
+	if b.stats == nil {
+		b.stats = make(map[bookMove]*tally)
+	}
+
+	maxPly := b.MaxPly
+	if maxPly == 0 {
+		maxPly = DefaultMaxPly
+	}
+
+	p := g.Start
+	for _, m := range g.Moves[:min(maxPly, len(g.Moves))] {
+		points := white
+		if p.SideToMove == core.Black {
+			points = winPoints - white
+		}
+
+		k := bookMove{key: p.PolyglotKey(), move: EncodeMove(p, m)}
+		t, ok := b.stats[k]
+		if !ok {
+			t = new(tally)
+			b.stats[k] = t
+		}
+		t.games++
+		t.points += points
+
+		p.Make(m)
+	}
+}
+
+// AddPGN adds every game in a PGN collection.
+func (b *Builder) AddPGN(r io.Reader) error {
+	d := pgn.NewDecoder(r)
+	for {
+		g, err := d.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		b.Add(g)
+	}
+}
+
+// Book returns a book with the moves added so far. Moves with no points are
+// left out, and weights are scaled down if needed to fit in 16 bits.
+func (b *Builder) Book() *Book {
+	var maxPoints int
+	for _, t := range b.stats {
+		maxPoints = max(maxPoints, t.points)
+	}
+
+	scale := 1.0
+	if maxPoints > math.MaxUint16 {
+		scale = math.MaxUint16 / float64(maxPoints)
+	}
+
+	var entries []Entry
+	for k, t := range b.stats {
+		if t.games < b.MinGames {
+			continue
+		}
+		w := uint16(float64(t.points) * scale)
+		if w == 0 {
+			continue
+		}
+		entries = append(entries, Entry{Key: k.key, Move: k.move, Weight: w})
+	}
+
+	// Break ties between equal weights by move, so that builds are
+	// reproducible.
+	slices.SortFunc(entries, func(a, b Entry) int { return cmp.Compare(a.Move, b.Move) })
+
+	return New(entries)
+}
@@ -0,0 +1,87 @@
+package book
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/encoding/pgn"
+	"github.com/google/go-cmp/cmp"
+)
+
+const collection = `
+[Result "1-0"]
+1. e4 e5 2. Nf3 1-0
+
+[Result "1/2-1/2"]
+1. e4 c5 1/2-1/2
+
+[Result "0-1"]
+1. d4 d5 0-1
+
+[Result "*"]
+1. c4 *
+`
+
+func TestBuilder(t *testing.T) {
+	var b Builder
+	if err := b.AddPGN(strings.NewReader(collection)); err != nil {
+		t.Fatalf("AddPGN() error: %v", err)
+	}
+	bk := b.Book()
+
+	cases := []struct {
+		moves []string
+		want  []WeightedMove
+	}{
+		// e4 scored a win and a draw, and d4 a loss. The unfinished game is
+		// skipped.
+		{nil, []WeightedMove{{pcn.MustDecode("e2e4"), 3}}},
+		{[]string{"e2e4"}, []WeightedMove{{pcn.MustDecode("c7c5"), 1}}},
+		{[]string{"d2d4"}, []WeightedMove{{pcn.MustDecode("d7d5"), 2}}},
+		{[]string{"e2e4", "e7e5"}, []WeightedMove{{pcn.MustDecode("g1f3"), 2}}},
+	}
+
+	for _, tc := range cases {
+		p := core.NewPosition()
+		for _, m := range tc.moves {
+			p.Make(pcn.MustDecode(m))
+		}
+		if diff := cmp.Diff(tc.want, bk.Moves(p)); diff != "" {
+			t.Errorf("%v: mismatch (-want +got):\n%s", tc.moves, diff)
+		}
+	}
+}
+
+func TestBuilder_Limits(t *testing.T) {
+	b := Builder{MaxPly: 1, MinGames: 2}
+	if err := b.AddPGN(strings.NewReader(collection)); err != nil {
+		t.Fatalf("AddPGN() error: %v", err)
+	}
+
+	// Only e4 appears in two games, and only the first ply is added.
+	if got := b.Book().Len(); got != 1 {
+		t.Errorf("got %d entries, want 1", got)
+	}
+}
+
+func TestBuilder_Scale(t *testing.T) {
+	var (
+		b  Builder
+		e4 = &pgn.Game{Start: core.NewPosition(), Moves: []core.Move{pcn.MustDecode("e2e4")}, Result: pgn.WhiteWins}
+		d4 = &pgn.Game{Start: core.NewPosition(), Moves: []core.Move{pcn.MustDecode("d2d4")}, Result: pgn.Draw}
+	)
+	for range 40000 {
+		b.Add(e4)
+		b.Add(d4)
+	}
+
+	want := []WeightedMove{
+		{pcn.MustDecode("e2e4"), 65535},
+		{pcn.MustDecode("d2d4"), 32767},
+	}
+	if diff := cmp.Diff(want, b.Book().Moves(core.NewPosition())); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
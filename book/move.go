@@ -0,0 +1,70 @@
+package book
+
+import "github.com/clfs/simple/core"
+
+// Polyglot moves are 16-bit values holding, from the least significant bit,
+// the destination file and rank, the origin file and rank, and the promotion
+// piece type, in 3 bits each. Castling is encoded as the king capturing its
+// own rook.
+
+var decodePromotion = [8]core.PieceType{0, core.Knight, core.Bishop, core.Rook, core.Queen}
+
+var encodePromotion = map[core.PieceType]uint16{
+	core.Knight: 1,
+	core.Bishop: 2,
+	core.Rook:   3,
+	core.Queen:  4,
+}
+
+// Castling moves as the king's squares and in Polyglot encoding. Chess960
+// castling moves are already the king capturing its own rook.
+var castlingMoves = []struct {
+	king     core.Piece
+	from, to core.Square // King's destination.
+	rook     core.Square // Polyglot destination.
+}{
+	{core.WhiteKing, core.E1, core.G1, core.H1},
+	{core.WhiteKing, core.E1, core.C1, core.A1},
+	{core.BlackKing, core.E8, core.G8, core.H8},
+	{core.BlackKing, core.E8, core.C8, core.A8},
+}
+
+// DecodeMove decodes a move in Polyglot encoding for a position.
+func DecodeMove(p core.Position, v uint16) core.Move {
+	m := core.Move{
+		From:      square(v >> 6),
+		To:        square(v),
+		Promotion: decodePromotion[v>>12&7],
+	}
+
+	piece, _ := p.Board.Get(m.From)
+	for _, c := range castlingMoves {
+		if !p.Chess960 && piece == c.king && m.From == c.from && m.To == c.rook {
+			m.To = c.to
+		}
+	}
+	return m
+}
+
+// EncodeMove encodes a legal move in a position in Polyglot encoding.
+func EncodeMove(p core.Position, m core.Move) uint16 {
+	to := m.To
+
+	piece, _ := p.Board.Get(m.From)
+	for _, c := range castlingMoves {
+		if !p.Chess960 && piece == c.king && m.From == c.from && m.To == c.to {
+			to = c.rook
+		}
+	}
+
+	return encodePromotion[m.Promotion]<<12 | encodeSquare(m.From)<<6 | encodeSquare(to)
+}
+
+// square decodes the square in the low 6 bits of v.
+func square(v uint16) core.Square {
+	return core.NewSquare(core.File(v&7), core.Rank(v>>3&7))
+}
+
+func encodeSquare(s core.Square) uint16 {
+	return uint16(s.Rank())<<3 | uint16(s.File())
+}
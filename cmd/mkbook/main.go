@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/clfs/simple/book"
+)
+
+var (
+	outFlag      = flag.String("o", "book.bin", "output file")
+	maxPlyFlag   = flag.Int("maxply", book.DefaultMaxPly, "plies to add from each game")
+	minGamesFlag = flag.Int("mingames", 1, "minimum number of games per move")
+)
+
+func main() {
+	log.SetFlags(0)
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatal("error: no PGN files given")
+	}
+	if *maxPlyFlag < 1 {
+		log.Fatal("error: -maxply must be at least 1")
+	}
+
+	if err := run(flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(names []string) error {
+	b := book.Builder{MaxPly: *maxPlyFlag, MinGames: *minGamesFlag}
+
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = b.AddPGN(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
+	bk := b.Book()
+
+	f, err := os.Create(*outFlag)
+	if err != nil {
+		return err
+	}
+	if _, err := bk.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %d entries to %s\n", bk.Len(), *outFlag)
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/encoding/pgn"
+	"github.com/clfs/simple/render"
+	"github.com/clfs/simple/render/svg"
+)
+
+var (
+	outFlag    = flag.String("o", "board.svg", "output file")
+	fenFlag    = flag.String("fen", fen.Starting, "position")
+	pgnFlag    = flag.String("pgn", "", "PGN file to take the position from, instead of -fen")
+	gameFlag   = flag.Int("game", 1, "game in the PGN file")
+	plyFlag    = flag.Int("ply", -1, "plies to play from the start of the game, or -1 for all")
+	sizeFlag   = flag.Int("size", 360, "image size")
+	coordsFlag = flag.Bool("coords", true, "label ranks and files")
+	flipFlag   = flag.Bool("flip", false, "draw the board from black's side")
+	arrowsFlag = flag.String("arrows", "", "comma-separated arrows in PCN, like e2e4,g1f3")
+)
+
+func main() {
+	log.SetFlags(0)
+	flag.Parse()
+
+	if *gameFlag < 1 {
+		log.Fatal("error: -game must be at least 1")
+	}
+	if *sizeFlag < 1 {
+		log.Fatal("error: -size must be at least 1")
+	}
+
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	opts := svg.Options{
+		Size:        *sizeFlag,
+		Coordinates: *coordsFlag,
+		Flip:        *flipFlag,
+	}
+
+	var p core.Position
+	if *pgnFlag == "" {
+		var err error
+		p, err = fen.DecodeStrict(*fenFlag)
+		if err != nil {
+			return fmt.Errorf("invalid FEN: %v", err)
+		}
+	} else {
+		var (
+			last core.Move
+			err  error
+		)
+		p, last, err = fromPGN(*pgnFlag, *gameFlag, *plyFlag)
+		if err != nil {
+			return err
+		}
+		// Highlight the last move, if there is one.
+		if last != (core.Move{}) {
+			opts.Highlight = render.Move(last)
+		}
+	}
+
+	if *arrowsFlag != "" {
+		for _, s := range strings.Split(*arrowsFlag, ",") {
+			m, err := pcn.Decode(s)
+			if err != nil || m.Drop {
+				return fmt.Errorf("invalid arrow: %s", s)
+			}
+			opts.Arrows = append(opts.Arrows, svg.Arrow{From: m.From, To: m.To})
+		}
+	}
+
+	if err := os.WriteFile(*outFlag, []byte(svg.Render(p, opts)), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", *outFlag)
+	return nil
+}
+
+// fromPGN returns the position after some plies of the nth game in a PGN
+// file, and the last move played, if any.
+func fromPGN(name string, n, ply int) (core.Position, core.Move, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return core.Position{}, core.Move{}, err
+	}
+	defer f.Close()
+
+	games, err := pgn.DecodeAll(f)
+	if err != nil {
+		return core.Position{}, core.Move{}, fmt.Errorf("%s: %v", name, err)
+	}
+	if n > len(games) {
+		return core.Position{}, core.Move{}, fmt.Errorf("%s: no game %d, only %d", name, n, len(games))
+	}
+
+	g := games[n-1]
+	if ply < 0 || ply > len(g.Moves) {
+		ply = len(g.Moves)
+	}
+	p := g.Start
+	var last core.Move
+	for _, m := range g.Moves[:ply] {
+		p.Make(m)
+		last = m
+	}
+	return p, last, nil
+}
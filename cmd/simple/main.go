@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/clfs/simple/uci"
+	"github.com/clfs/simple/xboard"
+)
+
+var protocolFlag = flag.String("protocol", "uci", "protocol to speak, uci or xboard")
+
+func main() {
+	log.SetFlags(0)
+	flag.Parse()
+
+	run := uci.Run
+	switch *protocolFlag {
+	case "uci":
+	case "xboard":
+		run = xboard.Run
+	default:
+		log.Fatalf("error: unknown protocol %q", *protocolFlag)
+	}
+
+	if err := run(os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
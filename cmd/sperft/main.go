@@ -32,7 +32,7 @@ func main() {
 }
 
 func run(w io.Writer) error {
-	p, err := fen.Decode(*fenFlag)
+	p, err := fen.DecodeStrict(*fenFlag)
 	if err != nil {
 		return fmt.Errorf("invalid FEN: %v", err)
 	}
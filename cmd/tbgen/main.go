@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/clfs/simple/dtm"
+)
+
+var dirFlag = flag.String("dir", ".", "output directory")
+
+func main() {
+	log.SetFlags(0)
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatal("error: no materials given")
+	}
+
+	if err := run(flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(materials []string) error {
+	for _, material := range materials {
+		t, err := dtm.Generate(material)
+		if err != nil {
+			return fmt.Errorf("%s: %v", material, err)
+		}
+
+		name := filepath.Join(*dirFlag, t.Material()+".dtm")
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := t.WriteTo(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+
+		fmt.Printf("wrote %s, longest mate %d plies\n", name, t.Longest())
+	}
+	return nil
+}
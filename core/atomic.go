@@ -0,0 +1,38 @@
+package core
+
+// explode removes the capturing piece on a square after a capture in Atomic,
+// along with every piece other than a pawn next to it. Castling rights are
+// lost if the king or castling rook explodes.
+func (p *Position) explode(s Square) {
+	p.Board.Clear(s)
+	for _, d := range kingSteps {
+		t, ok := offset(s, d)
+		if !ok {
+			continue
+		}
+		if piece, ok := p.Board.Get(t); ok && piece.Type() != Pawn {
+			p.Board.Clear(t)
+		}
+	}
+
+	for _, r := range p.castlingRights() {
+		if *r.ok && !p.canCastle(r.c, r.kingside) {
+			*r.ok, *r.file = false, 0
+		}
+	}
+}
+
+// KingsTouch returns true if the kings are on neighboring squares. In Atomic,
+// neither king is in check then, since capturing one would explode the other.
+func (p *Position) KingsTouch() bool {
+	w, b := p.Board[WhiteKing], p.Board[BlackKing]
+	if w.Count() != 1 || b.Count() != 1 {
+		return false
+	}
+	for _, d := range kingSteps {
+		if t, ok := offset(w.First(), d); ok && t == b.First() {
+			return true
+		}
+	}
+	return false
+}
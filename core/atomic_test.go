@@ -0,0 +1,57 @@
+package core
+
+import "testing"
+
+func TestPosition_Make_Atomic(t *testing.T) {
+	var p Position
+
+	p.Variant = Atomic
+	p.FullMoveNumber = 1
+	p.WhiteOO, p.BlackOOO = true, true
+	p.Board.Set(WhiteKing, E1)
+	p.Board.Set(WhiteRook, H1)
+	p.Board.Set(WhiteBishop, B7)
+	p.Board.Set(BlackKing, E8)
+	p.Board.Set(BlackRook, A8)
+	p.Board.Set(BlackKnight, B8)
+	p.Board.Set(BlackPawn, A7)
+	p.Board.Set(BlackQueen, C7)
+	p.Board.Set(BlackBishop, C6)
+
+	p.Make(Move{From: B7, To: B8}) // Captures the knight.
+
+	var want Board
+	want.Set(WhiteKing, E1)
+	want.Set(WhiteRook, H1)
+	want.Set(BlackKing, E8)
+	want.Set(BlackPawn, A7)
+	want.Set(BlackBishop, C6)
+	if p.Board != want {
+		t.Errorf("board: got %v, want %v", p.Board, want)
+	}
+	if !p.WhiteOO || p.BlackOOO {
+		t.Errorf("castling: got %t and %t, want true and false", p.WhiteOO, p.BlackOOO)
+	}
+}
+
+func TestPosition_KingsTouch(t *testing.T) {
+	cases := []struct {
+		white, black Square
+		want         bool
+	}{
+		{E1, E8, false},
+		{E4, E5, true},
+		{A1, B2, true},
+		{A1, H2, false},
+		{H4, A5, false},
+	}
+
+	for _, tc := range cases {
+		var p Position
+		p.Board.Set(WhiteKing, tc.white)
+		p.Board.Set(BlackKing, tc.black)
+		if got := p.KingsTouch(); got != tc.want {
+			t.Errorf("%s and %s: got %t, want %t", tc.white, tc.black, got, tc.want)
+		}
+	}
+}
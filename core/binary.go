@@ -0,0 +1,150 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// packedSize is the size of a packed position in bytes.
+const packedSize = 32
+
+// Errors returned by MarshalBinary and UnmarshalBinary.
+var (
+	ErrNotPackable   = errors.New("position can't be packed")
+	ErrInvalidPacked = errors.New("invalid packed position")
+)
+
+// MarshalBinary packs a position into 32 bytes, in the format described by
+// package encoding/packed. Positions with more than 32 pieces, Crazyhouse
+// positions, and clocks that don't fit return an error.
+func (p Position) MarshalBinary() ([]byte, error) {
+	switch {
+	case p.Variant == Crazyhouse:
+		return nil, fmt.Errorf("%w: %s", ErrNotPackable, p.Variant)
+	case !p.Variant.Valid():
+		return nil, fmt.Errorf("%w: %s", ErrNotPackable, p.Variant)
+	case p.HalfMoveClock < 0 || p.HalfMoveClock > math.MaxUint8:
+		return nil, fmt.Errorf("%w: half move clock %d", ErrNotPackable, p.HalfMoveClock)
+	case p.FullMoveNumber < 0 || p.FullMoveNumber > math.MaxUint16:
+		return nil, fmt.Errorf("%w: full move number %d", ErrNotPackable, p.FullMoveNumber)
+	case p.WhiteChecks < 0 || p.WhiteChecks > 3 || p.BlackChecks < 0 || p.BlackChecks > 3:
+		return nil, fmt.Errorf("%w: %d and %d checks", ErrNotPackable, p.WhiteChecks, p.BlackChecks)
+	case p.EnPassant > H8:
+		return nil, fmt.Errorf("%w: en passant square %d", ErrNotPackable, p.EnPassant)
+	}
+	for _, f := range []File{p.WhiteOOFile, p.WhiteOOOFile, p.BlackOOFile, p.BlackOOOFile} {
+		if !f.Valid() {
+			return nil, fmt.Errorf("%w: castling file %d", ErrNotPackable, f)
+		}
+	}
+
+	var occupied Bitboard
+	for _, bb := range p.Board {
+		if occupied.Intersects(bb) {
+			return nil, fmt.Errorf("%w: pieces share a square", ErrNotPackable)
+		}
+		occupied.With(bb)
+	}
+	if n := occupied.Count(); n > 32 {
+		return nil, fmt.Errorf("%w: %d pieces", ErrNotPackable, n)
+	}
+
+	b := make([]byte, packedSize)
+	binary.LittleEndian.PutUint64(b, uint64(occupied))
+
+	// Pieces, in square order, take a nibble each.
+	var i int
+	for s := A1; s <= H8; s++ {
+		piece, ok := p.Board.Get(s)
+		if !ok {
+			continue
+		}
+		b[8+i/2] |= byte(piece) << (4 * (i % 2))
+		i++
+	}
+
+	if p.SideToMove == Black {
+		b[24] = 1
+	}
+	for i, flag := range p.packedFlags() {
+		if *flag {
+			b[24] |= 2 << i
+		}
+	}
+	b[25] = byte(p.EnPassant)
+
+	files := uint16(p.WhiteOOFile) | uint16(p.WhiteOOOFile)<<3 | uint16(p.BlackOOFile)<<6 | uint16(p.BlackOOOFile)<<9
+	binary.LittleEndian.PutUint16(b[26:], files|uint16(p.Variant)<<12)
+
+	b[28] = byte(p.HalfMoveClock)
+	binary.LittleEndian.PutUint16(b[29:], uint16(p.FullMoveNumber))
+	b[31] = byte(p.WhiteChecks) | byte(p.BlackChecks)<<2
+
+	return b, nil
+}
+
+// UnmarshalBinary unpacks a position packed by MarshalBinary.
+func (p *Position) UnmarshalBinary(b []byte) error {
+	if len(b) != packedSize {
+		return fmt.Errorf("%w: %d bytes", ErrInvalidPacked, len(b))
+	}
+
+	var q Position
+
+	occupied := Bitboard(binary.LittleEndian.Uint64(b))
+	if n := occupied.Count(); n > 32 {
+		return fmt.Errorf("%w: %d pieces", ErrInvalidPacked, n)
+	}
+	var i int
+	for s := A1; s <= H8; s++ {
+		if !occupied.Get(s) {
+			continue
+		}
+		piece := Piece(b[8+i/2] >> (4 * (i % 2)) & 0xf)
+		if !piece.Valid() {
+			return fmt.Errorf("%w: piece %d", ErrInvalidPacked, piece)
+		}
+		q.Board.Set(piece, s)
+		i++
+	}
+	// Unused nibbles must be zero.
+	for ; i < 32; i++ {
+		if b[8+i/2]>>(4*(i%2))&0xf != 0 {
+			return fmt.Errorf("%w: unused piece %d", ErrInvalidPacked, i)
+		}
+	}
+
+	if b[24]>>6 != 0 || b[25]>>6 != 0 || b[31]>>4 != 0 {
+		return fmt.Errorf("%w: unused bits set", ErrInvalidPacked)
+	}
+	q.SideToMove = Color(b[24]&1 != 0)
+	for i, flag := range q.packedFlags() {
+		*flag = b[24]&(2<<i) != 0
+	}
+	q.EnPassant = Square(b[25])
+
+	files := binary.LittleEndian.Uint16(b[26:])
+	q.WhiteOOFile = File(files & 7)
+	q.WhiteOOOFile = File(files >> 3 & 7)
+	q.BlackOOFile = File(files >> 6 & 7)
+	q.BlackOOOFile = File(files >> 9 & 7)
+	q.Variant = Variant(files >> 12)
+	if !q.Variant.Valid() || q.Variant == Crazyhouse {
+		return fmt.Errorf("%w: variant %s", ErrInvalidPacked, q.Variant)
+	}
+
+	q.HalfMoveClock = int(b[28])
+	q.FullMoveNumber = int(binary.LittleEndian.Uint16(b[29:]))
+	q.WhiteChecks = int(b[31] & 3)
+	q.BlackChecks = int(b[31] >> 2 & 3)
+
+	*p = q
+	return nil
+}
+
+// packedFlags returns the flags packed into a bit each after the side to move.
+func (p *Position) packedFlags() []*bool {
+	return []*bool{&p.WhiteOO, &p.WhiteOOO, &p.BlackOO, &p.BlackOOO, &p.Chess960}
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"encoding"
+	"errors"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = Position{}
+	_ encoding.BinaryUnmarshaler = (*Position)(nil)
+)
+
+func TestPosition_MarshalBinary(t *testing.T) {
+	var positions []Position
+	for n := range 960 {
+		positions = append(positions, NewChess960Position(n))
+	}
+
+	p := NewPosition()
+	p.Make(Move{From: E2, To: E4})
+	p.Variant, p.WhiteChecks, p.BlackChecks = ThreeCheck, 3, 1
+	positions = append(positions, p)
+
+	for _, want := range positions {
+		b, err := want.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() error: %v", err)
+		}
+		var got Position
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatalf("UnmarshalBinary() error: %v", err)
+		}
+		if got != want {
+			t.Errorf("round trip: got %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestPosition_MarshalBinary_Error(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup func(p *Position)
+	}{
+		{"crazyhouse", func(p *Position) { p.Variant = Crazyhouse }},
+		{"overlap", func(p *Position) { p.Board[WhiteQueen].Set(E2) }},
+		{"too many pieces", func(p *Position) { p.Board.Set(WhitePawn, E4) }},
+		{"clock", func(p *Position) { p.HalfMoveClock = 300 }},
+		{"checks", func(p *Position) { p.WhiteChecks = 4 }},
+	}
+
+	for _, tc := range cases {
+		p := NewPosition()
+		tc.setup(&p)
+		if _, err := p.MarshalBinary(); !errors.Is(err, ErrNotPackable) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, ErrNotPackable)
+		}
+	}
+}
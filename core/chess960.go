@@ -0,0 +1,167 @@
+package core
+
+// backRank returns the rank a color's pieces start on.
+func backRank(c Color) Rank {
+	if c == White {
+		return Rank1
+	}
+	return Rank8
+}
+
+// CastlingRook returns the starting square of the rook a color castles with,
+// kingside or queenside. It's only meaningful if the color has the castling
+// right.
+func (p *Position) CastlingRook(c Color, kingside bool) Square {
+	var f File
+	switch {
+	case !p.Chess960 && kingside:
+		f = FileH
+	case !p.Chess960:
+		f = FileA
+	case c == White && kingside:
+		f = p.WhiteOOFile
+	case c == White:
+		f = p.WhiteOOOFile
+	case kingside:
+		f = p.BlackOOFile
+	default:
+		f = p.BlackOOOFile
+	}
+	return NewSquare(f, backRank(c))
+}
+
+// IsCastle returns true if a move castles.
+func (p *Position) IsCastle(m Move) bool {
+	_, ok := p.castlingRook(m)
+	return ok
+}
+
+// castlingRook returns the square of the rook a move castles with, if it
+// castles. Castling moves are the king capturing its own rook, or, outside of
+// Chess960, the king moving two squares from the e-file.
+func (p *Position) castlingRook(m Move) (Square, bool) {
+	king, ok := p.Board.Get(m.From)
+	if !ok || king.Type() != King {
+		return 0, false
+	}
+	c := king.Color()
+
+	if piece, ok := p.Board.Get(m.To); ok && piece == NewPiece(c, Rook) {
+		return m.To, true
+	}
+
+	if p.Chess960 || m.From != NewSquare(FileE, backRank(c)) || m.To.Rank() != m.From.Rank() {
+		return 0, false
+	}
+	switch m.To.File() {
+	case FileG:
+		return p.CastlingRook(c, true), true
+	case FileC:
+		return p.CastlingRook(c, false), true
+	default:
+		return 0, false
+	}
+}
+
+// castle moves a king and rook to their squares after castling. The king ends
+// up on the g-file or c-file, and the rook next to it, toward the center.
+func (p *Position) castle(king Piece, from, rook Square) {
+	kingTo, rookTo := FileG, FileF
+	if rook.File() < from.File() {
+		kingTo, rookTo = FileC, FileD
+	}
+
+	r := from.Rank()
+	p.Board.Clear(from)
+	p.Board.Clear(rook)
+	p.Board.SetOnEmpty(king, NewSquare(kingTo, r))
+	p.Board.SetOnEmpty(NewPiece(king.Color(), Rook), NewSquare(rookTo, r))
+}
+
+// SetChess960 makes a position a Chess960 position, so that castling moves
+// are the king capturing its own rook. Castling rooks stay where they are.
+func (p *Position) SetChess960() {
+	if p.Chess960 {
+		return
+	}
+	if p.WhiteOO {
+		p.WhiteOOFile = FileH
+	}
+	if p.WhiteOOO {
+		p.WhiteOOOFile = FileA
+	}
+	if p.BlackOO {
+		p.BlackOOFile = FileH
+	}
+	if p.BlackOOO {
+		p.BlackOOOFile = FileA
+	}
+	p.Chess960 = true
+}
+
+// chess960Knights holds the placements of the knights among the five empty
+// squares left after placing the bishops and queen, by index.
+var chess960Knights = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4},
+	{1, 2}, {1, 3}, {1, 4},
+	{2, 3}, {2, 4},
+	{3, 4},
+}
+
+// NewChess960Position returns a Chess960 starting position, numbered from 0
+// to 959 as in Scharnagl's scheme. Position 518 has the standard
+// arrangement of pieces. It panics if n is out of range.
+func NewChess960Position(n int) Position {
+	if n < 0 || n > 959 {
+		panic("core: invalid Chess960 position number")
+	}
+
+	var (
+		pieces [8]PieceType
+		placed [8]bool
+	)
+	// place puts a piece on the i-th empty file.
+	place := func(pt PieceType, i int) {
+		for f := range pieces {
+			if placed[f] {
+				continue
+			}
+			if i == 0 {
+				pieces[f], placed[f] = pt, true
+				return
+			}
+			i--
+		}
+	}
+
+	pieces[2*(n%4)+1], placed[2*(n%4)+1] = Bishop, true // Light squares.
+	n /= 4
+	pieces[2*(n%4)], placed[2*(n%4)] = Bishop, true // Dark squares.
+	n /= 4
+	place(Queen, n%6)
+	n /= 6
+	k := chess960Knights[n]
+	place(Knight, k[0])
+	place(Knight, k[1]-1)
+	place(Rook, 0)
+	place(King, 0)
+	place(Rook, 0)
+
+	p := Position{Chess960: true, FullMoveNumber: 1}
+	var rooks []File
+	for f, pt := range pieces {
+		p.Board.SetOnEmpty(NewPiece(White, pt), NewSquare(File(f), Rank1))
+		p.Board.SetOnEmpty(NewPiece(White, Pawn), NewSquare(File(f), Rank2))
+		p.Board.SetOnEmpty(NewPiece(Black, Pawn), NewSquare(File(f), Rank7))
+		p.Board.SetOnEmpty(NewPiece(Black, pt), NewSquare(File(f), Rank8))
+		if pt == Rook {
+			rooks = append(rooks, File(f))
+		}
+	}
+
+	p.WhiteOO, p.WhiteOOO, p.BlackOO, p.BlackOOO = true, true, true, true
+	p.WhiteOOFile, p.BlackOOFile = rooks[1], rooks[1]
+	p.WhiteOOOFile, p.BlackOOOFile = rooks[0], rooks[0]
+
+	return p
+}
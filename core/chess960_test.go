@@ -0,0 +1,116 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewChess960Position(t *testing.T) {
+	cases := []struct {
+		n    int
+		want [8]PieceType
+	}{
+		{0, [8]PieceType{Bishop, Bishop, Queen, Knight, Knight, Rook, King, Rook}},
+		{518, [8]PieceType{Rook, Knight, Bishop, Queen, King, Bishop, Knight, Rook}},
+		{959, [8]PieceType{Rook, King, Rook, Knight, Knight, Queen, Bishop, Bishop}},
+	}
+
+	for _, tc := range cases {
+		p := NewChess960Position(tc.n)
+		for f, pt := range tc.want {
+			for _, s := range []Square{NewSquare(File(f), Rank1), NewSquare(File(f), Rank8)} {
+				piece, ok := p.Board.Get(s)
+				if !ok || piece.Type() != pt {
+					t.Errorf("%d: expected %s on %s, got %s, %t", tc.n, pt, s, piece, ok)
+				}
+			}
+		}
+	}
+}
+
+func TestNewChess960Position_Standard(t *testing.T) {
+	want := NewPosition()
+	want.Chess960 = true
+	want.WhiteOOFile, want.WhiteOOOFile = FileH, FileA
+	want.BlackOOFile, want.BlackOOOFile = FileH, FileA
+
+	if diff := cmp.Diff(want, NewChess960Position(518)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPosition_Make_Chess960Castle(t *testing.T) {
+	var p Position
+
+	p.Board.Set(WhiteKing, G1)
+	p.Board.Set(WhiteRook, H1)
+	p.Board.Set(WhiteRook, B1)
+	p.Board.Set(BlackKing, B8)
+	p.Board.Set(BlackRook, A8)
+	p.Board.Set(BlackRook, G8)
+
+	p.Chess960 = true
+	p.WhiteOO, p.WhiteOOFile = true, FileH
+	p.WhiteOOO, p.WhiteOOOFile = true, FileB
+	p.BlackOO, p.BlackOOFile = true, FileG
+	p.BlackOOO, p.BlackOOOFile = true, FileA
+
+	if !p.IsCastle(Move{From: G1, To: H1}) {
+		t.Errorf("expected G1H1 to castle")
+	}
+	if p.IsCastle(Move{From: G1, To: F1}) {
+		t.Errorf("expected G1F1 not to castle")
+	}
+
+	p.Make(Move{From: G1, To: H1})
+	p.Make(Move{From: B8, To: A8})
+
+	if p.WhiteOO || p.WhiteOOO || p.BlackOO || p.BlackOOO {
+		t.Errorf(
+			"expected no castling rights, got WhiteOO=%v WhiteOOO=%v BlackOO=%v BlackOOO=%v",
+			p.WhiteOO, p.WhiteOOO, p.BlackOO, p.BlackOOO,
+		)
+	}
+
+	want := map[Square]Piece{
+		G1: WhiteKing,
+		F1: WhiteRook,
+		B1: WhiteRook,
+		C8: BlackKing,
+		D8: BlackRook,
+		G8: BlackRook,
+	}
+	for s, want := range want {
+		piece, ok := p.Board.Get(s)
+		if !ok || piece != want {
+			t.Errorf("expected %s on %s, got %s, %t", want, s, piece, ok)
+		}
+	}
+	for _, s := range []Square{H1, A8, B8} {
+		if p.Board.IsOccupied(s) {
+			t.Errorf("expected %s to be empty", s)
+		}
+	}
+}
+
+func TestPosition_Make_Chess960RookRights(t *testing.T) {
+	var p Position
+
+	p.Board.Set(WhiteKing, D1)
+	p.Board.Set(WhiteRook, C1)
+	p.Board.Set(WhiteRook, F1)
+
+	p.Chess960 = true
+	p.WhiteOO, p.WhiteOOFile = true, FileF
+	p.WhiteOOO, p.WhiteOOOFile = true, FileC
+
+	p.Make(Move{From: F1, To: F2})
+
+	if p.WhiteOO {
+		t.Errorf("expected WhiteOO=false, got %t", p.WhiteOO)
+	}
+	if !p.WhiteOOO || p.WhiteOOOFile != FileC {
+		t.Errorf("expected WhiteOOO=true on FileC, got %t on %s", p.WhiteOOO, p.WhiteOOOFile)
+	}
+}
@@ -0,0 +1,52 @@
+package core
+
+// A Pocket holds the number of pieces of each type that a side has captured
+// in Crazyhouse, and can drop on the board. Kings are never captured.
+type Pocket [King]int
+
+// Pocket returns a color's pocket.
+func (p *Position) Pocket(c Color) *Pocket {
+	if c == White {
+		return &p.WhitePocket
+	}
+	return &p.BlackPocket
+}
+
+// drop places a piece from the side to move's pocket on the board.
+func (p *Position) drop(m Move) {
+	p.Pocket(p.SideToMove)[m.Dropped]--
+	p.Board.SetOnEmpty(NewPiece(p.SideToMove, m.Dropped), m.To)
+	p.EnPassant = 0
+}
+
+// makeCrazyhouse updates the pockets and promoted pieces for a move that isn't
+// a drop, before the board changes. Captured pieces go to the capturer's
+// pocket, as pawns if they were promoted.
+func (p *Position) makeCrazyhouse(m Move, isCapture bool) {
+	if isCapture {
+		s := m.To
+		if p.Board.IsEmpty(s) { // en passant
+			if p.SideToMove == White {
+				s = s.Below()
+			} else {
+				s = s.Above()
+			}
+		}
+
+		piece, _ := p.Board.Get(s)
+		pt := piece.Type()
+		if p.Promoted.Get(s) {
+			pt = Pawn
+			p.Promoted.Clear(s)
+		}
+		p.Pocket(p.SideToMove)[pt]++
+	}
+
+	switch {
+	case p.Promoted.Get(m.From):
+		p.Promoted.Clear(m.From)
+		p.Promoted.Set(m.To)
+	case m.Promotion != 0:
+		p.Promoted.Set(m.To)
+	}
+}
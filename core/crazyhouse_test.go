@@ -0,0 +1,40 @@
+package core
+
+import "testing"
+
+func TestPosition_Make_Crazyhouse(t *testing.T) {
+	var p Position
+
+	p.Variant = Crazyhouse
+	p.FullMoveNumber = 1
+	p.Board.Set(WhiteKing, E1)
+	p.Board.Set(WhitePawn, B7)
+	p.Board.Set(BlackKing, E8)
+	p.Board.Set(BlackKnight, A8)
+	p.Board.Set(BlackRook, C6)
+
+	p.Make(Move{From: B7, To: A8, Promotion: Queen}) // Captures a knight.
+	p.Make(Move{From: C6, To: C8})
+	p.Make(Move{From: A8, To: C8}) // Captures a rook.
+	p.Make(Move{From: E8, To: D7})
+	p.Make(Move{To: F3, Drop: true, Dropped: Knight})
+	p.Make(Move{From: D7, To: C8}) // Captures the promoted queen.
+
+	if want := (Pocket{Rook: 1}); p.WhitePocket != want {
+		t.Errorf("white pocket: got %v, want %v", p.WhitePocket, want)
+	}
+	if want := (Pocket{Pawn: 1}); p.BlackPocket != want {
+		t.Errorf("black pocket: got %v, want %v", p.BlackPocket, want)
+	}
+	if piece, ok := p.Board.Get(F3); !ok || piece != WhiteKnight {
+		t.Errorf("F3: got %v, want %v", piece, WhiteKnight)
+	}
+	if p.Promoted != 0 {
+		t.Errorf("promoted: got %#x, want 0", uint64(p.Promoted))
+	}
+
+	p.Make(Move{To: D2, Drop: true, Dropped: Rook})
+	if p.WhitePocket != (Pocket{}) || p.HalfMoveClock != 1 {
+		t.Errorf("after drop: got pocket %v and half move clock %d", p.WhitePocket, p.HalfMoveClock)
+	}
+}
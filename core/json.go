@@ -0,0 +1,328 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A StructuredPosition is a position that's encoded in JSON as an object of
+// its fields, rather than as a FEN string, for clients that don't parse FEN.
+// EnPassant and the castling files are omitted when they're zero. Pockets are
+// arrays of counts by piece type, and Promoted is a bitboard, both numeric.
+type StructuredPosition Position
+
+// MarshalJSON encodes a position as a JSON string of its FEN, as described by
+// package encoding/fen. Castling rights of Chess960 positions are the files of
+// the castling rooks, as in Shredder-FEN.
+func (p Position) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encodeFEN(p))
+}
+
+// UnmarshalJSON decodes a position from a JSON string of its FEN, or from an
+// object of its fields, as encoded for a StructuredPosition. Like other
+// decoders, it ignores null.
+func (p *Position) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		q, err := decodeFEN(s)
+		if err != nil {
+			return err
+		}
+		*p = q
+		return nil
+	}
+
+	var q StructuredPosition
+	if err := json.Unmarshal(b, &q); err != nil {
+		return err
+	}
+	*p = Position(q)
+	return nil
+}
+
+// pocketOrder is the order of pieces in pockets in FEN.
+const pocketOrder = "QRBNPqrbnp"
+
+// encodeFEN encodes a position as a FEN string. Package encoding/fen
+// implements FEN in full, and decodes every string encoded here.
+func encodeFEN(p Position) string {
+	var b strings.Builder
+
+	for r := Rank8; r.Valid(); r-- {
+		gap := 0
+		for f := FileA; f.Valid(); f++ {
+			piece, ok := p.Board.Get(NewSquare(f, r))
+			if !ok {
+				gap++
+				continue
+			}
+			if gap > 0 {
+				b.WriteByte('0' + byte(gap))
+				gap = 0
+			}
+			b.WriteByte(pieceLetters[piece])
+			if p.Variant == Crazyhouse && p.Promoted.Get(NewSquare(f, r)) {
+				b.WriteByte('~')
+			}
+		}
+		if gap > 0 {
+			b.WriteByte('0' + byte(gap))
+		}
+		if r != Rank1 {
+			b.WriteByte('/')
+		}
+	}
+
+	if p.Variant == Crazyhouse {
+		b.WriteByte('[')
+		for i := range len(pocketOrder) {
+			piece := Piece(strings.IndexByte(pieceLetters, pocketOrder[i]))
+			b.WriteString(strings.Repeat(pocketOrder[i:i+1], p.Pocket(piece.Color())[piece.Type()]))
+		}
+		b.WriteByte(']')
+	}
+
+	if p.SideToMove == White {
+		b.WriteString(" w ")
+	} else {
+		b.WriteString(" b ")
+	}
+
+	rights := []struct {
+		ok       bool
+		c        Color
+		kingside bool
+	}{
+		{p.WhiteOO, White, true},
+		{p.WhiteOOO, White, false},
+		{p.BlackOO, Black, true},
+		{p.BlackOOO, Black, false},
+	}
+	castling := b.Len()
+	for _, r := range rights {
+		if !r.ok {
+			continue
+		}
+		letter := byte('q')
+		switch {
+		case p.Chess960:
+			letter = 'a' + byte(p.CastlingRook(r.c, r.kingside).File())
+		case r.kingside:
+			letter = 'k'
+		}
+		if r.c == White {
+			letter -= 'a' - 'A'
+		}
+		b.WriteByte(letter)
+	}
+	if b.Len() == castling {
+		b.WriteByte('-')
+	}
+
+	if p.EnPassant == 0 {
+		b.WriteString(" -")
+	} else {
+		ep, _ := p.EnPassant.MarshalText()
+		fmt.Fprintf(&b, " %s", ep)
+	}
+
+	fmt.Fprintf(&b, " %d %d", p.HalfMoveClock, p.FullMoveNumber)
+
+	if p.Variant == ThreeCheck {
+		fmt.Fprintf(&b, " +%d+%d", p.WhiteChecks, p.BlackChecks)
+	}
+
+	return b.String()
+}
+
+// decodeFEN decodes a FEN string encoded by encodeFEN, or with castling rights
+// in X-FEN, like "KQkq".
+func decodeFEN(s string) (Position, error) {
+	invalid := func(what string) (Position, error) {
+		return Position{}, fmt.Errorf("invalid FEN %q: bad %s", s, what)
+	}
+
+	fields := strings.Split(s, " ")
+	if n := len(fields); n != 6 && n != 7 {
+		return invalid("number of fields")
+	}
+
+	var p Position
+
+	board := fields[0]
+	if i := strings.IndexByte(board, '['); i >= 0 && strings.HasSuffix(board, "]") {
+		p.Variant = Crazyhouse
+		for j := i + 1; j < len(board)-1; j++ {
+			// Kings are never in pockets.
+			k := strings.IndexByte(pocketOrder, board[j])
+			if k < 0 {
+				return invalid("pockets")
+			}
+			piece := Piece(strings.IndexByte(pieceLetters, board[j]))
+			p.Pocket(piece.Color())[piece.Type()]++
+		}
+		board = board[:i]
+	}
+
+	rows := strings.Split(board, "/")
+	if len(rows) != 8 {
+		return invalid("board")
+	}
+	for i, row := range rows {
+		r, f := Rank8-Rank(i), FileA
+		for j := 0; j < len(row); j++ {
+			c := row[j]
+			switch {
+			case '1' <= c && c <= '8':
+				f += File(c - '0')
+			case c == '~':
+				// Promoted pieces in Crazyhouse are followed by a tilde.
+				if p.Variant != Crazyhouse || j == 0 || strings.IndexByte(pieceLetters, row[j-1]) < 0 {
+					return invalid("board")
+				}
+				p.Promoted.Set(NewSquare(f-1, r))
+			default:
+				k := strings.IndexByte(pieceLetters, c)
+				if k < 0 || !f.Valid() {
+					return invalid("board")
+				}
+				p.Board.SetOnEmpty(Piece(k), NewSquare(f, r))
+				f++
+			}
+			if f > FileH+1 {
+				return invalid("board")
+			}
+		}
+		if f != FileH+1 {
+			return invalid("board")
+		}
+	}
+
+	switch fields[1] {
+	case "w":
+		p.SideToMove = White
+	case "b":
+		p.SideToMove = Black
+	default:
+		return invalid("side to move")
+	}
+
+	if !p.decodeCastling(fields[2]) {
+		return invalid("castling rights")
+	}
+
+	if fields[3] != "-" {
+		if err := p.EnPassant.UnmarshalText([]byte(fields[3])); err != nil {
+			return invalid("en passant square")
+		}
+		if want := Rank6 - 3*Rank(p.SideToMove.Uint64()); p.EnPassant.Rank() != want {
+			return invalid("en passant square")
+		}
+	}
+
+	hmc, err := strconv.ParseUint(fields[4], 10, 31)
+	if err != nil {
+		return invalid("half move clock")
+	}
+	p.HalfMoveClock = int(hmc)
+	fmn, err := strconv.ParseUint(fields[5], 10, 31)
+	if err != nil || fmn == 0 {
+		return invalid("full move number")
+	}
+	p.FullMoveNumber = int(fmn)
+
+	if len(fields) == 7 {
+		c := fields[6]
+		if p.Variant != Standard || len(c) != 4 || c[0] != '+' || c[2] != '+' ||
+			c[1] < '0' || c[1] > '3' || c[3] < '0' || c[3] > '3' {
+			return invalid("checks")
+		}
+		p.Variant = ThreeCheck
+		p.WhiteChecks, p.BlackChecks = int(c[1]-'0'), int(c[3]-'0')
+	}
+
+	return p, nil
+}
+
+// decodeCastling decodes castling rights, which are K, Q, k and q for the
+// outermost rooks on each side of the king, or the files of the rooks. The
+// position is Chess960 if a file is used, or a castling king or rook isn't on
+// its standard square. It returns false if the rights are invalid.
+func (p *Position) decodeCastling(s string) bool {
+	if s == "-" {
+		return true
+	}
+	if s == "" {
+		return false
+	}
+
+	rights := [...]*bool{&p.WhiteOO, &p.WhiteOOO, &p.BlackOO, &p.BlackOOO}
+	files := [...]*File{&p.WhiteOOFile, &p.WhiteOOOFile, &p.BlackOOFile, &p.BlackOOOFile}
+
+	for i := range len(s) {
+		c, upper := White, s[i]
+		if 'a' <= upper && upper <= 'z' {
+			c, upper = Black, upper-('a'-'A')
+		}
+		rank := backRank(c)
+		rook := NewPiece(c, Rook)
+		king := p.Board[NewPiece(c, King)]
+		if king.Count() != 1 || king.First().Rank() != rank {
+			return false
+		}
+		kf := king.First().File()
+
+		var (
+			kingside bool
+			file     File
+		)
+		switch {
+		case upper == 'K' || upper == 'Q':
+			kingside = upper == 'K'
+			file = FileA
+			if kingside {
+				file = FileH
+			}
+			found := false
+			for f := FileA; f.Valid(); f++ {
+				if piece, ok := p.Board.Get(NewSquare(f, rank)); ok && piece == rook && (f > kf) == kingside {
+					if !found || kingside {
+						file, found = f, true
+					}
+				}
+			}
+			if found && (kf != FileE || file != FileA && file != FileH) {
+				p.Chess960 = true
+			}
+		case 'A' <= upper && upper <= 'H':
+			file = File(upper - 'A')
+			if piece, ok := p.Board.Get(NewSquare(file, rank)); !ok || piece != rook {
+				return false
+			}
+			kingside = file > kf
+			p.Chess960 = true
+		default:
+			return false
+		}
+
+		j := 2 * c.Uint64()
+		if !kingside {
+			j++
+		}
+		*rights[j], *files[j] = true, file
+	}
+
+	// Rook files are only kept for Chess960 positions.
+	if !p.Chess960 {
+		p.WhiteOOFile, p.WhiteOOOFile, p.BlackOOFile, p.BlackOOOFile = 0, 0, 0, 0
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// kings is the position "4k3/8/8/8/8/8/8/4K3 w - - 0 1".
+func kings() Position {
+	var p Position
+	p.Board.SetOnEmpty(WhiteKing, E1)
+	p.Board.SetOnEmpty(BlackKing, E8)
+	p.FullMoveNumber = 1
+	return p
+}
+
+func TestPosition_MarshalJSON(t *testing.T) {
+	got, err := json.Marshal(kings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"4k3/8/8/8/8/8/8/4K3 w - - 0 1"`; string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestPosition_JSON_FEN(t *testing.T) {
+	cases := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+		"r3k2r/8/8/8/8/8/8/R3K2R b Kq - 12 40",
+		// Chess960, with castling rights in Shredder-FEN.
+		"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+		// Crazyhouse.
+		"r1b1kb1r/pppp1ppp/5n2/4p3/4P3/2N5/PPPP1PPP/R1B1KB~NR[QNnp] b KQkq - 0 6",
+		"4k3/8/8/8/8/8/8/4K3[] w - - 0 1",
+		// Three-check.
+		"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2 +1+0",
+	}
+	for _, in := range cases {
+		b, _ := json.Marshal(in)
+		var p Position
+		if err := json.Unmarshal(b, &p); err != nil {
+			t.Errorf("%q: error: %v", in, err)
+			continue
+		}
+		got, err := json.Marshal(p)
+		if err != nil {
+			t.Errorf("%q: error: %v", in, err)
+			continue
+		}
+		if string(got) != string(b) {
+			t.Errorf("got %s, want %s", got, b)
+		}
+	}
+}
+
+func TestPosition_UnmarshalJSON_XFEN(t *testing.T) {
+	// The castling rooks are the outermost rooks on each side of the king.
+	var p Position
+	if err := json.Unmarshal([]byte(`"1r2k1r1/8/8/8/8/8/8/R3K2R b KQk - 0 1"`), &p); err != nil {
+		t.Fatal(err)
+	}
+	if !p.Chess960 || !p.WhiteOO || !p.WhiteOOO || !p.BlackOO || p.BlackOOO ||
+		p.WhiteOOFile != FileH || p.WhiteOOOFile != FileA || p.BlackOOFile != FileG {
+		t.Errorf("got Chess960 %t, castling %t %t %t %t, files %s %s %s",
+			p.Chess960, p.WhiteOO, p.WhiteOOO, p.BlackOO, p.BlackOOO, p.WhiteOOFile, p.WhiteOOOFile, p.BlackOOFile)
+	}
+}
+
+// structuredKings is kings encoded as a StructuredPosition.
+const structuredKings = `{"Board":[0,0,0,0,0,16,0,0,0,0,0,1152921504606846976],` +
+	`"SideToMove":"white","WhiteOO":false,"WhiteOOO":false,` +
+	`"BlackOO":false,"BlackOOO":false,"Chess960":false,"Variant":"standard",` +
+	`"WhiteChecks":0,"BlackChecks":0,"WhitePocket":[0,0,0,0,0],` +
+	`"BlackPocket":[0,0,0,0,0],"Promoted":0,"HalfMoveClock":0,"FullMoveNumber":1}`
+
+func TestStructuredPosition_MarshalJSON(t *testing.T) {
+	got, err := json.Marshal(StructuredPosition(kings()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != structuredKings {
+		t.Errorf("got %s, want %s", got, structuredKings)
+	}
+}
+
+func TestStructuredPosition_RoundTrip(t *testing.T) {
+	want := kings()
+	want.EnPassant = E3
+	want.SideToMove = Black
+	want.Board.SetOnEmpty(WhiteRook, B1)
+	want.WhiteOOO, want.WhiteOOOFile = true, FileB
+	want.Chess960 = true
+	want.Variant = Crazyhouse
+	want.WhitePocket[Knight] = 1
+
+	b, err := json.Marshal(StructuredPosition(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"EnPassant":"e3"`, `"WhiteOOOFile":"b"`, `"Variant":"crazyhouse"`} {
+		if !strings.Contains(string(b), field) {
+			t.Errorf("got %s, want %s", b, field)
+		}
+	}
+	if strings.Contains(string(b), `"WhiteOOFile"`) {
+		t.Errorf("got %s, want no WhiteOOFile", b)
+	}
+
+	var got Position
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPosition_UnmarshalJSON(t *testing.T) {
+	cases := []string{
+		`"4k3/8/8/8/8/8/8/4K3 w - - 0 1"`,
+		structuredKings,
+	}
+	for _, in := range cases {
+		var got Position
+		if err := json.Unmarshal([]byte(in), &got); err != nil {
+			t.Errorf("%s: error: %v", in, err)
+			continue
+		}
+		if diff := cmp.Diff(kings(), got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", in, diff)
+		}
+	}
+}
+
+func TestPosition_UnmarshalJSON_Error(t *testing.T) {
+	cases := []string{
+		`"4k3/8/8/8/8/8/8/4K3 w - - 0"`,
+		`"4k3/8/8/8/8/8/8/4K4 w - - 0 1"`,
+		`"4k3/8/8/8/8/8/8/4K3 w X - 0 1"`,
+		`"4k3/8/8/8/8/8/8/4K3 w - e3 0 1"`,
+		`"4k3/8/8/8/8/8/8/4K3 w - - -1 1"`,
+		`"4k3/8/8/8/8/8/8/4K3 w - - 0 0"`,
+		`"4k3/8/8/8/8/8/8/4K~3 w - - 0 1"`,
+		`"4k3/8/8/8/8/8/8/4K3[K] w - - 0 1"`,
+		`"4k3/8/8/8/8/8/8/4K3 w - - 0 1 +4+0"`,
+		`{"SideToMove":"green"}`,
+		`42`,
+	}
+	for _, in := range cases {
+		var p Position
+		if err := json.Unmarshal([]byte(in), &p); err == nil {
+			t.Errorf("%s: got nil error", in)
+		}
+	}
+}
+
+// An apiMove is like a message in a JSON API that embeds core types.
+type apiMove struct {
+	Position Position
+	Move     Move
+	Piece    Piece
+	Type     PieceType
+	Mover    Color
+	Targets  map[Square]Piece
+}
+
+func TestJSON_RoundTrip(t *testing.T) {
+	in := apiMove{
+		Position: kings(),
+		Move:     Move{From: E1, To: E2},
+		Piece:    WhiteKing,
+		Type:     King,
+		Mover:    White,
+		Targets:  map[Square]Piece{D2: BlackKnight, F2: BlackRook},
+	}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Position":"4k3/8/8/8/8/8/8/4K3 w - - 0 1","Move":"e1e2","Piece":"K",` +
+		`"Type":"king","Mover":"white","Targets":{"d2":"n","f2":"r"}}`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+
+	var got apiMove
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(in, got); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -1,8 +1,37 @@
 package core
 
 // A Move represents a chess move.
-// For castling moves, From and To are the king's squares.
+// For castling moves, From and To are the king's squares, except in Chess960,
+// where the king captures its own rook: To is the rook's square.
+// For drops in Crazyhouse, a piece of type Dropped is placed on To from the
+// pocket, and From is unused.
 type Move struct {
 	From, To  Square
 	Promotion PieceType // The zero value indicates no promotion.
+
+	Drop    bool
+	Dropped PieceType
+}
+
+// A PackedMove is a move packed into 16 bits, for compact storage. The
+// destination square is in bits 0-5, the origin square in bits 6-11, and the
+// promotion in bits 12-14. Drops set bit 15, with the dropped piece type in
+// place of the origin square.
+type PackedMove uint16
+
+// Pack packs a move.
+func (m Move) Pack() PackedMove {
+	if m.Drop {
+		return PackedMove(m.To) | PackedMove(m.Dropped)<<6 | 1<<15
+	}
+	return PackedMove(m.To) | PackedMove(m.From)<<6 | PackedMove(m.Promotion)<<12
+}
+
+// Unpack returns the move that was packed.
+func (pm PackedMove) Unpack() Move {
+	to := Square(pm & 63)
+	if pm&(1<<15) != 0 {
+		return Move{To: to, Drop: true, Dropped: PieceType(pm >> 6 & 63)}
+	}
+	return Move{From: Square(pm >> 6 & 63), To: to, Promotion: PieceType(pm >> 12 & 7)}
 }
@@ -1 +1,25 @@
 package core
+
+import "testing"
+
+func TestMove_Pack(t *testing.T) {
+	cases := []struct {
+		m    Move
+		want PackedMove
+	}{
+		{Move{}, 0},
+		{Move{From: E2, To: E4}, 0x031c},
+		{Move{From: A7, To: A8, Promotion: Queen}, 0x4c38},
+		{Move{From: B2, To: A1, Promotion: King}, 0x5240},
+		{Move{To: F3, Drop: true, Dropped: Knight}, 0x8055},
+	}
+	for _, c := range cases {
+		got := c.m.Pack()
+		if got != c.want {
+			t.Errorf("%+v.Pack() = %#04x, want %#04x", c.m, got, c.want)
+		}
+		if back := got.Unpack(); back != c.m {
+			t.Errorf("%#04x.Unpack() = %+v, want %+v", got, back, c.m)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package core
+
+import "testing"
+
+func TestPosition_PolyglotKey(t *testing.T) {
+	// Test vectors from the Polyglot book format specification.
+	cases := []struct {
+		moves []Move
+		want  uint64
+	}{
+		{nil, 0x463b96181691fc9c},
+		{[]Move{{From: E2, To: E4}}, 0x823c9b50fd114196},
+		{[]Move{{From: E2, To: E4}, {From: D7, To: D5}}, 0x0756b94461c50fb0},
+		{[]Move{{From: E2, To: E4}, {From: D7, To: D5}, {From: E4, To: E5}}, 0x662fafb965db29d4},
+		{[]Move{{From: E2, To: E4}, {From: D7, To: D5}, {From: E4, To: E5}, {From: F7, To: F5}}, 0x22a48b5a8e47ff78},
+		{[]Move{{From: E2, To: E4}, {From: D7, To: D5}, {From: E4, To: E5}, {From: F7, To: F5}, {From: E1, To: E2}}, 0x652a607ca3f242c1},
+		{[]Move{{From: E2, To: E4}, {From: D7, To: D5}, {From: E4, To: E5}, {From: F7, To: F5}, {From: E1, To: E2}, {From: E8, To: F7}}, 0x00fdd303c946bdd9},
+		{[]Move{{From: A2, To: A4}, {From: B7, To: B5}, {From: H2, To: H4}, {From: B5, To: B4}, {From: C2, To: C4}}, 0x3c8123ea7b067637},
+		{[]Move{{From: A2, To: A4}, {From: B7, To: B5}, {From: H2, To: H4}, {From: B5, To: B4}, {From: C2, To: C4}, {From: B4, To: C3}, {From: A1, To: A3}}, 0x5c3f9b829b279560},
+	}
+
+	for i, tc := range cases {
+		p := NewPosition()
+		for _, m := range tc.moves {
+			p.Make(m)
+		}
+		if got := p.PolyglotKey(); got != tc.want {
+			t.Errorf("case %d: got %#016x, want %#016x", i, got, tc.want)
+		}
+	}
+}
+
+func TestPosition_PolyglotKey_EnPassant(t *testing.T) {
+	// After 1. e4, no black pawn can capture on e3, so the en passant square
+	// doesn't change the hash.
+	p := NewPosition()
+	p.Make(Move{From: E2, To: E4})
+
+	q := p
+	q.EnPassant = 0
+
+	if p.PolyglotKey() != q.PolyglotKey() {
+		t.Error("en passant square changed the hash without a capturing pawn")
+	}
+}
@@ -4,11 +4,27 @@ package core
 type Position struct {
 	Board      Board
 	SideToMove Color
-	EnPassant  Square // The zero value indicates no en passant square.
+	EnPassant  Square `json:",omitempty"` // The zero value indicates no en passant square.
 
 	WhiteOO, WhiteOOO bool
 	BlackOO, BlackOOO bool
 
+	// In Chess960, kings and rooks start on any files of the back rank, so
+	// castling rights are for the rooks on these files. Otherwise, they're
+	// ignored, and castling rooks start on the a-file and h-file.
+	Chess960                  bool
+	WhiteOOFile, WhiteOOOFile File `json:",omitempty"`
+	BlackOOFile, BlackOOOFile File `json:",omitempty"`
+
+	// The variant the position is played in, and its state: in Three-check,
+	// the number of checks each side has given, and in Crazyhouse, the pieces
+	// each side can drop and the pieces that were promoted, which are pawns
+	// again once captured.
+	Variant                  Variant
+	WhiteChecks, BlackChecks int
+	WhitePocket, BlackPocket Pocket
+	Promoted                 Bitboard
+
 	HalfMoveClock  int
 	FullMoveNumber int // Starts at 1.
 }
@@ -56,12 +72,23 @@ func NewPosition() Position {
 // Make makes a move.
 // It does not check for invalid moves.
 func (p *Position) Make(m Move) {
+	if m.Drop {
+		p.drop(m)
+		p.finishMove(m.Dropped == Pawn)
+		return
+	}
+
 	// Select the piece that we're going to move.
 	heldPiece, _ := p.Board.Get(m.From)
 
-	// Determine if the move is a capture.
-	isCapture := p.Board.IsOccupied(m.To) ||
-		(p.EnPassant != 0 && heldPiece.Type() == Pawn && m.To == p.EnPassant)
+	// Determine if the move castles or captures.
+	rook, isCastle := p.castlingRook(m)
+	isCapture := !isCastle && (p.Board.IsOccupied(m.To) ||
+		(p.EnPassant != 0 && heldPiece.Type() == Pawn && m.To == p.EnPassant))
+
+	if p.Variant == Crazyhouse {
+		p.makeCrazyhouse(m, isCapture)
+	}
 
 	// Adjust pawn placements if capturing en passant.
 	if p.EnPassant != 0 {
@@ -73,28 +100,16 @@ func (p *Position) Make(m Move) {
 		}
 	}
 
-	// Update castling rights.
-	switch {
-	case heldPiece == WhiteKing:
-		p.WhiteOO, p.WhiteOOO = false, false
-	case heldPiece == WhiteRook && m.From == A1:
-		p.WhiteOOO = false
-	case heldPiece == WhiteRook && m.From == H1:
-		p.WhiteOO = false
-	case heldPiece == BlackKing:
-		p.BlackOO, p.BlackOOO = false, false
-	case heldPiece == BlackRook && m.From == A8:
-		p.BlackOOO = false
-	case heldPiece == BlackRook && m.From == H8:
-		p.BlackOO = false
-	case m.To == A1:
-		p.WhiteOOO = false
-	case m.To == H1:
-		p.WhiteOO = false
-	case m.To == A8:
-		p.BlackOOO = false
-	case m.To == H8:
-		p.BlackOO = false
+	// Update castling rights. They're lost when the king moves, or when a
+	// castling rook moves or is captured.
+	for _, r := range p.castlingRights() {
+		if !*r.ok {
+			continue
+		}
+		s := p.CastlingRook(r.c, r.kingside)
+		if heldPiece == NewPiece(r.c, King) || m.From == s || m.To == s {
+			*r.ok, *r.file = false, 0
+		}
 	}
 
 	// Update the en passant square.
@@ -107,27 +122,47 @@ func (p *Position) Make(m Move) {
 		p.EnPassant = 0
 	}
 
-	// Adjust rook positions if castling.
+	// Move the piece, and the rook if castling.
 	switch {
-	case heldPiece.Type() == King && m.From == E1 && m.To == G1: // WhiteOO
-		p.Board.MoveToEmpty(WhiteRook, H1, F1)
-	case heldPiece.Type() == King && m.From == E1 && m.To == C1: // WhiteOOO
-		p.Board.MoveToEmpty(WhiteRook, A1, D1)
-	case heldPiece.Type() == King && m.From == E8 && m.To == G8: // BlackOO
-		p.Board.MoveToEmpty(BlackRook, H8, F8)
-	case heldPiece.Type() == King && m.From == E8 && m.To == C8: // BlackOOO
-		p.Board.MoveToEmpty(BlackRook, A8, D8)
-	}
-
-	// Move the piece.
-	if m.Promotion == 0 {
+	case isCastle:
+		p.castle(heldPiece, m.From, rook)
+	case m.Promotion == 0:
 		p.Board.Move(heldPiece, m.From, m.To)
-	} else {
+	default:
 		p.Board.Promote(m.From, m.To, m.Promotion)
 	}
 
+	if p.Variant == Atomic && isCapture {
+		p.explode(m.To)
+	}
+
+	p.finishMove(heldPiece.Type() == Pawn || isCapture)
+}
+
+// A castlingRight is a castling right of a position, and the file of the rook
+// it's for.
+type castlingRight struct {
+	ok       *bool
+	file     *File
+	c        Color
+	kingside bool
+}
+
+// castlingRights returns the castling rights of a position.
+func (p *Position) castlingRights() [4]castlingRight {
+	return [...]castlingRight{
+		{&p.WhiteOO, &p.WhiteOOFile, White, true},
+		{&p.WhiteOOO, &p.WhiteOOOFile, White, false},
+		{&p.BlackOO, &p.BlackOOFile, Black, true},
+		{&p.BlackOOO, &p.BlackOOOFile, Black, false},
+	}
+}
+
+// finishMove updates the move counters and switches sides after a move. The
+// half move clock is reset after captures and pawn moves.
+func (p *Position) finishMove(resetClock bool) {
 	// Update the half move clock.
-	if heldPiece.Type() == Pawn || isCapture {
+	if resetClock {
 		p.HalfMoveClock = 0
 	} else {
 		p.HalfMoveClock++
@@ -140,9 +175,12 @@ func (p *Position) Make(m Move) {
 
 	// Switch sides.
 	p.SideToMove = p.SideToMove.Other()
+
+	p.makeVariant()
 }
 
 // FriendlyKing returns the location of the side to move's king.
+// The side must have exactly one king, which isn't true in every variant.
 func (p *Position) FriendlyKing() Square {
 	if p.SideToMove == White {
 		return p.Board.WhiteKing()
@@ -151,6 +189,7 @@ func (p *Position) FriendlyKing() Square {
 }
 
 // EnemyKing returns the location of the opponent's king.
+// The opponent must have exactly one king, which isn't true in every variant.
 func (p *Position) EnemyKing() Square {
 	if p.SideToMove == White {
 		return p.Board.BlackKing()
@@ -170,3 +170,22 @@ func TestPosition_Make_BlackEPCapture(t *testing.T) {
 		t.Errorf("expected no WhitePawn on B4, got %s, %t", piece, ok)
 	}
 }
+
+func TestPosition_Make_ThreeCheck(t *testing.T) {
+	var p Position
+
+	p.Variant = ThreeCheck
+	p.Board.Set(WhiteKing, E1)
+	p.Board.Set(WhiteRook, A1)
+	p.Board.Set(BlackKing, E8)
+	p.Board.Set(BlackQueen, B6)
+
+	p.Make(Move{From: A1, To: A8}) // Check.
+	p.Make(Move{From: E8, To: E7})
+	p.Make(Move{From: A8, To: A7}) // Check.
+	p.Make(Move{From: B6, To: B1}) // Check.
+
+	if p.WhiteChecks != 2 || p.BlackChecks != 1 {
+		t.Errorf("expected 2 white checks and 1 black check, got %d and %d", p.WhiteChecks, p.BlackChecks)
+	}
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pieceLetters are the letters of pieces in FEN.
+const pieceLetters = "PNBRQKpnbrqk"
+
+// MarshalText encodes a square in lower case, like "e4".
+func (s Square) MarshalText() ([]byte, error) {
+	if s > H8 {
+		return nil, fmt.Errorf("invalid square: %d", s)
+	}
+	return []byte(strings.ToLower(s.String())), nil
+}
+
+// UnmarshalText decodes a square encoded by MarshalText.
+func (s *Square) UnmarshalText(b []byte) error {
+	if len(b) != 2 {
+		return fmt.Errorf("invalid square: %s", b)
+	}
+	f, r := File(b[0]-'a'), Rank(b[1]-'1')
+	if !f.Valid() || !r.Valid() {
+		return fmt.Errorf("invalid square: %s", b)
+	}
+	*s = NewSquare(f, r)
+	return nil
+}
+
+// MarshalText encodes a file as its letter, like "e".
+func (f File) MarshalText() ([]byte, error) {
+	if !f.Valid() {
+		return nil, fmt.Errorf("invalid file: %d", f)
+	}
+	return []byte{'a' + byte(f)}, nil
+}
+
+// UnmarshalText decodes a file encoded by MarshalText.
+func (f *File) UnmarshalText(b []byte) error {
+	if len(b) != 1 || !File(b[0]-'a').Valid() {
+		return fmt.Errorf("invalid file: %s", b)
+	}
+	*f = File(b[0] - 'a')
+	return nil
+}
+
+// MarshalText encodes a piece as its letter in FEN, like "N" or "n".
+func (p Piece) MarshalText() ([]byte, error) {
+	if !p.Valid() {
+		return nil, fmt.Errorf("invalid piece: %d", p)
+	}
+	return []byte{pieceLetters[p]}, nil
+}
+
+// UnmarshalText decodes a piece encoded by MarshalText.
+func (p *Piece) UnmarshalText(b []byte) error {
+	if len(b) == 1 {
+		if i := strings.IndexByte(pieceLetters, b[0]); i >= 0 {
+			*p = Piece(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid piece: %s", b)
+}
+
+// MarshalText encodes a piece type as its name in lower case, like "knight".
+func (p PieceType) MarshalText() ([]byte, error) {
+	if !p.Valid() {
+		return nil, fmt.Errorf("invalid piece type: %d", p)
+	}
+	return []byte(strings.ToLower(pieceTypeNames[p])), nil
+}
+
+// UnmarshalText decodes a piece type encoded by MarshalText.
+func (p *PieceType) UnmarshalText(b []byte) error {
+	for pt, name := range pieceTypeNames {
+		if string(b) == strings.ToLower(name) {
+			*p = PieceType(pt)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid piece type: %s", b)
+}
+
+// MarshalText encodes a color as "white" or "black".
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(c.String())), nil
+}
+
+// UnmarshalText decodes a color encoded by MarshalText.
+func (c *Color) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "white":
+		*c = White
+	case "black":
+		*c = Black
+	default:
+		return fmt.Errorf("invalid color: %s", b)
+	}
+	return nil
+}
+
+// MarshalText encodes a variant as its name in lower case, like "crazyhouse".
+func (v Variant) MarshalText() ([]byte, error) {
+	if !v.Valid() {
+		return nil, fmt.Errorf("invalid variant: %d", v)
+	}
+	return []byte(strings.ToLower(variantNames[v])), nil
+}
+
+// UnmarshalText decodes a variant encoded by MarshalText.
+func (v *Variant) UnmarshalText(b []byte) error {
+	for i, name := range variantNames {
+		if string(b) == strings.ToLower(name) {
+			*v = Variant(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid variant: %s", b)
+}
+
+// MarshalText encodes a move in pure coordinate notation, as described by
+// package encoding/pcn, like "e2e4", "a7a8q" or "N@f3".
+func (m Move) MarshalText() ([]byte, error) {
+	switch {
+	case m.Drop && (m.To > H8 || m.Dropped >= King):
+		return nil, fmt.Errorf("invalid drop: %s of %s", m.To, m.Dropped)
+	case !m.Drop && (m.From > H8 || m.To > H8 || !m.Promotion.Valid()):
+		return nil, fmt.Errorf("invalid move: %s to %s promoting to %s", m.From, m.To, m.Promotion)
+	}
+
+	to, _ := m.To.MarshalText()
+	if m.Drop {
+		return append([]byte{pieceLetters[NewPiece(White, m.Dropped)], '@'}, to...), nil
+	}
+	b, _ := m.From.MarshalText()
+	b = append(b, to...)
+	if m.Promotion != Pawn {
+		b = append(b, pieceLetters[NewPiece(Black, m.Promotion)])
+	}
+	return b, nil
+}
+
+// UnmarshalText decodes a move encoded by MarshalText.
+func (m *Move) UnmarshalText(b []byte) error {
+	var move Move
+	switch {
+	case len(b) == 4 && b[1] == '@':
+		// Kings can't be dropped.
+		i := strings.IndexByte(pieceLetters[:WhiteKing], b[0])
+		if i < 0 || move.To.UnmarshalText(b[2:]) != nil {
+			return fmt.Errorf("invalid move: %s", b)
+		}
+		move.Drop, move.Dropped = true, PieceType(i)
+	case len(b) == 4 || len(b) == 5:
+		if move.From.UnmarshalText(b[:2]) != nil || move.To.UnmarshalText(b[2:4]) != nil {
+			return fmt.Errorf("invalid move: %s", b)
+		}
+		if len(b) == 5 {
+			// Kings are promoted to in Antichess.
+			i := strings.IndexByte(pieceLetters[BlackKnight:], b[4])
+			if i < 0 {
+				return fmt.Errorf("invalid move: %s", b)
+			}
+			move.Promotion = Knight + PieceType(i)
+		}
+	default:
+		return fmt.Errorf("invalid move: %s", b)
+	}
+	*m = move
+	return nil
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"encoding"
+	"flag"
+	"testing"
+)
+
+func TestMarshalText(t *testing.T) {
+	cases := []struct {
+		in   encoding.TextMarshaler
+		want string
+	}{
+		{A1, "a1"},
+		{E4, "e4"},
+		{H8, "h8"},
+		{FileA, "a"},
+		{FileH, "h"},
+		{WhiteKnight, "N"},
+		{BlackPawn, "p"},
+		{BlackKing, "k"},
+		{Pawn, "pawn"},
+		{Knight, "knight"},
+		{King, "king"},
+		{White, "white"},
+		{Black, "black"},
+		{Standard, "standard"},
+		{KingOfTheHill, "kingofthehill"},
+		{Crazyhouse, "crazyhouse"},
+		{Move{From: E2, To: E4}, "e2e4"},
+		{Move{From: A7, To: A8, Promotion: Queen}, "a7a8q"},
+		{Move{From: B2, To: B1, Promotion: King}, "b2b1k"},
+		{Move{To: F3, Drop: true, Dropped: Knight}, "N@f3"},
+		{Move{To: E4, Drop: true, Dropped: Pawn}, "P@e4"},
+	}
+	for _, c := range cases {
+		got, err := c.in.MarshalText()
+		if err != nil {
+			t.Errorf("%#v: error: %v", c.in, err)
+			continue
+		}
+		if string(got) != c.want {
+			t.Errorf("%#v: got %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestMarshalText_Error(t *testing.T) {
+	cases := []encoding.TextMarshaler{
+		Square(64),
+		File(8),
+		Piece(12),
+		PieceType(6),
+		Variant(8),
+		Move{From: E2, To: Square(64)},
+		Move{From: E2, To: E4, Promotion: PieceType(6)},
+		Move{To: E4, Drop: true, Dropped: King},
+	}
+	for _, c := range cases {
+		if _, err := c.MarshalText(); err == nil {
+			t.Errorf("%#v: got nil error", c)
+		}
+	}
+}
+
+func TestUnmarshalText(t *testing.T) {
+	var (
+		s  Square
+		f  File
+		p  Piece
+		pt PieceType
+		c  Color
+		v  Variant
+		m  Move
+	)
+	cases := []struct {
+		in   string
+		v    encoding.TextUnmarshaler
+		want any
+	}{
+		{"e4", &s, E4},
+		{"h8", &s, H8},
+		{"c", &f, FileC},
+		{"n", &p, BlackKnight},
+		{"Q", &p, WhiteQueen},
+		{"rook", &pt, Rook},
+		{"black", &c, Black},
+		{"white", &c, White},
+		{"threecheck", &v, ThreeCheck},
+		{"racingkings", &v, RacingKings},
+		{"e7e8n", &m, Move{From: E7, To: E8, Promotion: Knight}},
+		{"Q@d5", &m, Move{To: D5, Drop: true, Dropped: Queen}},
+	}
+	for _, tc := range cases {
+		if err := tc.v.UnmarshalText([]byte(tc.in)); err != nil {
+			t.Errorf("%q: error: %v", tc.in, err)
+			continue
+		}
+		var got any
+		switch v := tc.v.(type) {
+		case *Square:
+			got = *v
+		case *File:
+			got = *v
+		case *Piece:
+			got = *v
+		case *PieceType:
+			got = *v
+		case *Color:
+			got = *v
+		case *Variant:
+			got = *v
+		case *Move:
+			got = *v
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestUnmarshalText_Error(t *testing.T) {
+	cases := []struct {
+		in string
+		v  encoding.TextUnmarshaler
+	}{
+		{"E4", new(Square)},
+		{"i1", new(Square)},
+		{"i", new(File)},
+		{"A", new(File)},
+		{"", new(Piece)},
+		{"NN", new(Piece)},
+		{"x", new(Piece)},
+		{"Knight", new(PieceType)},
+		{"n", new(PieceType)},
+		{"w", new(Color)},
+		{"Standard", new(Variant)},
+		{"e2e9", new(Move)},
+		{"K@e4", new(Move)},
+		{"e7e8p", new(Move)},
+		{"e2e4q1", new(Move)},
+	}
+	for _, tc := range cases {
+		if err := tc.v.UnmarshalText([]byte(tc.in)); err == nil {
+			t.Errorf("%q: got nil error", tc.in)
+		}
+	}
+}
+
+func TestSquare_TextVar(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var s Square
+	fs.TextVar(&s, "square", E2, "")
+	if err := fs.Parse([]string{"-square", "g7"}); err != nil {
+		t.Fatal(err)
+	}
+	if s != G7 {
+		t.Errorf("got %s, want %s", s, G7)
+	}
+}
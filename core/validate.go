@@ -0,0 +1,206 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by Validate.
+var (
+	ErrOverlap   = errors.New("pieces share a square")
+	ErrKings     = errors.New("wrong number of kings")
+	ErrPawnRank  = errors.New("pawn on the first or last rank")
+	ErrCheck     = errors.New("side not to move is in check")
+	ErrCastling  = errors.New("castling rights without the king and rook on their squares")
+	ErrEnPassant = errors.New("en passant square without a pushed pawn")
+	ErrRacing    = errors.New("king in check in Racing Kings")
+)
+
+// Validate returns an error if a position can't be played from: pieces share
+// a square, a side doesn't have exactly one king, a pawn is on the first or
+// last rank, the side not to move is in check, a side has castling rights
+// without its king and rook on their squares, or the en passant square isn't
+// behind a pawn that was just pushed two squares.
+//
+// In Antichess, any number of kings is allowed, and there's no check. In
+// Atomic, kings on neighboring squares aren't in check. In Horde, white has
+// no king, and can have pawns on the first rank. In Racing Kings, neither
+// side can be in check.
+func (p *Position) Validate() error {
+	var occupied Bitboard
+	for _, b := range p.Board {
+		if occupied.Intersects(b) {
+			return ErrOverlap
+		}
+		occupied.With(b)
+	}
+
+	for _, c := range []Color{White, Black} {
+		n := p.Board[NewPiece(c, King)].Count()
+		switch {
+		case p.Variant == Antichess:
+		case p.Variant == Horde && c == White:
+			if n != 0 {
+				return fmt.Errorf("%w: %s has %d", ErrKings, c, n)
+			}
+		case n != 1:
+			return fmt.Errorf("%w: %s has %d", ErrKings, c, n)
+		}
+	}
+
+	for f := FileA; f.Valid(); f++ {
+		for _, s := range []Square{NewSquare(f, Rank1), NewSquare(f, Rank8)} {
+			piece, ok := p.Board.Get(s)
+			if !ok || piece.Type() != Pawn || (p.Variant == Horde && piece == WhitePawn && s.Rank() == Rank1) {
+				continue
+			}
+			return fmt.Errorf("%w: %s", ErrPawnRank, s)
+		}
+	}
+
+	if p.inCheck(p.SideToMove.Other()) {
+		return ErrCheck
+	}
+	if p.Variant == RacingKings && p.inCheck(p.SideToMove) {
+		return ErrRacing
+	}
+
+	for _, r := range []struct {
+		ok       bool
+		c        Color
+		kingside bool
+		name     string
+	}{
+		{p.WhiteOO, White, true, "white kingside"},
+		{p.WhiteOOO, White, false, "white queenside"},
+		{p.BlackOO, Black, true, "black kingside"},
+		{p.BlackOOO, Black, false, "black queenside"},
+	} {
+		if r.ok && !p.canCastle(r.c, r.kingside) {
+			return fmt.Errorf("%w: %s", ErrCastling, r.name)
+		}
+	}
+
+	if p.EnPassant != 0 && !p.validEnPassant() {
+		return fmt.Errorf("%w: %s", ErrEnPassant, p.EnPassant)
+	}
+
+	return nil
+}
+
+// inCheck returns true if a color's king is in check, in variants where
+// there's a king to check.
+func (p *Position) inCheck(c Color) bool {
+	king := p.Board[NewPiece(c, King)]
+	if king.Count() != 1 || p.Variant == Antichess || (p.Variant == Atomic && p.KingsTouch()) {
+		return false
+	}
+	return p.Board.attacked(king.First(), c.Other())
+}
+
+// canCastle returns true if a color's king and castling rook are where a
+// castling right needs them: on the back rank, with the rook on the side it
+// castles to, and outside of Chess960, on their standard squares.
+func (p *Position) canCastle(c Color, kingside bool) bool {
+	king := p.Board[NewPiece(c, King)].First()
+	rook := p.CastlingRook(c, kingside)
+	if piece, ok := p.Board.Get(rook); !ok || piece != NewPiece(c, Rook) || king.Rank() != backRank(c) {
+		return false
+	}
+	if !p.Chess960 {
+		return king.File() == FileE
+	}
+	return (rook.File() > king.File()) == kingside
+}
+
+// validEnPassant returns true if the en passant square is empty, with a pawn
+// of the side not to move in front of it, and an empty square behind it that
+// the pawn was pushed from.
+func (p *Position) validEnPassant() bool {
+	ep := p.EnPassant
+	switch {
+	case p.SideToMove == White && ep.Rank() == Rank6:
+		piece, ok := p.Board.Get(ep.Below())
+		return ok && piece == BlackPawn && p.Board.IsEmpty(ep) && p.Board.IsEmpty(ep.Above())
+	case p.SideToMove == Black && ep.Rank() == Rank3:
+		piece, ok := p.Board.Get(ep.Above())
+		return ok && piece == WhitePawn && p.Board.IsEmpty(ep) && p.Board.IsEmpty(ep.Below())
+	default:
+		return false
+	}
+}
+
+// Steps to neighboring squares, as file and rank offsets.
+var (
+	knightSteps = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	rookSteps   = [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	bishopSteps = [][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	kingSteps   = append(rookSteps[:4:4], bishopSteps...)
+)
+
+// offset returns the square a step away from s, and false if it's off the
+// board.
+func offset(s Square, d [2]int) (Square, bool) {
+	f, r := int(s.File())+d[0], int(s.Rank())+d[1]
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return 0, false
+	}
+	return NewSquare(File(f), Rank(r)), true
+}
+
+// attacked returns true if a square is attacked by a color's pieces.
+func (b *Board) attacked(s Square, by Color) bool {
+	// has returns true if a square a step away holds a piece of by's.
+	has := func(d [2]int, pt PieceType) bool {
+		t, ok := offset(s, d)
+		if !ok {
+			return false
+		}
+		piece, ok := b.Get(t)
+		return ok && piece == NewPiece(by, pt)
+	}
+
+	// Pawns attack diagonally forward, so they're behind the square.
+	back := -1
+	if by == Black {
+		back = 1
+	}
+	if has([2]int{-1, back}, Pawn) || has([2]int{1, back}, Pawn) {
+		return true
+	}
+
+	for _, d := range knightSteps {
+		if has(d, Knight) {
+			return true
+		}
+	}
+	for _, d := range kingSteps {
+		if has(d, King) {
+			return true
+		}
+	}
+
+	// slides returns true if a slider of type pt or a queen attacks along a
+	// direction.
+	slides := func(d [2]int, pt PieceType) bool {
+		for t, ok := offset(s, d); ok; t, ok = offset(t, d) {
+			piece, ok := b.Get(t)
+			if !ok {
+				continue
+			}
+			return piece.Color() == by && (piece.Type() == pt || piece.Type() == Queen)
+		}
+		return false
+	}
+	for _, d := range rookSteps {
+		if slides(d, Rook) {
+			return true
+		}
+	}
+	for _, d := range bishopSteps {
+		if slides(d, Bishop) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPosition_Validate(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup func(p *Position)
+		want  error
+	}{
+		{"valid", func(p *Position) {}, nil},
+		{"no king", func(p *Position) { p.Board.Clear(E8) }, ErrKings},
+		{"two kings", func(p *Position) { p.Board.Set(WhiteKing, E4) }, ErrKings},
+		{"overlap", func(p *Position) { p.Board[WhiteQueen].Set(E2) }, ErrOverlap},
+		{"pawn on last rank", func(p *Position) { p.Board.Set(WhitePawn, B8) }, ErrPawnRank},
+		{"pawn on first rank", func(p *Position) { p.Board.Set(BlackPawn, G1) }, ErrPawnRank},
+		{"check", func(p *Position) { p.Board.Clear(F7); p.Board.Set(WhiteQueen, H5) }, ErrCheck},
+		{"missing rook", func(p *Position) { p.Board.Clear(H1) }, ErrCastling},
+		{"moved king", func(p *Position) { p.Board.Clear(E8); p.Board.Set(BlackKing, D6) }, ErrCastling},
+		{"no pushed pawn", func(p *Position) { p.EnPassant = E6 }, ErrEnPassant},
+		{"wrong rank", func(p *Position) { p.EnPassant = E3 }, ErrEnPassant},
+		{
+			"en passant",
+			func(p *Position) {
+				p.Board.Move(WhitePawn, E2, E4)
+				p.SideToMove, p.EnPassant = Black, E3
+			},
+			nil,
+		},
+		{"antichess without a king", func(p *Position) { p.Variant = Antichess; p.Board.Clear(E8); p.BlackOO, p.BlackOOO = false, false }, nil},
+		{"antichess check", func(p *Position) { p.Variant = Antichess; p.Board.Clear(F7); p.Board.Set(WhiteQueen, H5) }, nil},
+		{"atomic check", func(p *Position) { p.Variant = Atomic; p.Board.Clear(F7); p.Board.Set(WhiteQueen, H5) }, ErrCheck},
+		{
+			"atomic touching kings",
+			func(p *Position) {
+				p.Variant = Atomic
+				p.Board.Clear(E1)
+				p.Board.Set(WhiteKing, E7)
+				p.Board.Clear(E2)
+				p.Board.Set(WhiteRook, E2)
+				p.WhiteOO, p.WhiteOOO = false, false
+			},
+			nil,
+		},
+		{
+			"horde",
+			func(p *Position) {
+				p.Variant = Horde
+				p.Board.Clear(E1)
+				p.Board.Set(WhitePawn, E1)
+				p.WhiteOO, p.WhiteOOO = false, false
+			},
+			nil,
+		},
+		{"horde with a white king", func(p *Position) { p.Variant = Horde }, ErrKings},
+		{"racing kings check", func(p *Position) { p.Variant = RacingKings; p.Board.Clear(F2); p.Board.Set(BlackQueen, H4) }, ErrRacing},
+		{"check on the side to move", func(p *Position) { p.Board.Clear(F2); p.Board.Set(BlackQueen, H4) }, nil},
+	}
+
+	for _, tc := range cases {
+		p := NewPosition()
+		tc.setup(&p)
+		if got := p.Validate(); !errors.Is(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPosition_Validate_Chess960(t *testing.T) {
+	for n := range 960 {
+		p := NewChess960Position(n)
+		if err := p.Validate(); err != nil {
+			t.Errorf("%d: %v", n, err)
+		}
+	}
+}
+
+func TestBoard_attacked(t *testing.T) {
+	var b Board
+	b.Set(WhitePawn, D4)
+	b.Set(BlackKnight, F6)
+	b.Set(WhiteRook, A8)
+	b.Set(BlackBishop, H1)
+	b.Set(WhitePawn, E4)
+
+	cases := []struct {
+		s    Square
+		by   Color
+		want bool
+	}{
+		{E5, White, true},  // Pawn.
+		{D5, White, true},  // Pawn.
+		{D3, White, false}, // Pawns attack forward.
+		{D4, Black, false},
+		{E4, Black, true},  // Knight.
+		{A1, White, true},  // Rook.
+		{H8, White, true},  // Rook.
+		{G2, Black, true},  // Bishop.
+		{D5, Black, true},  // Knight.
+		{A8, Black, false}, // Bishop blocked by the pawn on e4.
+		{F3, Black, true},  // Bishop.
+	}
+
+	for _, tc := range cases {
+		if got := b.attacked(tc.s, tc.by); got != tc.want {
+			t.Errorf("attacked(%s, %s) = %t, want %t", tc.s, tc.by, got, tc.want)
+		}
+	}
+}
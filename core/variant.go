@@ -0,0 +1,58 @@
+package core
+
+import "fmt"
+
+// A Variant is a set of rules that changes standard chess.
+type Variant uint8
+
+// Variant constants.
+const (
+	Standard      Variant = iota
+	KingOfTheHill         // A king reaching the center wins.
+	ThreeCheck            // Checking the opponent three times wins.
+	Crazyhouse            // Captured pieces can be dropped back on the board.
+	Atomic                // Captures explode, and exploding the enemy king wins.
+	Antichess             // Captures are compulsory, and losing every piece wins.
+	Horde                 // White has many pawns and no king, and loses with no pieces left.
+	RacingKings           // There's no check, and a king reaching the eighth rank wins.
+)
+
+var variantNames = [...]string{
+	"Standard",
+	"KingOfTheHill",
+	"ThreeCheck",
+	"Crazyhouse",
+	"Atomic",
+	"Antichess",
+	"Horde",
+	"RacingKings",
+}
+
+func (v Variant) Valid() bool {
+	return int(v) < len(variantNames)
+}
+
+func (v Variant) String() string {
+	if v.Valid() {
+		return variantNames[v]
+	}
+	return fmt.Sprintf("Variant(%d)", v)
+}
+
+// Center holds the squares a king wins on in King of the Hill.
+const Center Bitboard = 1<<D4 | 1<<E4 | 1<<D5 | 1<<E5
+
+// makeVariant updates the variant state of a position after a move, once the
+// side to move has switched.
+func (p *Position) makeVariant() {
+	switch p.Variant {
+	case ThreeCheck:
+		if p.Board.attacked(p.FriendlyKing(), p.SideToMove.Other()) {
+			if p.SideToMove == Black {
+				p.WhiteChecks++
+			} else {
+				p.BlackChecks++
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Package dtm implements distance-to-mate endgame tables with up to four
+// pieces.
+//
+// A table stores, for each position with its material, the number of plies
+// to checkmate with optimal play, where the winning side mates as quickly as
+// possible and the losing side delays it as long as possible. The fifty-move
+// rule is ignored. Positions with castling rights aren't in tables, and since
+// en passant isn't supported, only one side may have pawns.
+//
+// Tables are symmetric, so only positions with the white king on the a1-d1-d4
+// triangle, or on files a-d if there are pawns, are stored. The table file
+// format is a 4-byte magic number, a byte with the length of the material,
+// the material, like "KRvKN", and a byte for each position.
+package dtm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/clfs/simple/core"
+)
+
+// magic starts every table file.
+const magic = "DTM1"
+
+// Each position is stored as a byte: 0 for a draw, the plies to checkmate
+// plus 1 otherwise, or invalid for positions that aren't legal or are stored
+// elsewhere by symmetry. The side to move wins if the plies are odd and loses
+// if they're even.
+const (
+	invalid  = 0xff
+	maxPlies = invalid - 2
+)
+
+// An Outcome is the outcome of a position for the side to move.
+type Outcome int
+
+// Outcome constants.
+const (
+	Loss Outcome = -1
+	Draw Outcome = 0
+	Win  Outcome = 1
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Loss:
+		return "loss"
+	case Draw:
+		return "draw"
+	case Win:
+		return "win"
+	default:
+		return fmt.Sprintf("Outcome(%d)", int(o))
+	}
+}
+
+// A Result is the result of a position with optimal play.
+type Result struct {
+	Outcome Outcome
+	Plies   int // Plies to checkmate. Zero for draws and checkmates.
+}
+
+func (r Result) String() string {
+	switch r.Outcome {
+	case Win:
+		return fmt.Sprintf("mate in %d", (r.Plies+1)/2)
+	case Loss:
+		return fmt.Sprintf("mated in %d", r.Plies/2)
+	default:
+		return r.Outcome.String()
+	}
+}
+
+// Errors returned when probing.
+var (
+	ErrMaterial = errors.New("position has different material")
+	ErrCastling = errors.New("position has castling rights")
+	ErrInvalid  = errors.New("invalid position")
+)
+
+// A Table is a distance-to-mate table for one material.
+type Table struct {
+	m    material
+	data []byte
+}
+
+// Material returns the material of a table, like "KRvKN".
+func (t *Table) Material() string {
+	return t.m.name
+}
+
+// Probe returns the result of a position with the table's material, or with
+// colors reversed.
+func (t *Table) Probe(p core.Position) (Result, error) {
+	if p.WhiteOO || p.WhiteOOO || p.BlackOO || p.BlackOOO {
+		return Result{}, ErrCastling
+	}
+	m, err := materialOf(p)
+	if err != nil {
+		return Result{}, ErrMaterial
+	}
+	switch m.name {
+	case t.m.name:
+	case reverse(t.m.name):
+		p = flip(p)
+	default:
+		return Result{}, ErrMaterial
+	}
+	return t.probe(p)
+}
+
+// probe returns the result of a position with the table's material.
+func (t *Table) probe(p core.Position) (Result, error) {
+	n := t.m.nodeOf(p)
+	return result(t.data[t.m.index(&n)])
+}
+
+// result decodes a stored position.
+func result(v byte) (Result, error) {
+	switch {
+	case v == invalid:
+		return Result{}, ErrInvalid
+	case v == 0:
+		return Result{Outcome: Draw}, nil
+	case v%2 == 0:
+		return Result{Outcome: Win, Plies: int(v) - 1}, nil
+	default:
+		return Result{Outcome: Loss, Plies: int(v) - 1}, nil
+	}
+}
+
+// Longest returns the most plies to checkmate of any position in the table.
+func (t *Table) Longest() int {
+	var longest int
+	for _, v := range t.data {
+		if v != invalid && v != 0 {
+			longest = max(longest, int(v)-1)
+		}
+	}
+	return longest
+}
+
+// reverse returns a material with colors reversed.
+func reverse(name string) string {
+	white, black, _ := strings.Cut(name, "v")
+	return black + "v" + white
+}
+
+// flip returns a position with the board mirrored vertically and colors
+// reversed.
+func flip(p core.Position) core.Position {
+	q := p
+	for piece, b := range p.Board {
+		b.FlipV()
+		q.Board[(piece+6)%12] = b
+	}
+	q.SideToMove = p.SideToMove.Other()
+	q.EnPassant = 0
+	return q
+}
+
+// Read reads a table.
+func Read(r io.Reader) (*Table, error) {
+	br := bufio.NewReader(r)
+
+	var header [len(magic) + 1]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, errors.New("bad magic number")
+	}
+
+	name := make([]byte, header[len(magic)])
+	if _, err := io.ReadFull(br, name); err != nil {
+		return nil, fmt.Errorf("reading material: %w", err)
+	}
+	m, err := parseMaterial(string(name))
+	if err != nil {
+		return nil, err
+	}
+	if m.name != string(name) {
+		return nil, fmt.Errorf("non-canonical material %q", name)
+	}
+
+	t := &Table{m: m, data: make([]byte, m.size())}
+	if _, err := io.ReadFull(br, t.data); err != nil {
+		return nil, fmt.Errorf("reading positions: %w", err)
+	}
+	if _, err := br.ReadByte(); err != io.EOF {
+		return nil, errors.New("trailing data")
+	}
+	return t, nil
+}
+
+// Open reads a table from a file.
+func Open(name string) (*Table, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return t, nil
+}
+
+// WriteTo writes the table to w.
+func (t *Table) WriteTo(w io.Writer) (int64, error) {
+	var (
+		bw = bufio.NewWriter(w)
+		n  int64
+	)
+	for _, b := range [][]byte{[]byte(magic), {byte(len(t.m.name))}, []byte(t.m.name), t.data} {
+		m, err := bw.Write(b)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
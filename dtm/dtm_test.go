@@ -0,0 +1,102 @@
+package dtm
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProbe(t *testing.T) {
+	tbl, err := Generate("KQK")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"k7/8/1K6/8/8/8/7Q/8 w - - 0 1", "mate in 1"},
+		{"8/7q/8/8/8/1k6/8/K7 b - - 0 1", "mate in 1"},
+		{"k7/1Q6/1K6/8/8/8/8/8 b - - 0 1", "mated in 0"},
+		{"k7/8/1QK5/8/8/8/8/8 b - - 0 1", "draw"},
+		{"8/8/8/8/8/8/1Q6/k6K b - - 0 1", "draw"},
+		{"8/8/8/8/3k4/8/8/KQ6 b - - 0 1", "mated in 9"},
+	}
+
+	for _, tc := range cases {
+		r, err := tbl.Probe(fen.MustDecode(tc.in))
+		if err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+			continue
+		}
+		if got := r.String(); got != tc.want {
+			t.Errorf("%q: got %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestProbe_Error(t *testing.T) {
+	tbl, err := Generate("KQvK")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		in   string
+		want error
+	}{
+		{"k7/8/8/8/8/8/8/K6R w - - 0 1", ErrMaterial},
+		{"k7/8/8/8/8/8/8/K6Q w - - 0 1", ErrInvalid},
+		{"k7/8/8/8/8/8/8/4K2Q w K - 0 1", ErrCastling},
+	}
+
+	for _, tc := range cases {
+		if _, err := tbl.Probe(fen.MustDecode(tc.in)); !errors.Is(err, tc.want) {
+			t.Errorf("%q: got %v, want %v", tc.in, err, tc.want)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	tbl, err := Generate("KRvK")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	n, err := tbl.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo() error: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo() = %d, wrote %d bytes", n, buf.Len())
+	}
+
+	got, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+	if got.Material() != "KRvK" {
+		t.Errorf("got material %s, want KRvK", got.Material())
+	}
+	if diff := cmp.Diff(tbl.data, got.data); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	b := buf.Bytes()
+	for name, in := range map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte("XXXX"), b[4:]...),
+		"material":  append([]byte("DTM1\x04KXvK"), b[10:]...),
+		"truncated": b[:len(b)-1],
+		"trailing":  append(b[:len(b):len(b)], 0),
+	} {
+		if _, err := Read(bytes.NewReader(in)); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+}
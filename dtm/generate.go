@@ -0,0 +1,218 @@
+package dtm
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/clfs/simple/core"
+)
+
+// Generate generates the table for a material, like "KRvKN" or "KRKN", with up
+// to four pieces. If black is stronger, the table is generated with colors
+// reversed, but it can still be probed with either.
+//
+// Tables are generated by retrograde analysis. Checkmates are found first,
+// then positions that lead to them, and so on, until no more positions can be
+// resolved; those left are draws. Captures and promotions lead to smaller
+// tables, which are generated first.
+func Generate(material string) (*Table, error) {
+	m, err := parseMaterial(material)
+	if err != nil {
+		return nil, err
+	}
+	m, _ = m.canonical()
+	g := &generator{tables: make(map[string]*Table)}
+	return g.generate(m)
+}
+
+// A generator generates tables, caching those that others depend on.
+type generator struct {
+	tables map[string]*Table
+}
+
+// noWin marks positions without a known win.
+const noWin = 0xff
+
+func (g *generator) generate(m material) (*Table, error) {
+	if t, ok := g.tables[m.name]; ok {
+		return t, nil
+	}
+	for _, sub := range m.subMaterials() {
+		if _, err := g.generate(sub); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		size = m.size()
+		t    = &Table{m: m, data: make([]byte, size)}
+		done = make([]bool, size)
+
+		// For each position, the number of children with this material
+		// that aren't known to be won, and whether a capture or promotion
+		// draws.
+		pending = make([]uint8, size)
+		drawn   = make([]bool, size)
+
+		// For each position, the fewest plies to a known win and the most
+		// plies to a known loss.
+		win  = make([]uint8, size)
+		loss = make([]uint8, size)
+
+		// Positions to resolve, by plies to checkmate.
+		queue = make([][]int32, maxPlies+1)
+	)
+
+	push := func(idx, plies int) error {
+		if plies > maxPlies {
+			return fmt.Errorf("%s: checkmate takes more than %d plies", m.name, maxPlies)
+		}
+		queue[plies] = append(queue[plies], int32(idx))
+		return nil
+	}
+
+	for idx := range size {
+		n := m.node(idx)
+		if m.index(&n) != idx || !m.legal(&n) {
+			t.data[idx] = invalid
+		}
+	}
+
+	var children []int
+	for idx := range size {
+		if t.data[idx] == invalid {
+			continue
+		}
+		n := m.node(idx)
+
+		children = children[:0]
+		win[idx] = noWin
+		legal := false
+		var err error
+		m.moves(&n, func(child *node, exit *core.Position) {
+			legal = true
+			if child != nil {
+				children = append(children, m.index(child))
+				return
+			}
+			r := g.probe(*exit)
+			switch r.Outcome {
+			case Draw:
+				drawn[idx] = true
+			case Win:
+				loss[idx] = max(loss[idx], uint8(r.Plies+1))
+			case Loss:
+				win[idx] = min(win[idx], uint8(r.Plies+1))
+			}
+		})
+		slices.Sort(children)
+		pending[idx] = uint8(len(slices.Compact(children)))
+
+		switch {
+		case !legal && m.inCheck(&n):
+			err = push(idx, 0)
+		case !legal:
+			done[idx] = true
+		case win[idx] != noWin:
+			err = push(idx, int(win[idx]))
+		case pending[idx] == 0 && !drawn[idx]:
+			err = push(idx, int(loss[idx]))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var parents []int
+	for plies := range maxPlies + 1 {
+		for i := 0; i < len(queue[plies]); i++ {
+			idx := int(queue[plies][i])
+			if done[idx] {
+				continue
+			}
+			done[idx] = true
+			t.data[idx] = uint8(plies + 1)
+
+			n := m.node(idx)
+			parents = parents[:0]
+			m.unmoves(&n, func(parent *node) {
+				if j := m.index(parent); t.data[j] != invalid {
+					parents = append(parents, j)
+				}
+			})
+			slices.Sort(parents)
+
+			for _, j := range slices.Compact(parents) {
+				if done[j] {
+					continue
+				}
+				var err error
+				if plies%2 == 0 {
+					// The side to move loses, so parents win.
+					if plies+1 < int(win[j]) {
+						win[j] = uint8(plies + 1)
+						err = push(j, plies+1)
+					}
+				} else {
+					// The side to move wins, so parents may lose.
+					pending[j]--
+					loss[j] = max(loss[j], uint8(plies+1))
+					if pending[j] == 0 && win[j] == noWin && !drawn[j] {
+						err = push(j, int(loss[j]))
+					}
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		queue[plies] = nil
+	}
+
+	g.tables[m.name] = t
+	return t, nil
+}
+
+// probe probes the table for the material of a position, which must have been
+// generated.
+func (g *generator) probe(p core.Position) Result {
+	m, _ := materialOf(p)
+	m, flipped := m.canonical()
+	if flipped {
+		p = flip(p)
+	}
+	r, _ := g.tables[m.name].probe(p)
+	return r
+}
+
+// subMaterials returns the materials that captures and promotions lead to.
+func (m *material) subMaterials() []material {
+	var subs []material
+	add := func(counts [2][6]int) {
+		sub, _ := newMaterial(counts)
+		sub, _ = sub.canonical()
+		if !slices.ContainsFunc(subs, func(s material) bool { return s.name == sub.name }) {
+			subs = append(subs, sub)
+		}
+	}
+
+	counts := m.counts()
+	for c := range counts {
+		for pt, n := range counts[c] {
+			if n == 0 {
+				continue
+			}
+			counts[c][pt]--
+			add(counts)
+			if core.PieceType(pt) == core.Pawn {
+				for _, promo := range promotions {
+					counts[c][promo]++
+					add(counts)
+					counts[c][promo]--
+				}
+			}
+			counts[c][pt]++
+		}
+	}
+	return subs
+}
@@ -0,0 +1,107 @@
+package dtm
+
+import (
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/movegen"
+)
+
+func TestGenerate_Longest(t *testing.T) {
+	// The longest checkmates, with black to move and losing.
+	cases := []struct {
+		material string
+		want     int
+	}{
+		{"KvK", 0},
+		{"KNvK", 0},
+		{"KQvK", 20},
+		{"KRvK", 32},
+		{"KPvK", 56},
+	}
+
+	for _, tc := range cases {
+		tbl, err := Generate(tc.material)
+		if err != nil {
+			t.Errorf("%s: %v", tc.material, err)
+			continue
+		}
+		if got := tbl.Longest(); got != tc.want {
+			t.Errorf("%s: got %d plies, want %d", tc.material, got, tc.want)
+		}
+	}
+}
+
+func TestGenerate_Error(t *testing.T) {
+	for _, in := range []string{"KQRvKR", "KPvKP", "K"} {
+		if _, err := Generate(in); err == nil {
+			t.Errorf("%q: got no error", in)
+		}
+	}
+}
+
+// TestGenerate_MoveGen checks generated tables against package movegen: each
+// position has the same legal moves, and its result follows from the results
+// of the positions they lead to.
+func TestGenerate_MoveGen(t *testing.T) {
+	for _, name := range []string{"KQvK", "KRvK", "KPvK"} {
+		m, err := parseMaterial(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := &generator{tables: make(map[string]*Table)}
+		tbl, err := g.generate(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var checked int
+		for idx := 0; idx < m.size(); idx += 7 {
+			if tbl.data[idx] == invalid {
+				continue
+			}
+			checked++
+
+			n := m.node(idx)
+			p := m.position(&n)
+			moves := movegen.LegalMoves(p)
+
+			var count int
+			m.moves(&n, func(*node, *core.Position) { count++ })
+			if count != len(moves) {
+				t.Errorf("%s: %d moves in %q, want %d", name, count, fen.Encode(p), len(moves))
+			}
+
+			var want Result
+			switch {
+			case len(moves) == 0 && movegen.InCheck(p):
+				want = Result{Outcome: Loss}
+			case len(moves) == 0:
+				want = Result{Outcome: Draw}
+			default:
+				want = Result{Outcome: Loss, Plies: -1}
+				for _, mv := range moves {
+					child := p
+					child.Make(mv)
+					r := g.probe(child)
+					switch {
+					case r.Outcome == Loss && (want.Outcome != Win || r.Plies+1 < want.Plies):
+						want = Result{Outcome: Win, Plies: r.Plies + 1}
+					case r.Outcome == Draw && want.Outcome == Loss:
+						want = Result{Outcome: Draw}
+					case r.Outcome == Win && want.Outcome == Loss:
+						want.Plies = max(want.Plies, r.Plies+1)
+					}
+				}
+			}
+
+			if got, _ := result(tbl.data[idx]); got != want {
+				t.Errorf("%s: got %v for %q, want %v", name, got, fen.Encode(p), want)
+			}
+		}
+		if checked == 0 {
+			t.Errorf("%s: no positions checked", name)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package dtm
+
+import (
+	"cmp"
+	"errors"
+	"strings"
+
+	"github.com/clfs/simple/core"
+)
+
+// maxPieces is the largest number of pieces in a table, including kings.
+const maxPieces = 4
+
+// A material is the set of pieces in a table.
+type material struct {
+	name   string       // Like "KRvKN".
+	pieces []core.Piece // The kings, then white's pieces, then black's.
+	pawns  bool
+}
+
+// pieceOrder is the order of pieces within a side, strongest first.
+var pieceOrder = []core.PieceType{core.Queen, core.Rook, core.Bishop, core.Knight, core.Pawn}
+
+var pieceLetters = [...]byte{
+	core.Pawn:   'P',
+	core.Knight: 'N',
+	core.Bishop: 'B',
+	core.Rook:   'R',
+	core.Queen:  'Q',
+	core.King:   'K',
+}
+
+var pieceValues = [...]int{
+	core.Pawn:   1,
+	core.Knight: 3,
+	core.Bishop: 3,
+	core.Rook:   5,
+	core.Queen:  9,
+	core.King:   0,
+}
+
+// parseMaterial parses a material like "KRvKN" or "KRKN".
+func parseMaterial(s string) (material, error) {
+	white, black, ok := strings.Cut(s, "v")
+	if !ok {
+		i := strings.LastIndexByte(s, 'K')
+		if i <= 0 {
+			return material{}, errors.New("invalid material")
+		}
+		white, black = s[:i], s[i:]
+	}
+
+	var counts [2][6]int
+	for i, side := range []string{white, black} {
+		if len(side) == 0 || side[0] != 'K' {
+			return material{}, errors.New("invalid material")
+		}
+		for j := 1; j < len(side); j++ {
+			pt := strings.IndexByte("PNBRQ", side[j])
+			if pt < 0 {
+				return material{}, errors.New("invalid material")
+			}
+			counts[i][pt]++
+		}
+	}
+	return newMaterial(counts)
+}
+
+// newMaterial returns the material with counts of each piece type, by color.
+func newMaterial(counts [2][6]int) (material, error) {
+	m := material{pieces: []core.Piece{core.WhiteKing, core.BlackKing}}
+	var name strings.Builder
+	for i, c := range []core.Color{core.White, core.Black} {
+		if i > 0 {
+			name.WriteByte('v')
+		}
+		name.WriteByte('K')
+		for _, pt := range pieceOrder {
+			for range counts[i][pt] {
+				m.pieces = append(m.pieces, core.NewPiece(c, pt))
+				name.WriteByte(pieceLetters[pt])
+			}
+		}
+	}
+	m.name = name.String()
+
+	if len(m.pieces) > maxPieces {
+		return material{}, errors.New("too many pieces")
+	}
+	// En passant isn't supported, so only one side can have pawns.
+	if counts[0][core.Pawn] > 0 && counts[1][core.Pawn] > 0 {
+		return material{}, errors.New("both sides have pawns")
+	}
+	m.pawns = counts[0][core.Pawn]+counts[1][core.Pawn] > 0
+	return m, nil
+}
+
+// counts returns the number of pieces of each type, by color.
+func (m *material) counts() [2][6]int {
+	var counts [2][6]int
+	for _, p := range m.pieces[2:] {
+		counts[p.Color().Uint64()][p.Type()]++
+	}
+	return counts
+}
+
+// strength orders the sides of a material.
+func strength(counts [6]int) (value, pieces int) {
+	for pt, n := range counts {
+		value += n * pieceValues[pt]
+		pieces += n
+	}
+	return value, pieces
+}
+
+// canonical returns the material with colors reversed if black is stronger,
+// and whether it reversed them. Tables are only generated with white at least
+// as strong as black.
+func (m *material) canonical() (material, bool) {
+	counts := m.counts()
+	wv, wn := strength(counts[0])
+	bv, bn := strength(counts[1])
+	c := cmp.Or(cmp.Compare(wv, bv), cmp.Compare(wn, bn))
+	for _, pt := range pieceOrder {
+		c = cmp.Or(c, cmp.Compare(counts[0][pt], counts[1][pt]))
+	}
+	if c >= 0 {
+		return *m, false
+	}
+	counts[0], counts[1] = counts[1], counts[0]
+	r, _ := newMaterial(counts)
+	return r, true
+}
+
+// materialOf returns the material of a position.
+func materialOf(p core.Position) (material, error) {
+	var counts [2][6]int
+	for piece, b := range p.Board {
+		pt := core.Piece(piece).Type()
+		if pt == core.King {
+			if b.Count() != 1 {
+				return material{}, errors.New("invalid number of kings")
+			}
+			continue
+		}
+		counts[core.Piece(piece).Color().Uint64()][pt] += b.Count()
+	}
+	return newMaterial(counts)
+}
+
+// Tables are indexed by the squares of each piece and the side to move. They
+// are symmetric, so the white king is restricted to the a1-d1-d4 triangle in
+// tables without pawns, which can be reflected and rotated, and to files a-d
+// in tables with pawns, which can only be mirrored.
+
+var (
+	pawnlessRegion, pawnRegion   [64]int // Index of the white king, or -1.
+	pawnlessSquares, pawnSquares []core.Square
+)
+
+func init() {
+	for s := core.A1; s <= core.H8; s++ {
+		f, r := s.File(), s.Rank()
+
+		pawnlessRegion[s] = -1
+		if f <= core.FileD && r <= core.Rank(f) {
+			pawnlessRegion[s] = len(pawnlessSquares)
+			pawnlessSquares = append(pawnlessSquares, s)
+		}
+
+		pawnRegion[s] = -1
+		if f <= core.FileD {
+			pawnRegion[s] = len(pawnSquares)
+			pawnSquares = append(pawnSquares, s)
+		}
+	}
+}
+
+// region returns the indices of the white king's squares, and the squares.
+func (m *material) region() (*[64]int, []core.Square) {
+	if m.pawns {
+		return &pawnRegion, pawnSquares
+	}
+	return &pawnlessRegion, pawnlessSquares
+}
+
+// size returns the number of indices in a table.
+func (m *material) size() int {
+	_, squares := m.region()
+	n := len(squares) * 2
+	for range len(m.pieces) - 1 {
+		n *= 64
+	}
+	return n
+}
+
+// transform applies one of eight symmetries to a square. Symmetries 0 and 1
+// preserve pawns.
+func transform(s core.Square, k int) core.Square {
+	if k&4 != 0 {
+		s = s>>3 | (s&7)<<3
+	}
+	if k&2 != 0 {
+		s ^= 56
+	}
+	if k&1 != 0 {
+		s ^= 7
+	}
+	return s
+}
+
+// index returns the index of a node. Symmetric nodes, and nodes that only
+// differ by swapping identical pieces, have the same index: the lowest of
+// theirs.
+func (m *material) index(n *node) int {
+	region, _ := m.region()
+	symmetries := 8
+	if m.pawns {
+		symmetries = 2
+	}
+
+	best := -1
+	for k := range symmetries {
+		wk := region[transform(n.sq[0], k)]
+		if wk < 0 {
+			continue
+		}
+
+		var sq [maxPieces]core.Square
+		for i := range m.pieces {
+			sq[i] = transform(n.sq[i], k)
+		}
+		// Sort identical pieces by square.
+		for i := 3; i < len(m.pieces); i++ {
+			for j := i; j > 2 && m.pieces[j] == m.pieces[j-1] && sq[j] < sq[j-1]; j-- {
+				sq[j], sq[j-1] = sq[j-1], sq[j]
+			}
+		}
+
+		idx := wk
+		for _, s := range sq[1:len(m.pieces)] {
+			idx = idx*64 + int(s)
+		}
+		idx = idx*2 + int(n.stm.Uint64())
+
+		if best < 0 || idx < best {
+			best = idx
+		}
+	}
+	return best
+}
+
+// node returns the node at an index.
+func (m *material) node(idx int) node {
+	_, squares := m.region()
+	n := node{stm: idx&1 == 1}
+	idx >>= 1
+	for i := len(m.pieces) - 1; i > 0; i-- {
+		n.sq[i] = core.Square(idx & 63)
+		idx >>= 6
+	}
+	n.sq[0] = squares[idx]
+	return n
+}
+
+// nodeOf returns the node of a position with the material.
+func (m *material) nodeOf(p core.Position) node {
+	n := node{stm: p.SideToMove}
+	b := p.Board
+	for i, piece := range m.pieces {
+		n.sq[i] = b[piece].First()
+		b[piece].Clear(n.sq[i])
+	}
+	return n
+}
+
+// position returns the position of a node.
+func (m *material) position(n *node) core.Position {
+	p := core.Position{SideToMove: n.stm, FullMoveNumber: 1}
+	for i, piece := range m.pieces {
+		p.Board.SetOnEmpty(piece, n.sq[i])
+	}
+	return p
+}
@@ -0,0 +1,115 @@
+package dtm
+
+import (
+	"testing"
+
+	"github.com/clfs/simple/core"
+)
+
+func TestParseMaterial(t *testing.T) {
+	cases := []struct {
+		in        string
+		want      string
+		canonical string
+		wantErr   bool
+	}{
+		{in: "KQvK", want: "KQvK", canonical: "KQvK"},
+		{in: "KQK", want: "KQvK", canonical: "KQvK"},
+		{in: "KRKN", want: "KRvKN", canonical: "KRvKN"},
+		{in: "KNKR", want: "KNvKR", canonical: "KRvKN"},
+		{in: "KvKP", want: "KvKP", canonical: "KPvK"},
+		{in: "KNRvK", want: "KRNvK", canonical: "KRNvK"},
+		{in: "KvK", want: "KvK", canonical: "KvK"},
+		{in: "KBvKN", want: "KBvKN", canonical: "KBvKN"},
+		{in: "KNvKB", want: "KNvKB", canonical: "KBvKN"},
+		{in: "KQRvKR", wantErr: true},
+		{in: "KPvKP", wantErr: true},
+		{in: "QvK", wantErr: true},
+		{in: "KQ", wantErr: true},
+		{in: "KXvK", wantErr: true},
+		{in: "KKvK", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		m, err := parseMaterial(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%q: got no error", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+			continue
+		}
+		c, _ := m.canonical()
+		if m.name != tc.want || c.name != tc.canonical {
+			t.Errorf("%q: got %s and %s, want %s and %s", tc.in, m.name, c.name, tc.want, tc.canonical)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	for _, name := range []string{"KRvK", "KPvK", "KNNvK"} {
+		m, err := parseMaterial(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		symmetries := 8
+		if m.pawns {
+			symmetries = 2
+		}
+
+		for idx := 0; idx < m.size(); idx += 97 {
+			n := m.node(idx)
+			want := m.index(&n)
+			if got := m.node(want); m.index(&got) != want {
+				t.Errorf("%s: index %d isn't canonical", name, want)
+			}
+
+			for k := range symmetries {
+				var s node
+				s.stm = n.stm
+				for i := range m.pieces {
+					s.sq[i] = transform(n.sq[i], k)
+				}
+				if got := m.index(&s); got != want {
+					t.Errorf("%s: index %d with symmetry %d is %d", name, want, k, got)
+				}
+			}
+
+			if len(m.pieces) == 4 {
+				s := n
+				s.sq[2], s.sq[3] = s.sq[3], s.sq[2]
+				if got := m.index(&s); got != want {
+					t.Errorf("%s: index %d with swapped knights is %d", name, want, got)
+				}
+			}
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	cases := []struct {
+		s    core.Square
+		k    int
+		want core.Square
+	}{
+		{core.B1, 0, core.B1},
+		{core.B1, 1, core.G1},
+		{core.B1, 2, core.B8},
+		{core.B1, 3, core.G8},
+		{core.B1, 4, core.A2},
+		{core.B1, 5, core.H2},
+		{core.B1, 6, core.A7},
+		{core.B1, 7, core.H7},
+	}
+
+	for _, tc := range cases {
+		if got := transform(tc.s, tc.k); got != tc.want {
+			t.Errorf("transform(%s, %d) = %s, want %s", tc.s, tc.k, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package dtm
+
+import "github.com/clfs/simple/core"
+
+// A node is a position in a table, with a square for each of the material's
+// pieces.
+type node struct {
+	sq  [maxPieces]core.Square
+	stm core.Color
+}
+
+// A board holds the piece of a node on each square, or -1.
+type board [64]int8
+
+func (m *material) board(n *node) board {
+	var b board
+	for i := range b {
+		b[i] = -1
+	}
+	for i := range m.pieces {
+		b[n.sq[i]] = int8(i)
+	}
+	return b
+}
+
+// kingIndex returns the index of a color's king in a material.
+func kingIndex(c core.Color) int {
+	return int(c.Uint64())
+}
+
+// Directions, as file and rank steps.
+var (
+	rookDirections   = [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	bishopDirections = [][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	queenDirections  = append(rookDirections[:4:4], bishopDirections...)
+	knightSteps      = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+)
+
+// step returns the square a step away from s, and false if it's off the board.
+func step(s core.Square, d [2]int) (core.Square, bool) {
+	f, r := int(s.File())+d[0], int(s.Rank())+d[1]
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return 0, false
+	}
+	return core.NewSquare(core.File(f), core.Rank(r)), true
+}
+
+// targets calls f for each square a piece other than a pawn moves to or
+// attacks, whether it's empty or not.
+func targets(pt core.PieceType, from core.Square, b *board, f func(to core.Square)) {
+	switch pt {
+	case core.Knight, core.King:
+		steps := knightSteps
+		if pt == core.King {
+			steps = queenDirections
+		}
+		for _, d := range steps {
+			if to, ok := step(from, d); ok {
+				f(to)
+			}
+		}
+	default:
+		directions := queenDirections
+		switch pt {
+		case core.Rook:
+			directions = rookDirections
+		case core.Bishop:
+			directions = bishopDirections
+		}
+		for _, d := range directions {
+			for to, ok := step(from, d); ok; to, ok = step(to, d) {
+				f(to)
+				if b[to] >= 0 {
+					break
+				}
+			}
+		}
+	}
+}
+
+// attacks returns true if a piece on from attacks to.
+func attacks(piece core.Piece, from, to core.Square, b *board) bool {
+	df := int(to.File()) - int(from.File())
+	dr := int(to.Rank()) - int(from.Rank())
+	switch piece.Type() {
+	case core.Pawn:
+		if piece.Color() == core.Black {
+			dr = -dr
+		}
+		return dr == 1 && (df == 1 || df == -1)
+	case core.Knight:
+		return df*df+dr*dr == 5
+	case core.King:
+		return max(df, -df, dr, -dr) == 1
+	case core.Bishop:
+		return df != 0 && (df == dr || df == -dr) && unblocked(from, to, b)
+	case core.Rook:
+		return (df == 0) != (dr == 0) && unblocked(from, to, b)
+	default:
+		return (df != 0 && (df == dr || df == -dr) || (df == 0) != (dr == 0)) && unblocked(from, to, b)
+	}
+}
+
+// unblocked returns true if the squares between two squares on a line are
+// empty.
+func unblocked(from, to core.Square, b *board) bool {
+	d := [2]int{
+		sign(int(to.File()) - int(from.File())),
+		sign(int(to.Rank()) - int(from.Rank())),
+	}
+	for s, _ := step(from, d); s != to; s, _ = step(s, d) {
+		if b[s] >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func sign(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// attacked returns true if a square is attacked by a color, ignoring the
+// piece at index skip, if any.
+func (m *material) attacked(n *node, b *board, s core.Square, by core.Color, skip int) bool {
+	for i, piece := range m.pieces {
+		if i != skip && piece.Color() == by && attacks(piece, n.sq[i], s, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// inCheck returns true if the side to move is in check.
+func (m *material) inCheck(n *node) bool {
+	b := m.board(n)
+	return m.attacked(n, &b, n.sq[kingIndex(n.stm)], n.stm.Other(), -1)
+}
+
+// legal returns true if a node is a legal position: pieces are on different
+// squares, pawns aren't on the first or last rank, and the side not to move
+// isn't in check.
+func (m *material) legal(n *node) bool {
+	var occupied core.Bitboard
+	for i, piece := range m.pieces {
+		s := n.sq[i]
+		if occupied.Get(s) {
+			return false
+		}
+		occupied.Set(s)
+		if piece.Type() == core.Pawn && (s.Rank() == core.Rank1 || s.Rank() == core.Rank8) {
+			return false
+		}
+	}
+	b := m.board(n)
+	return !m.attacked(n, &b, n.sq[kingIndex(n.stm.Other())], n.stm, -1)
+}
+
+// promotions are the piece types pawns promote to.
+var promotions = []core.PieceType{core.Queen, core.Rook, core.Bishop, core.Knight}
+
+// moves calls f for each legal move in a node. Moves that keep the material
+// lead to a child node; captures and promotions lead to a position with other
+// material.
+func (m *material) moves(n *node, f func(child *node, exit *core.Position)) {
+	b := m.board(n)
+	for i, piece := range m.pieces {
+		if piece.Color() != n.stm {
+			continue
+		}
+		if piece.Type() != core.Pawn {
+			targets(piece.Type(), n.sq[i], &b, func(to core.Square) {
+				m.play(n, &b, i, to, piece.Type(), f)
+			})
+			continue
+		}
+
+		var (
+			forward = 1
+			start   = core.Rank2
+			last    = core.Rank8
+		)
+		if n.stm == core.Black {
+			forward, start, last = -1, core.Rank7, core.Rank1
+		}
+		push := func(to core.Square) {
+			if to.Rank() != last {
+				m.play(n, &b, i, to, core.Pawn, f)
+				return
+			}
+			for _, pt := range promotions {
+				m.play(n, &b, i, to, pt, f)
+			}
+		}
+
+		from := n.sq[i]
+		if to, ok := step(from, [2]int{0, forward}); ok && b[to] < 0 {
+			push(to)
+			if to2, _ := step(to, [2]int{0, forward}); from.Rank() == start && b[to2] < 0 {
+				push(to2)
+			}
+		}
+		for _, df := range []int{-1, 1} {
+			if to, ok := step(from, [2]int{df, forward}); ok && b[to] >= 0 {
+				push(to)
+			}
+		}
+	}
+}
+
+// play calls f for the move of the piece at index i to a square, if it's
+// legal. The piece becomes pt, which differs from its type for promotions.
+func (m *material) play(n *node, b *board, i int, to core.Square, pt core.PieceType, f func(*node, *core.Position)) {
+	captured := int(b[to])
+	if captured >= 0 && m.pieces[captured].Color() == n.stm {
+		return
+	}
+
+	child := *n
+	child.sq[i] = to
+	child.stm = n.stm.Other()
+
+	from := n.sq[i]
+	b[from], b[to] = -1, int8(i)
+	legal := !m.attacked(&child, b, child.sq[kingIndex(n.stm)], child.stm, captured)
+	b[from], b[to] = int8(i), int8(captured)
+	if !legal {
+		return
+	}
+
+	if captured < 0 && pt == m.pieces[i].Type() {
+		f(&child, nil)
+		return
+	}
+
+	p := core.Position{SideToMove: child.stm, FullMoveNumber: 1}
+	for j, piece := range m.pieces {
+		switch j {
+		case captured:
+			continue
+		case i:
+			piece = core.NewPiece(n.stm, pt)
+		}
+		p.Board.SetOnEmpty(piece, child.sq[j])
+	}
+	f(nil, &p)
+}
+
+// unmoves calls f for each parent of a node: a node with the other side to
+// move, from which a move that neither captures nor promotes leads to it.
+// Parents may not be legal.
+func (m *material) unmoves(n *node, f func(parent *node)) {
+	b := m.board(n)
+	mover := n.stm.Other()
+	for i, piece := range m.pieces {
+		if piece.Color() != mover {
+			continue
+		}
+		parent := *n
+		parent.stm = mover
+
+		if piece.Type() != core.Pawn {
+			targets(piece.Type(), n.sq[i], &b, func(from core.Square) {
+				if b[from] < 0 {
+					parent.sq[i] = from
+					f(&parent)
+				}
+			})
+			continue
+		}
+
+		var (
+			back   = -1
+			double = core.Rank4
+		)
+		if mover == core.Black {
+			back, double = 1, core.Rank5
+		}
+		from, ok := step(n.sq[i], [2]int{0, back})
+		if !ok || b[from] >= 0 || from.Rank() == core.Rank1 || from.Rank() == core.Rank8 {
+			continue
+		}
+		parent.sq[i] = from
+		f(&parent)
+		if from2, _ := step(from, [2]int{0, back}); n.sq[i].Rank() == double && b[from2] < 0 {
+			parent.sq[i] = from2
+			f(&parent)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/clfs/simple/core"
 )
@@ -12,6 +13,9 @@ import (
 // numRegexp matches any non-negative integer.
 var numRegexp = regexp.MustCompile(`^(0|[1-9]\d*)$`)
 
+// checksRegexp matches the checks given by each side in Three-check.
+var checksRegexp = regexp.MustCompile(`^\+([0-3])\+([0-3])$`)
+
 var decodePiece = map[rune]core.Piece{
 	'P': core.WhitePawn,
 	'N': core.WhiteKnight,
@@ -55,16 +59,26 @@ func Decode(s string) (core.Position, error) {
 	var p core.Position
 
 	fields := strings.Split(s, " ")
-	if n := len(fields); n != 6 {
+	if n := len(fields); n != 6 && n != 7 {
 		return core.Position{}, fmt.Errorf("invalid number of fields: %d", n)
 	}
 
 	// TODO(cfiguereosupran): See if there's a way to iterate over the board
 	// squares in order, rather than jump around according to the FEN input.
 
+	// Pockets in Crazyhouse.
+	board := fields[0]
+	if i := strings.IndexByte(board, '['); i >= 0 && strings.HasSuffix(board, "]") {
+		if err := decodePockets(&p, board[i+1:len(board)-1]); err != nil {
+			return core.Position{}, err
+		}
+		board = board[:i]
+		p.Variant = core.Crazyhouse
+	}
+
 	// Board.
 	offset := int(core.A8) // top left corner
-	rows := strings.Split(fields[0], "/")
+	rows := strings.Split(board, "/")
 	if len(rows) != 8 {
 		return core.Position{}, fmt.Errorf("invalid number of board rows: %d", len(rows))
 	}
@@ -78,6 +92,15 @@ func Decode(s string) (core.Position, error) {
 				}
 				offset += int(r - '0') // advance rightwards by n
 				numPrev = true
+			case '~':
+				// Promoted pieces in Crazyhouse are followed by a tilde.
+				prev := core.Square(offset - 1)
+				piece, ok := p.Board.Get(prev)
+				if p.Variant != core.Crazyhouse || numPrev || offset == 8*(7-i) || !ok ||
+					piece.Type() == core.Pawn || piece.Type() == core.King || p.Promoted.Get(prev) {
+					return core.Position{}, fmt.Errorf("invalid promoted piece in board row: %s", row)
+				}
+				p.Promoted.Set(prev)
 			default:
 				piece, ok := decodePiece[r]
 				if !ok {
@@ -108,40 +131,8 @@ func Decode(s string) (core.Position, error) {
 	}
 
 	// Castling rights.
-	switch fields[2] {
-	case "-":
-	case "K":
-		p.WhiteOO = true
-	case "Q":
-		p.WhiteOOO = true
-	case "k":
-		p.BlackOO = true
-	case "q":
-		p.BlackOOO = true
-	case "KQ":
-		p.WhiteOO, p.WhiteOOO = true, true
-	case "Kk":
-		p.WhiteOO, p.BlackOO = true, true
-	case "Kq":
-		p.WhiteOO, p.BlackOOO = true, true
-	case "Qk":
-		p.WhiteOOO, p.BlackOO = true, true
-	case "Qq":
-		p.WhiteOOO, p.BlackOOO = true, true
-	case "kq":
-		p.BlackOO, p.BlackOOO = true, true
-	case "KQk":
-		p.WhiteOO, p.WhiteOOO, p.BlackOO = true, true, true
-	case "KQq":
-		p.WhiteOO, p.WhiteOOO, p.BlackOOO = true, true, true
-	case "Kkq":
-		p.WhiteOO, p.BlackOO, p.BlackOOO = true, true, true
-	case "Qkq":
-		p.WhiteOOO, p.BlackOO, p.BlackOOO = true, true, true
-	case "KQkq":
-		p.WhiteOO, p.WhiteOOO, p.BlackOO, p.BlackOOO = true, true, true, true
-	default:
-		return core.Position{}, fmt.Errorf("invalid castling rights: %s", fields[2])
+	if err := decodeCastling(&p, fields[2]); err != nil {
+		return core.Position{}, err
 	}
 
 	// En passant square.
@@ -187,5 +178,161 @@ func Decode(s string) (core.Position, error) {
 	}
 	p.FullMoveNumber = fmn
 
+	// Checks given in Three-check.
+	if len(fields) == 7 {
+		m := checksRegexp.FindStringSubmatch(fields[6])
+		if m == nil || p.Variant != core.Standard {
+			return core.Position{}, fmt.Errorf("invalid checks: %s", fields[6])
+		}
+		p.Variant = core.ThreeCheck
+		p.WhiteChecks = int(m[1][0] - '0')
+		p.BlackChecks = int(m[2][0] - '0')
+	}
+
+	return p, nil
+}
+
+// DecodeStrict is like Decode, but also returns an error if the position
+// isn't valid, as reported by core.Position.Validate.
+func DecodeStrict(s string) (core.Position, error) {
+	p, err := Decode(s)
+	if err != nil {
+		return core.Position{}, err
+	}
+	if err := p.Validate(); err != nil {
+		return core.Position{}, err
+	}
 	return p, nil
 }
+
+// decodeCastling decodes castling rights, in standard notation, X-FEN or
+// Shredder-FEN.
+//
+// In standard notation and X-FEN, K and Q are the rights to castle with the
+// outermost rooks on each side of the king, with k and q for black. X-FEN also
+// allows the file of the rook instead, like C or c, if it isn't the outermost.
+// Shredder-FEN only uses files. Positions are Chess960 if a file is used, or a
+// castling king or rook isn't on its standard square.
+func decodeCastling(p *core.Position, s string) error {
+	switch s {
+	case "-":
+		return nil
+	case "":
+		return fmt.Errorf("invalid castling rights: %s", s)
+	}
+
+	var (
+		rights = [...]*bool{&p.WhiteOO, &p.WhiteOOO, &p.BlackOO, &p.BlackOOO}
+		files  = [...]*core.File{&p.WhiteOOFile, &p.WhiteOOOFile, &p.BlackOOFile, &p.BlackOOOFile}
+		xfen   = strings.ContainsAny(s, "KQkq")
+		next   int
+	)
+
+	for _, r := range s {
+		c := core.White
+		if unicode.IsLower(r) {
+			c = core.Black
+		}
+		rank := core.Rank1
+		if c == core.Black {
+			rank = core.Rank8
+		}
+
+		// Find the king on the back rank.
+		var (
+			king      core.File
+			kingFound bool
+		)
+		if b := p.Board[core.NewPiece(c, core.King)]; b.Count() == 1 && b.First().Rank() == rank {
+			king, kingFound = b.First().File(), true
+		}
+
+		// outermost returns the file of the outermost rook on a side of the
+		// king, if any.
+		outermost := func(kingside bool) (core.File, bool) {
+			if !kingFound {
+				return 0, false
+			}
+			f, end, step := core.FileA, king, 1
+			if kingside {
+				f, end, step = core.FileH, king, -1
+			}
+			for ; f != end; f = core.File(int(f) + step) {
+				if piece, ok := p.Board.Get(core.NewSquare(f, rank)); ok && piece == core.NewPiece(c, core.Rook) {
+					return f, true
+				}
+			}
+			return 0, false
+		}
+
+		var (
+			kingside bool
+			file     core.File
+		)
+		switch upper := unicode.ToUpper(r); upper {
+		case 'K', 'Q':
+			kingside = upper == 'K'
+			f, ok := outermost(kingside)
+			switch {
+			case ok:
+				file = f
+				if king != core.FileE || (kingside && f != core.FileH) || (!kingside && f != core.FileA) {
+					p.Chess960 = true
+				}
+			case kingside:
+				file = core.FileH
+			default:
+				file = core.FileA
+			}
+		case 'A', 'B', 'C', 'D', 'E', 'F', 'G', 'H':
+			file = core.File(upper - 'A')
+			piece, ok := p.Board.Get(core.NewSquare(file, rank))
+			if !kingFound || file == king || !ok || piece != core.NewPiece(c, core.Rook) {
+				return fmt.Errorf("invalid castling rights: %s", s)
+			}
+			kingside = file > king
+			// X-FEN only uses files for rooks that aren't the outermost.
+			if f, _ := outermost(kingside); xfen && f == file {
+				return fmt.Errorf("invalid castling rights: %s", s)
+			}
+			p.Chess960 = true
+		default:
+			return fmt.Errorf("invalid castling rights: %s", s)
+		}
+
+		i := 2 * int(c.Uint64())
+		if !kingside {
+			i++
+		}
+		if i < next {
+			return fmt.Errorf("invalid castling rights: %s", s)
+		}
+		next = i + 1
+		*rights[i], *files[i] = true, file
+	}
+
+	// Rook files are only kept for Chess960 positions.
+	if !p.Chess960 {
+		p.WhiteOOFile, p.WhiteOOOFile, p.BlackOOFile, p.BlackOOOFile = 0, 0, 0, 0
+	}
+	return nil
+}
+
+// pocketOrder is the order of pieces in pockets.
+const pocketOrder = "QRBNPqrbnp"
+
+// decodePockets decodes the pieces in the pockets of a Crazyhouse position,
+// which must be in the order of pocketOrder, like "QNPPbp".
+func decodePockets(p *core.Position, s string) error {
+	var next int
+	for _, r := range s {
+		i := strings.IndexRune(pocketOrder, r)
+		if i < next {
+			return fmt.Errorf("invalid pockets: %s", s)
+		}
+		next = i
+		piece := decodePiece[r]
+		p.Pocket(piece.Color())[piece.Type()]++
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package fen
 
 import (
 	"bufio"
+	"errors"
 	"os"
 	"testing"
 
@@ -165,3 +166,198 @@ func TestDecode_PiecePlacements(t *testing.T) {
 		}
 	}
 }
+
+func TestDecode_Chess960(t *testing.T) {
+	cases := []struct {
+		in   string
+		want core.Position
+	}{
+		{
+			"bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1",
+			core.NewChess960Position(0),
+		},
+		{
+			"bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w HFhf - 0 1",
+			core.NewChess960Position(0),
+		},
+		{
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1",
+			core.NewChess960Position(518),
+		},
+		{
+			"rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKRNNQBB w CAca - 0 1",
+			core.NewChess960Position(959),
+		},
+	}
+
+	for i, tc := range cases {
+		got, err := Decode(tc.in)
+		if err != nil {
+			t.Errorf("#%d: Decode() error: %v", i, err)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("#%d: Decode() mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+func TestDecode_Chess960InnerRook(t *testing.T) {
+	want := core.Position{
+		Chess960:       true,
+		WhiteOO:        true,
+		WhiteOOO:       true,
+		BlackOOO:       true,
+		WhiteOOFile:    core.FileG,
+		WhiteOOOFile:   core.FileB,
+		BlackOOOFile:   core.FileB,
+		FullMoveNumber: 1,
+	}
+	for s, piece := range map[core.Square]core.Piece{
+		core.B1: core.WhiteRook,
+		core.E1: core.WhiteKing,
+		core.G1: core.WhiteRook,
+		core.H1: core.WhiteRook,
+		core.B8: core.BlackRook,
+		core.E8: core.BlackKing,
+		core.H8: core.BlackRook,
+	} {
+		want.Board.Set(piece, s)
+	}
+
+	for _, in := range []string{
+		"1r2k2r/8/8/8/8/8/8/1R2K1RR w GQq - 0 1",
+		"1r2k2r/8/8/8/8/8/8/1R2K1RR w GBb - 0 1",
+	} {
+		got, err := Decode(in)
+		if err != nil {
+			t.Errorf("Decode(%q) error: %v", in, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Decode(%q) mismatch (-want +got):\n%s", in, diff)
+		}
+	}
+}
+
+func TestDecode_InvalidCastling(t *testing.T) {
+	cases := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w QK - 0 1",   // Out of order.
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KKkq - 0 1", // Repeated.
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HQkq - 0 1", // Outermost rook by file.
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w GAga - 0 1", // No rook.
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w Z - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w  - 0 1",
+	}
+
+	for _, in := range cases {
+		if _, err := Decode(in); err == nil {
+			t.Errorf("Decode(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	cases := []struct {
+		in   string
+		want error
+	}{
+		{Starting, nil},
+		{"bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w HFhf - 0 1", nil},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", nil},
+		{"8/8/8/8/8/8/8/8 w - - 0 1", core.ErrKings},
+		{"4k3/8/8/8/8/8/8/3KK3 w - - 0 1", core.ErrKings},
+		{"P3k3/8/8/8/8/8/8/4K3 w - - 0 1", core.ErrPawnRank},
+		{"4k3/8/8/8/8/8/8/4K2p w - - 0 1", core.ErrPawnRank},
+		{"4k3/8/8/8/8/8/8/4K2R w - - 0 1", nil},
+		{"4k3/8/8/8/8/8/8/4R1K1 w - - 0 1", core.ErrCheck},
+		{"4k3/8/8/8/8/8/8/4K3 w K - 0 1", core.ErrCastling},
+		{"4k3/8/8/8/8/8/4K3/7R w K - 0 1", core.ErrCastling},
+		{"4k3/8/8/8/8/8/8/4K3 w - e6 0 1", core.ErrEnPassant},
+		{"4k3/8/8/4p3/8/8/8/4K3 w - e6 0 1", nil},
+		{"4k3/4p3/8/4p3/8/8/8/4K3 w - e6 0 1", core.ErrEnPassant},
+	}
+
+	for _, tc := range cases {
+		_, err := DecodeStrict(tc.in)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("DecodeStrict(%q): got %v, want %v", tc.in, err, tc.want)
+		}
+	}
+}
+
+func TestDecodeStrict_Valid(t *testing.T) {
+	for _, fen := range readFENs(t, "testdata/valid.fen") {
+		if _, err := DecodeStrict(fen); err != nil {
+			t.Errorf("DecodeStrict(%q) error: %v", fen, err)
+		}
+	}
+}
+
+func TestDecode_ThreeCheck(t *testing.T) {
+	want := core.NewPosition()
+	want.Variant = core.ThreeCheck
+	want.WhiteChecks, want.BlackChecks = 1, 2
+
+	got, err := Decode(Starting + " +1+2")
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, in := range []string{Starting + " +4+0", Starting + " 3+3", Starting + " +0+0 +0+0", Starting + " "} {
+		if _, err := Decode(in); err == nil {
+			t.Errorf("Decode(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestDecode_Crazyhouse(t *testing.T) {
+	var want core.Position
+	want.Variant = core.Crazyhouse
+	want.Board.Set(core.BlackKing, core.E8)
+	want.Board.Set(core.WhiteQueen, core.B7)
+	want.Board.Set(core.WhiteKing, core.A2)
+	want.Promoted.Set(core.B7)
+	want.WhitePocket[core.Knight] = 1
+	want.WhitePocket[core.Pawn] = 2
+	want.BlackPocket[core.Rook] = 1
+	want.SideToMove = core.Black
+	want.FullMoveNumber = 30
+
+	got, err := Decode("4k3/1Q~6/8/8/8/8/K7/8[NPPr] b - - 0 30")
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, in := range []string{
+		"4k3/1Q~6/8/8/8/8/K7/8 b - - 0 30",       // Promoted without pockets.
+		"4k3/1P~6/8/8/8/8/K7/8[] b - - 0 30",     // Promoted pawn.
+		"4k3/~Q7/8/8/8/8/K7/8[] b - - 0 30",      // Tilde before a piece.
+		"4k3/1Q~~6/8/8/8/8/K7/8[] b - - 0 30",    // Two tildes.
+		"4k3/1Q6/8/8/8/8/K7/8[rN] b - - 0 30",    // Pockets out of order.
+		"4k3/1Q6/8/8/8/8/K7/8[K] b - - 0 30",     // King in a pocket.
+		"4k3/1Q6/8/8/8/8/K7/8[] b - - 0 30 +0+0", // Crazyhouse and Three-check.
+		"4k3/1Q6/8/8/8/8/K7/8[N b - - 0 30",      // Unclosed bracket.
+	} {
+		if _, err := Decode(in); err == nil {
+			t.Errorf("Decode(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestDecode_VariantStarting(t *testing.T) {
+	for _, in := range []string{HordeStarting, RacingKingsStarting} {
+		p, err := Decode(in)
+		if err != nil {
+			t.Errorf("Decode(%q) error: %v", in, err)
+			continue
+		}
+		if got := Encode(p); got != in {
+			t.Errorf("Encode(Decode(%q)) = %q", in, got)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package fen
 import (
 	"fmt"
 	"strings"
+	"unicode"
 
 	"github.com/clfs/simple/core"
 )
@@ -27,8 +28,19 @@ var encodePiece = map[core.Piece]rune{
 	core.BlackKing:   'k',
 }
 
-// Encode encodes a position as a FEN string.
+// Encode encodes a position as a FEN string. Castling rights of Chess960
+// positions are encoded in X-FEN.
 func Encode(p core.Position) string {
+	return encode(p, false)
+}
+
+// EncodeShredder encodes a position as a Shredder-FEN string, where castling
+// rights are the files of the castling rooks.
+func EncodeShredder(p core.Position) string {
+	return encode(p, true)
+}
+
+func encode(p core.Position, shredder bool) string {
 	var b strings.Builder
 
 	// Board.
@@ -48,6 +60,9 @@ func Encode(p core.Position) string {
 				gap = 0
 			}
 			b.WriteRune(encodePiece[piece])
+			if p.Variant == core.Crazyhouse && p.Promoted.Get(core.NewSquare(f, r)) {
+				b.WriteRune('~')
+			}
 		}
 
 		// Handle any gaps at the end of the row.
@@ -60,6 +75,16 @@ func Encode(p core.Position) string {
 		}
 	}
 
+	// Pockets in Crazyhouse.
+	if p.Variant == core.Crazyhouse {
+		b.WriteRune('[')
+		for _, r := range pocketOrder {
+			piece := decodePiece[r]
+			b.WriteString(strings.Repeat(string(r), p.Pocket(piece.Color())[piece.Type()]))
+		}
+		b.WriteRune(']')
+	}
+
 	b.WriteRune(' ')
 
 	// Side to move.
@@ -72,22 +97,7 @@ func Encode(p core.Position) string {
 	b.WriteRune(' ')
 
 	// Castling rights.
-	if !p.WhiteOO && !p.WhiteOOO && !p.BlackOO && !p.BlackOOO {
-		b.WriteRune('-')
-	} else {
-		if p.WhiteOO {
-			b.WriteRune('K')
-		}
-		if p.WhiteOOO {
-			b.WriteRune('Q')
-		}
-		if p.BlackOO {
-			b.WriteRune('k')
-		}
-		if p.BlackOOO {
-			b.WriteRune('q')
-		}
-	}
+	b.WriteString(encodeCastling(p, shredder))
 
 	b.WriteRune(' ')
 
@@ -108,5 +118,65 @@ func Encode(p core.Position) string {
 	// Full move counter.
 	fmt.Fprintf(&b, "%d", p.FullMoveNumber)
 
+	// Checks given in Three-check.
+	if p.Variant == core.ThreeCheck {
+		fmt.Fprintf(&b, " +%d+%d", p.WhiteChecks, p.BlackChecks)
+	}
+
 	return b.String()
 }
+
+// encodeCastling encodes castling rights. In X-FEN, rights are K, Q, k and q
+// unless there's another rook farther from the king than the castling rook,
+// in which case they're the file of the castling rook.
+func encodeCastling(p core.Position, shredder bool) string {
+	var b strings.Builder
+	for _, r := range []struct {
+		ok       bool
+		c        core.Color
+		kingside bool
+	}{
+		{p.WhiteOO, core.White, true},
+		{p.WhiteOOO, core.White, false},
+		{p.BlackOO, core.Black, true},
+		{p.BlackOOO, core.Black, false},
+	} {
+		if !r.ok {
+			continue
+		}
+		rook := p.CastlingRook(r.c, r.kingside)
+		letter := 'a' + rune(rook.File())
+		if !shredder && (!p.Chess960 || !rookBeyond(p, rook, r.kingside)) {
+			letter = 'q'
+			if r.kingside {
+				letter = 'k'
+			}
+		}
+		if r.c == core.White {
+			letter = unicode.ToUpper(letter)
+		}
+		b.WriteRune(letter)
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
+
+// rookBeyond returns true if there's a rook of the same color as the rook on
+// a square farther along its rank, toward the h-file if kingside.
+func rookBeyond(p core.Position, rook core.Square, kingside bool) bool {
+	piece := core.NewPiece(core.White, core.Rook)
+	if rook.Rank() == core.Rank8 {
+		piece = core.NewPiece(core.Black, core.Rook)
+	}
+	for f := core.FileA; f.Valid(); f++ {
+		if (kingside && f <= rook.File()) || (!kingside && f >= rook.File()) {
+			continue
+		}
+		if got, ok := p.Board.Get(core.NewSquare(f, rook.Rank())); ok && got == piece {
+			return true
+		}
+	}
+	return false
+}
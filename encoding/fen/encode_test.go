@@ -1,6 +1,7 @@
 package fen
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/clfs/simple/core"
@@ -83,3 +84,101 @@ func TestEncode(t *testing.T) {
 		}
 	}
 }
+
+func TestEncode_Chess960(t *testing.T) {
+	cases := []struct {
+		p              core.Position
+		want, shredder string
+	}{
+		{
+			core.NewPosition(),
+			Starting,
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1",
+		},
+		{
+			core.NewChess960Position(0),
+			"bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1",
+			"bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w HFhf - 0 1",
+		},
+		{
+			MustDecode("1r2k2r/8/8/8/8/8/8/1R2K1RR w GBb - 0 1"),
+			"1r2k2r/8/8/8/8/8/8/1R2K1RR w GQq - 0 1",
+			"1r2k2r/8/8/8/8/8/8/1R2K1RR w GBb - 0 1",
+		},
+	}
+
+	for i, tc := range cases {
+		if got := Encode(tc.p); got != tc.want {
+			t.Errorf("#%d: Encode() = %q, want %q", i, got, tc.want)
+		}
+		if got := EncodeShredder(tc.p); got != tc.shredder {
+			t.Errorf("#%d: EncodeShredder() = %q, want %q", i, got, tc.shredder)
+		}
+	}
+}
+
+func TestEncode_ThreeCheck(t *testing.T) {
+	p := core.NewPosition()
+	p.Variant = core.ThreeCheck
+	p.WhiteChecks = 2
+
+	if got, want := Encode(p), Starting+" +2+0"; got != want {
+		t.Errorf("Encode() = %q, want %q", got, want)
+	}
+}
+
+func TestEncode_Crazyhouse(t *testing.T) {
+	p := core.NewPosition()
+	p.Variant = core.Crazyhouse
+	p.Board.Clear(core.D8)
+	p.Board.Set(core.WhiteQueen, core.D8)
+	p.Promoted.Set(core.D8)
+	p.WhitePocket[core.Queen] = 1
+	p.WhitePocket[core.Pawn] = 2
+	p.BlackPocket[core.Knight] = 1
+
+	want := "rnbQ~kbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[QPPn] w KQkq - 0 1"
+	if got := Encode(p); got != want {
+		t.Errorf("Encode() = %q, want %q", got, want)
+	}
+}
+
+// TestEncode_JSON checks that the FEN strings positions are marshaled to in
+// JSON by package core agree with Encode and Decode.
+func TestEncode_JSON(t *testing.T) {
+	fens := append(readFENs(t, "testdata/valid.fen"),
+		"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+		"1r2k1r1/8/8/8/8/8/8/R3K2R b KQk - 0 1",
+		"r1b1kb1r/pppp1ppp/5n2/4p3/4P3/2N5/PPPP1PPP/R1B1KB~NR[QNnp] b KQkq - 0 6",
+		"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2 +1+0",
+	)
+	for _, in := range fens {
+		p := MustDecode(in)
+
+		b, err := json.Marshal(p)
+		if err != nil {
+			t.Errorf("%q: error: %v", in, err)
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			t.Fatal(err)
+		}
+		// Castling rights of Chess960 positions are in Shredder-FEN.
+		if want := Encode(p); !p.Chess960 && s != want {
+			t.Errorf("%q: got %q, want %q", in, s, want)
+		}
+		if want := EncodeShredder(p); p.Chess960 && s != want {
+			t.Errorf("%q: got %q, want %q", in, s, want)
+		}
+
+		var got core.Position
+		if err := json.Unmarshal([]byte(`"`+Encode(p)+`"`), &got); err != nil {
+			t.Errorf("%q: error: %v", in, err)
+			continue
+		}
+		if diff := cmp.Diff(p, got); diff != "" {
+			t.Errorf("%q: mismatch (-want +got):\n%s", in, diff)
+		}
+	}
+}
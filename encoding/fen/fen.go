@@ -6,7 +6,23 @@
 // passant target square "if and only if the last move was a pawn advance of two
 // squares [...] even if there is no pawn of the opposing side that may
 // immediately execute the en passant capture".
+//
+// Chess960 positions are supported with X-FEN and Shredder-FEN castling
+// rights, which identify castling rooks by their files when needed.
+// Three-check positions have a seventh field with the checks each side has
+// given, like "+1+0" if white has given one check. Crazyhouse positions have
+// the pieces in each side's pocket in brackets after the board, like
+// "[QPPn]", and promoted pieces are followed by a tilde, like "Q~". Other
+// variants can't be told apart from standard chess, so decoded positions are
+// in standard chess unless they have the seventh field or pockets.
 package fen
 
 // Starting is the FEN string for the starting position.
 const Starting = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// FEN strings for the starting positions of variants that don't start from
+// the standard one.
+const (
+	HordeStarting       = "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1"
+	RacingKingsStarting = "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1"
+)
@@ -14,9 +14,10 @@ func FuzzRoundTrip(f *testing.F) {
 			t.Skip() // invalid FEN
 		}
 
-		s2 := Encode(p)
-		if s != s2 {
-			t.Fatalf("%q -> %q", s, s2)
+		// Chess960 positions may be in X-FEN or Shredder-FEN.
+		s2, s3 := Encode(p), EncodeShredder(p)
+		if s != s2 && s != s3 {
+			t.Fatalf("%q -> %q, %q", s, s2, s3)
 		}
 	})
 }
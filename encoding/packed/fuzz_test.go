@@ -0,0 +1,43 @@
+package packed
+
+import (
+	"bufio"
+	"os"
+	"testing"
+
+	"github.com/clfs/simple/encoding/fen"
+)
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add(fen.Starting)
+
+	file, err := os.Open("../fen/testdata/valid.fen")
+	if err != nil {
+		f.Fatal(err)
+	}
+	defer file.Close()
+	for s := bufio.NewScanner(file); s.Scan(); {
+		f.Add(s.Text())
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		p, err := fen.Decode(s)
+		if err != nil {
+			t.Skip() // invalid FEN
+		}
+		b, err := Encode(p)
+		if err != nil {
+			t.Skip() // position can't be packed
+		}
+		if len(b) != Size {
+			t.Fatalf("%q: got %d bytes, want %d", s, len(b), Size)
+		}
+		got, err := Decode(b)
+		if err != nil {
+			t.Fatalf("%q: decode error: %v", s, err)
+		}
+		if got != p {
+			t.Fatalf("%q -> %x -> %q", s, b, fen.Encode(got))
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package packed
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/movegen"
+)
+
+// ErrInvalidGame is returned by DecodeGame for data that doesn't decode to a
+// game.
+var ErrInvalidGame = errors.New("invalid packed game")
+
+// EncodeGame encodes the moves of a game played from a position. Each move is
+// stored as its index among the legal moves, sorted by packed value, which
+// takes a byte per ply, or two bytes, little-endian, in the rare positions
+// with more than 256 legal moves.
+func EncodeGame(p core.Position, moves []core.Move) ([]byte, error) {
+	b := make([]byte, 0, len(moves))
+	for ply, m := range moves {
+		legal := sortedMoves(p)
+		i, ok := slices.BinarySearch(legal, m.Pack())
+		if !ok {
+			return nil, fmt.Errorf("ply %d: illegal move %s", ply, pcn.Encode(m))
+		}
+		if len(legal) > 256 {
+			b = binary.LittleEndian.AppendUint16(b, uint16(i))
+		} else {
+			b = append(b, byte(i))
+		}
+		p.Make(m)
+	}
+	return b, nil
+}
+
+// DecodeGame decodes the moves of a game encoded by EncodeGame from the same
+// position.
+func DecodeGame(p core.Position, b []byte) ([]core.Move, error) {
+	var moves []core.Move
+	for len(b) > 0 {
+		legal := sortedMoves(p)
+		var i int
+		if len(legal) > 256 {
+			if len(b) < 2 {
+				return nil, fmt.Errorf("%w: ply %d: truncated", ErrInvalidGame, len(moves))
+			}
+			i = int(binary.LittleEndian.Uint16(b))
+			b = b[2:]
+		} else {
+			i = int(b[0])
+			b = b[1:]
+		}
+		if i >= len(legal) {
+			return nil, fmt.Errorf("%w: ply %d: index %d of %d moves", ErrInvalidGame, len(moves), i, len(legal))
+		}
+		m := legal[i].Unpack()
+		moves = append(moves, m)
+		p.Make(m)
+	}
+	return moves, nil
+}
+
+// sortedMoves returns the legal moves in a position, packed and sorted.
+func sortedMoves(p core.Position) []core.PackedMove {
+	moves := movegen.LegalMoves(p)
+	packed := make([]core.PackedMove, len(moves))
+	for i, m := range moves {
+		packed[i] = m.Pack()
+	}
+	slices.Sort(packed)
+	return packed
+}
@@ -0,0 +1,82 @@
+package packed
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func decodeMoves(s string) []core.Move {
+	var moves []core.Move
+	for _, f := range strings.Fields(s) {
+		moves = append(moves, pcn.MustDecode(f))
+	}
+	return moves
+}
+
+func TestEncodeGame(t *testing.T) {
+	cases := []struct {
+		fen   string
+		moves string
+		want  []byte
+	}{
+		{fen.Starting, "", []byte{}},
+		{fen.Starting, "e2e4 e7e5 g1f3", []byte{13, 8, 13}},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8n e8f8", []byte{5, 3}},
+		// More than 256 legal moves take two bytes.
+		{"2k5/8/8/8/8/8/8/4K3[QRBNPqrbnp] w - - 0 1", "Q@d7 c8d7", []byte{0x21, 0x01, 0}},
+	}
+
+	for _, tc := range cases {
+		moves := decodeMoves(tc.moves)
+		b, err := EncodeGame(fen.MustDecode(tc.fen), moves)
+		if err != nil {
+			t.Errorf("%q %q: error: %v", tc.fen, tc.moves, err)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, b); diff != "" {
+			t.Errorf("%q %q: mismatch (-want +got):\n%s", tc.fen, tc.moves, diff)
+		}
+
+		got, err := DecodeGame(fen.MustDecode(tc.fen), b)
+		if err != nil {
+			t.Errorf("%q %q: decode error: %v", tc.fen, tc.moves, err)
+			continue
+		}
+		if diff := cmp.Diff(moves, got, cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("%q %q: round trip mismatch (-want +got):\n%s", tc.fen, tc.moves, diff)
+		}
+	}
+}
+
+func TestEncodeGame_Illegal(t *testing.T) {
+	moves := decodeMoves("e2e4 e7e5 e4e5")
+	if _, err := EncodeGame(core.NewPosition(), moves); err == nil {
+		t.Error("got nil error")
+	}
+}
+
+func TestDecodeGame_Error(t *testing.T) {
+	cases := []struct {
+		fen string
+		b   []byte
+	}{
+		{fen.Starting, []byte{20}},
+		{fen.Starting, []byte{0, 255}},
+		{"2k5/8/8/8/8/8/8/4K3[QRBNPqrbnp] w - - 0 1", []byte{0}},
+		// No moves after checkmate.
+		{"7k/6Q1/6K1/8/8/8/8/8 b - - 0 1", []byte{0}},
+	}
+
+	for _, tc := range cases {
+		if _, err := DecodeGame(fen.MustDecode(tc.fen), tc.b); !errors.Is(err, ErrInvalidGame) {
+			t.Errorf("%q %v: got %v, want %v", tc.fen, tc.b, err, ErrInvalidGame)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Package packed implements a compact binary encoding of positions, for
+// storing many of them, like training positions or book entries.
+//
+// A packed position is Size bytes:
+//
+//	bytes 0-7    occupied squares, as a little-endian bitboard
+//	bytes 8-23   the piece on each occupied square, in square order, as a
+//	             nibble each, low nibble first, with unused nibbles zero
+//	byte  24     bit 0 if black is to move, then bits 1-4 for white and black
+//	             kingside and queenside castling rights, and bit 5 for Chess960
+//	byte  25     the en passant square, or zero if there isn't one
+//	bytes 26-27  a little-endian uint16 of the castling rook files, three bits
+//	             each in the same order as the rights, then the variant in the
+//	             top four bits
+//	byte  28     the half move clock
+//	bytes 29-30  the full move number, as a little-endian uint16
+//	byte  31     checks given by white and black in Three-check, two bits each
+//
+// Positions with more than 32 pieces, like in Horde, and Crazyhouse positions,
+// whose pockets don't fit, can't be packed.
+//
+// Games are packed separately, as the starting position followed by the
+// output of EncodeGame, which stores each move as an index among the legal
+// moves in about a byte per ply.
+package packed
+
+import (
+	"fmt"
+
+	"github.com/clfs/simple/core"
+)
+
+// Size is the size of a packed position in bytes.
+const Size = 32
+
+// Encode packs a position. It's the same as core.Position.MarshalBinary.
+func Encode(p core.Position) ([]byte, error) {
+	return p.MarshalBinary()
+}
+
+// Decode unpacks a position. It's the same as core.Position.UnmarshalBinary.
+func Decode(b []byte) (core.Position, error) {
+	var p core.Position
+	if err := p.UnmarshalBinary(b); err != nil {
+		return core.Position{}, err
+	}
+	return p, nil
+}
+
+// DecodeAll unpacks consecutive packed positions, like those read from a file
+// of them.
+func DecodeAll(b []byte) ([]core.Position, error) {
+	if len(b)%Size != 0 {
+		return nil, fmt.Errorf("%w: %d bytes", core.ErrInvalidPacked, len(b))
+	}
+	positions := make([]core.Position, len(b)/Size)
+	for i := range positions {
+		p, err := Decode(b[i*Size : (i+1)*Size])
+		if err != nil {
+			return nil, fmt.Errorf("position %d: %w", i, err)
+		}
+		positions[i] = p
+	}
+	return positions, nil
+}
@@ -0,0 +1,108 @@
+package packed
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{
+			fen.Starting,
+			"ffff00000000ffff" + "13422531" + "00000000" + "66666666" + "79a88b97" + "1e" + "00" + "0000" + "00" + "0100" + "00",
+		},
+		{
+			"4k3/8/8/3pP3/8/8/8/4K3 w - d6 3 40",
+			"1000000018000010" + "65b00000000000000000000000000000" + "00" + "2b" + "0000" + "03" + "2800" + "00",
+		},
+		{
+			"1r2k2r/8/8/8/8/8/8/1R2K1RR w GQq - 0 1 +1+2",
+			"d200000000000092" + "5333b909000000000000000000000000" + "36" + "00" + "0e22" + "00" + "0100" + "09",
+		},
+	}
+
+	for _, tc := range cases {
+		b, err := Encode(fen.MustDecode(tc.in))
+		if err != nil {
+			t.Errorf("%q: error: %v", tc.in, err)
+			continue
+		}
+		if got := hex.EncodeToString(b); got != tc.want {
+			t.Errorf("%q: got %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestEncode_Error(t *testing.T) {
+	cases := []string{
+		fen.HordeStarting,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1",
+		"4k3/8/8/8/8/8/8/4K3 w - - 256 1",
+		"4k3/8/8/8/8/8/8/4K3 w - - 0 65536",
+	}
+
+	for _, in := range cases {
+		if _, err := Encode(fen.MustDecode(in)); !errors.Is(err, core.ErrNotPackable) {
+			t.Errorf("%q: got %v, want %v", in, err, core.ErrNotPackable)
+		}
+	}
+}
+
+func TestDecode_Error(t *testing.T) {
+	start, err := Encode(core.NewPosition())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name  string
+		setup func(b []byte) []byte
+	}{
+		{"short", func(b []byte) []byte { return b[:Size-1] }},
+		{"invalid piece", func(b []byte) []byte { b[8] = 0xff; return b }},
+		{"unused piece", func(b []byte) []byte { b[23] = 0x10; b[0] = 0; return b }},
+		{"too many pieces", func(b []byte) []byte { b[3] = 0xff; return b }},
+		{"unused bits", func(b []byte) []byte { b[24] |= 0x80; return b }},
+		{"invalid variant", func(b []byte) []byte { b[27] = 0xf0; return b }},
+	}
+
+	for _, tc := range cases {
+		b := tc.setup(append([]byte(nil), start...))
+		if _, err := Decode(b); !errors.Is(err, core.ErrInvalidPacked) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, core.ErrInvalidPacked)
+		}
+	}
+}
+
+func TestDecodeAll(t *testing.T) {
+	want := []core.Position{core.NewPosition(), fen.MustDecode("4k3/8/8/3pP3/8/8/8/4K3 w - d6 3 40")}
+
+	var b []byte
+	for _, p := range want {
+		enc, err := Encode(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b = append(b, enc...)
+	}
+
+	got, err := DecodeAll(b)
+	if err != nil {
+		t.Fatalf("DecodeAll() error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DecodeAll() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := DecodeAll(b[:Size+1]); err == nil {
+		t.Errorf("DecodeAll() of a partial position succeeded")
+	}
+}
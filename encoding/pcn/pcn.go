@@ -14,10 +14,22 @@ var encodePromotion = map[core.PieceType]string{
 	core.Bishop: "b",
 	core.Rook:   "r",
 	core.Queen:  "q",
+	core.King:   "k", // Antichess.
 }
 
-// Encode encodes a move as a PCN string.
+var encodeDrop = map[core.PieceType]string{
+	core.Pawn:   "P",
+	core.Knight: "N",
+	core.Bishop: "B",
+	core.Rook:   "R",
+	core.Queen:  "Q",
+}
+
+// Encode encodes a move as a PCN string. Drops are encoded like "N@f3".
 func Encode(m core.Move) string {
+	if m.Drop {
+		return encodeDrop[m.Dropped] + "@" + strings.ToLower(m.To.String())
+	}
 	f := strings.ToLower(m.From.String())
 	t := strings.ToLower(m.To.String())
 	p := encodePromotion[m.Promotion]
@@ -43,10 +55,24 @@ var decodePromotion = map[byte]core.PieceType{
 	'b': core.Bishop,
 	'r': core.Rook,
 	'q': core.Queen,
+	'k': core.King, // Antichess.
+}
+
+var decodeDrop = map[byte]core.PieceType{
+	'P': core.Pawn,
+	'N': core.Knight,
+	'B': core.Bishop,
+	'R': core.Rook,
+	'Q': core.Queen,
 }
 
-// Decode decodes a PCN string and returns the move it represents.
+// Decode decodes a PCN string and returns the move it represents. It accepts
+// drops like "N@f3".
 func Decode(s string) (core.Move, error) {
+	if len(s) == 4 && s[1] == '@' {
+		return decodeDropMove(s)
+	}
+
 	if n := len(s); n < 4 || n > 5 {
 		return core.Move{}, fmt.Errorf("invalid length: %d", n)
 	}
@@ -74,6 +100,21 @@ func Decode(s string) (core.Move, error) {
 	return core.Move{From: from, To: to, Promotion: promotion}, nil
 }
 
+// decodeDropMove decodes a drop like "N@f3".
+func decodeDropMove(s string) (core.Move, error) {
+	pt, ok := decodeDrop[s[0]]
+	if !ok {
+		return core.Move{}, fmt.Errorf("invalid drop piece: %c", s[0])
+	}
+
+	to, ok := decodeSquare(s[2:])
+	if !ok {
+		return core.Move{}, fmt.Errorf("invalid drop square: %s", s[2:])
+	}
+
+	return core.Move{To: to, Drop: true, Dropped: pt}, nil
+}
+
 // MustDecode is like Decode but panics if the PCN is invalid.
 func MustDecode(s string) core.Move {
 	m, err := Decode(s)
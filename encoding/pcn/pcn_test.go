@@ -15,6 +15,8 @@ func ExampleEncode() {
 		{From: core.F2, To: core.F1, Promotion: core.Queen},
 		// White short castle.
 		{From: core.E1, To: core.G1},
+		// Knight drop.
+		{To: core.F3, Drop: true, Dropped: core.Knight},
 	}
 	for _, m := range moves {
 		fmt.Println(Encode(m))
@@ -23,6 +25,7 @@ func ExampleEncode() {
 	// b2b4
 	// f2f1q
 	// e1g1
+	// N@f3
 }
 
 func TestDecode(t *testing.T) {
@@ -34,9 +37,14 @@ func TestDecode(t *testing.T) {
 		{in: "b2b4", want: core.Move{From: core.B2, To: core.B4}},
 		{in: "f2f1q", want: core.Move{From: core.F2, To: core.F1, Promotion: core.Queen}},
 		{in: "e1g1", want: core.Move{From: core.E1, To: core.G1}},
+		{in: "a7a8k", want: core.Move{From: core.A7, To: core.A8, Promotion: core.King}},
 		{in: "b2b4x", wantErr: "invalid promotion: x"},
 		{in: "b2b", wantErr: "invalid length: 3"},
 		{in: "b2b4qq", wantErr: "invalid length: 6"},
+		{in: "N@f3", want: core.Move{To: core.F3, Drop: true, Dropped: core.Knight}},
+		{in: "P@e4", want: core.Move{To: core.E4, Drop: true, Dropped: core.Pawn}},
+		{in: "K@e4", wantErr: "invalid drop piece: K"},
+		{in: "N@i3", wantErr: "invalid drop square: i3"},
 		{in: "k1b2", wantErr: "invalid start square: k1"},
 		{in: "b2k1", wantErr: "invalid end square: k1"},
 	}
@@ -0,0 +1,280 @@
+// Package pgn implements decoding of games in portable game notation (PGN) as
+// defined in "Standard: Portable Game Notation Specification and
+// Implementation Guide", revision 1994.03.12.
+//
+// Only the main line of each game is decoded. Comments, variations and
+// numeric annotation glyphs are skipped.
+package pgn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/san"
+)
+
+// A Result is the result of a game.
+type Result string
+
+// Result constants.
+const (
+	WhiteWins Result = "1-0"
+	BlackWins Result = "0-1"
+	Draw      Result = "1/2-1/2"
+	Unknown   Result = "*"
+)
+
+func (r Result) valid() bool {
+	return r == WhiteWins || r == BlackWins || r == Draw || r == Unknown
+}
+
+// A Game is a decoded game.
+type Game struct {
+	Tags   map[string]string
+	Start  core.Position // From the FEN tag, or the starting position.
+	Moves  []core.Move
+	Result Result
+}
+
+// A Decoder reads games from an input stream.
+type Decoder struct {
+	r    *bufio.Reader
+	next string // Pushed back token, if any.
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Decode decodes the next game. It returns io.EOF if there are no more games.
+func (d *Decoder) Decode() (*Game, error) {
+	g := &Game{
+		Tags:   make(map[string]string),
+		Start:  core.NewPosition(),
+		Result: Unknown,
+	}
+
+	// Tag pairs.
+	for {
+		tok, err := d.token()
+		if err == io.EOF && len(g.Tags) == 0 {
+			return nil, io.EOF
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if tok != "[" {
+			d.unread(tok)
+			break
+		}
+		if err := d.tag(g); err != nil {
+			return nil, err
+		}
+	}
+
+	if s, ok := g.Tags["FEN"]; ok {
+		p, err := fen.DecodeStrict(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FEN tag: %v", err)
+		}
+		g.Start = p
+	}
+	if r := Result(g.Tags["Result"]); r.valid() {
+		g.Result = r
+	}
+
+	// Movetext.
+	var (
+		p     = g.Start
+		depth int // Variation nesting depth.
+	)
+	for {
+		tok, err := d.token()
+		if err == io.EOF {
+			return g, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case tok == "(":
+			depth++
+		case tok == ")":
+			depth--
+			if depth < 0 {
+				return nil, errors.New("unmatched ')'")
+			}
+		case depth > 0, tok == ".", tok[0] == '$', isMoveNumber(tok):
+		case Result(tok).valid():
+			if depth == 0 {
+				g.Result = Result(tok)
+				return g, nil
+			}
+		case tok == "[":
+			// A new game started without a result.
+			d.unread(tok)
+			return g, nil
+		default:
+			m, err := san.Decode(p, tok)
+			if err != nil {
+				return nil, fmt.Errorf("move %d: %v", len(g.Moves)+1, err)
+			}
+			g.Moves = append(g.Moves, m)
+			p.Make(m)
+		}
+	}
+}
+
+// tag decodes a tag pair after its opening bracket.
+func (d *Decoder) tag(g *Game) error {
+	name, err := d.token()
+	if err != nil {
+		return fmt.Errorf("invalid tag: %v", err)
+	}
+	if err := d.skipSpace(); err != nil {
+		return fmt.Errorf("invalid tag %s: %v", name, err)
+	}
+
+	if c, err := d.r.ReadByte(); err != nil || c != '"' {
+		return fmt.Errorf("invalid tag %s: missing value", name)
+	}
+
+	var b strings.Builder
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("invalid tag %s: unterminated value", name)
+		}
+		if c == '"' {
+			break
+		}
+		if c == '\\' {
+			if c, err = d.r.ReadByte(); err != nil {
+				return fmt.Errorf("invalid tag %s: unterminated value", name)
+			}
+		}
+		b.WriteByte(c)
+	}
+
+	if tok, err := d.token(); err != nil || tok != "]" {
+		return fmt.Errorf("invalid tag %s: missing ']'", name)
+	}
+
+	g.Tags[name] = b.String()
+	return nil
+}
+
+// unread pushes back a token.
+func (d *Decoder) unread(tok string) {
+	d.next = tok
+}
+
+// skipSpace skips whitespace.
+func (d *Decoder) skipSpace() error {
+	for {
+		r, _, err := d.r.ReadRune()
+		if err != nil {
+			return err
+		}
+		if !unicode.IsSpace(r) {
+			return d.r.UnreadRune()
+		}
+	}
+}
+
+// token returns the next token, skipping whitespace and comments.
+func (d *Decoder) token() (string, error) {
+	if tok := d.next; tok != "" {
+		d.next = ""
+		return tok, nil
+	}
+
+	for {
+		if err := d.skipSpace(); err != nil {
+			return "", err
+		}
+
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+
+		switch c {
+		case '{':
+			if _, err := d.r.ReadString('}'); err != nil {
+				return "", errors.New("unterminated comment")
+			}
+		case ';', '%':
+			if _, err := d.r.ReadString('\n'); err != nil && err != io.EOF {
+				return "", err
+			}
+		case '[', ']', '(', ')', '.', '*':
+			return string(c), nil
+		default:
+			b := []byte{c}
+			for {
+				c, err := d.r.ReadByte()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return "", err
+				}
+				if !isSymbolByte(c) {
+					d.r.UnreadByte()
+					break
+				}
+				b = append(b, c)
+			}
+			return string(b), nil
+		}
+	}
+}
+
+// isSymbolByte reports whether c can continue a symbol token.
+func isSymbolByte(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("_+#=:-/!?", c) != -1
+}
+
+// isMoveNumber reports whether a token is a move number.
+func isMoveNumber(tok string) bool {
+	for _, c := range []byte(tok) {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// DecodeAll decodes every game from r.
+func DecodeAll(r io.Reader) ([]*Game, error) {
+	var (
+		d     = NewDecoder(r)
+		games []*Game
+	)
+	for {
+		g, err := d.Decode()
+		if err == io.EOF {
+			return games, nil
+		}
+		if err != nil {
+			return games, fmt.Errorf("game %d: %v", len(games)+1, err)
+		}
+		games = append(games, g)
+	}
+}
@@ -0,0 +1,123 @@
+package pgn
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/google/go-cmp/cmp"
+)
+
+const games = `[Event "F/S Return Match"]
+[Site "Belgrade, Serbia JUG"]
+[Round "29"]
+[White "Fischer, Robert J."]
+[Black "Spassky, Boris V."]
+[Result "1/2-1/2"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 {This opening is called the Ruy Lopez.} 3... a6
+4. Ba4 Nf6 5. O-O Be7 (5... b5 6. Bb3 (6. Bxb5?) Be7) 6. Re1 $1 b5 1/2-1/2
+
+[Event "Short"]
+[SetUp "1"]
+[FEN "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1"]
+
+; A line comment.
+1. Ra8# 1-0
+
+1. d4 d5 *
+`
+
+func moves(s ...string) []core.Move {
+	var res []core.Move
+	for _, m := range s {
+		res = append(res, pcn.MustDecode(m))
+	}
+	return res
+}
+
+func TestDecoder_Decode(t *testing.T) {
+	want := []*Game{
+		{
+			Tags: map[string]string{
+				"Event":  "F/S Return Match",
+				"Site":   "Belgrade, Serbia JUG",
+				"Round":  "29",
+				"White":  "Fischer, Robert J.",
+				"Black":  "Spassky, Boris V.",
+				"Result": "1/2-1/2",
+			},
+			Start:  core.NewPosition(),
+			Moves:  moves("e2e4", "e7e5", "g1f3", "b8c6", "f1b5", "a7a6", "b5a4", "g8f6", "e1g1", "f8e7", "f1e1", "b7b5"),
+			Result: Draw,
+		},
+		{
+			Tags: map[string]string{
+				"Event": "Short",
+				"SetUp": "1",
+				"FEN":   "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1",
+			},
+			Start:  fen.MustDecode("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1"),
+			Moves:  moves("a1a8"),
+			Result: WhiteWins,
+		},
+		{
+			Tags:   map[string]string{},
+			Start:  core.NewPosition(),
+			Moves:  moves("d2d4", "d7d5"),
+			Result: Unknown,
+		},
+	}
+
+	got, err := DecodeAll(strings.NewReader(games))
+	if err != nil {
+		t.Fatalf("DecodeAll() error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecoder_Decode_MissingResult(t *testing.T) {
+	d := NewDecoder(strings.NewReader("[Result \"0-1\"]\n1. f3 e5\n[Event \"Next\"]\n1. e4"))
+
+	g, err := d.Decode()
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if g.Result != BlackWins || len(g.Moves) != 2 {
+		t.Errorf("got result %s and %d moves, want 0-1 and 2 moves", g.Result, len(g.Moves))
+	}
+
+	g, err = d.Decode()
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if g.Tags["Event"] != "Next" || len(g.Moves) != 1 {
+		t.Errorf("got event %q and %d moves, want Next and 1 move", g.Tags["Event"], len(g.Moves))
+	}
+
+	if _, err := d.Decode(); err != io.EOF {
+		t.Errorf("got error %v, want %v", err, io.EOF)
+	}
+}
+
+func TestDecoder_Decode_Error(t *testing.T) {
+	cases := []string{
+		"1. e5 *",
+		"[Event \"Unterminated]",
+		"[Event]",
+		"1. e4 {unterminated",
+		"1. e4 ) *",
+		"[FEN \"invalid\"]\n*",
+	}
+
+	for _, in := range cases {
+		if _, err := NewDecoder(strings.NewReader(in)).Decode(); err == nil || err == io.EOF {
+			t.Errorf("%q: got error %v, want a decoding error", in, err)
+		}
+	}
+}
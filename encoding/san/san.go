@@ -0,0 +1,305 @@
+// Package san implements encoding of standard algebraic notation (SAN) as
+// defined in "Standard: Portable Game Notation Specification and
+// Implementation Guide", revision 1994.03.12.
+package san
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/movegen"
+)
+
+var encodePieceType = map[core.PieceType]string{
+	core.Knight: "N",
+	core.Bishop: "B",
+	core.Rook:   "R",
+	core.Queen:  "Q",
+	core.King:   "K",
+}
+
+var encodeDrop = map[core.PieceType]string{
+	core.Pawn:   "P",
+	core.Knight: "N",
+	core.Bishop: "B",
+	core.Rook:   "R",
+	core.Queen:  "Q",
+}
+
+// encodeSquare encodes a square in lower case.
+func encodeSquare(s core.Square) string {
+	return strings.ToLower(s.String())
+}
+
+// encodeFile encodes a file in lower case.
+func encodeFile(f core.File) string {
+	return string(rune('a' + f))
+}
+
+// encodeRank encodes a rank.
+func encodeRank(r core.Rank) string {
+	return string(rune('1' + r))
+}
+
+// Encode encodes a legal move in a position as a SAN string.
+func Encode(p core.Position, m core.Move) string {
+	var b strings.Builder
+
+	piece, _ := p.Board.Get(m.From)
+	pt := piece.Type()
+
+	isCastle := !m.Drop && p.IsCastle(m)
+	isCapture := !m.Drop && !isCastle && (p.Board.IsOccupied(m.To) || (pt == core.Pawn && p.EnPassant != 0 && m.To == p.EnPassant))
+
+	switch {
+	case m.Drop:
+		b.WriteString(encodeDrop[m.Dropped])
+		b.WriteByte('@')
+		b.WriteString(encodeSquare(m.To))
+	case isCastle && m.To.File() > m.From.File():
+		b.WriteString("O-O")
+	case isCastle:
+		b.WriteString("O-O-O")
+	case pt == core.Pawn:
+		if isCapture {
+			b.WriteString(encodeFile(m.From.File()))
+			b.WriteByte('x')
+		}
+		b.WriteString(encodeSquare(m.To))
+		if m.Promotion != 0 {
+			b.WriteByte('=')
+			b.WriteString(encodePieceType[m.Promotion])
+		}
+	default:
+		b.WriteString(encodePieceType[pt])
+		b.WriteString(disambiguate(p, m, piece))
+		if isCapture {
+			b.WriteByte('x')
+		}
+		b.WriteString(encodeSquare(m.To))
+	}
+
+	child := p
+	child.Make(m)
+	if movegen.InCheck(child) {
+		if len(movegen.LegalMoves(child)) == 0 {
+			b.WriteByte('#')
+		} else {
+			b.WriteByte('+')
+		}
+	}
+
+	return b.String()
+}
+
+// disambiguate returns the file, rank, or square needed to distinguish a move
+// from other legal moves of the same piece to the same square.
+func disambiguate(p core.Position, m core.Move, piece core.Piece) string {
+	var others []core.Square
+	for _, other := range movegen.LegalMoves(p) {
+		if other.Drop || other.To != m.To || other.From == m.From {
+			continue
+		}
+		if op, _ := p.Board.Get(other.From); op == piece {
+			others = append(others, other.From)
+		}
+	}
+
+	if len(others) == 0 {
+		return ""
+	}
+
+	sameFile, sameRank := false, false
+	for _, s := range others {
+		sameFile = sameFile || s.File() == m.From.File()
+		sameRank = sameRank || s.Rank() == m.From.Rank()
+	}
+
+	switch {
+	case !sameFile:
+		return encodeFile(m.From.File())
+	case !sameRank:
+		return encodeRank(m.From.Rank())
+	default:
+		return encodeSquare(m.From)
+	}
+}
+
+// EncodeMoves encodes a sequence of legal moves, starting from a position, as
+// SAN strings.
+func EncodeMoves(p core.Position, moves []core.Move) []string {
+	res := make([]string, len(moves))
+	for i, m := range moves {
+		res[i] = Encode(p, m)
+		p.Make(m)
+	}
+	return res
+}
+
+var decodePieceType = map[byte]core.PieceType{
+	'N': core.Knight,
+	'B': core.Bishop,
+	'R': core.Rook,
+	'Q': core.Queen,
+	'K': core.King,
+}
+
+// Decode decodes a SAN string as a legal move in a position. It accepts
+// check and checkmate indicators, move suffix annotations such as "!?",
+// castling written with zeros, redundant disambiguation, and Crazyhouse drops
+// like "N@f3", where pawn drops may omit the P.
+func Decode(p core.Position, s string) (core.Move, error) {
+	text := strings.TrimRight(s, "+#!?")
+
+	switch text {
+	case "O-O", "0-0":
+		return decodeCastle(p, s, true)
+	case "O-O-O", "0-0-0":
+		return decodeCastle(p, s, false)
+	}
+
+	if text == "" {
+		return core.Move{}, errors.New("empty move")
+	}
+
+	if i := strings.IndexByte(text, '@'); i >= 0 {
+		return decodeDrop(p, s, text[:i], text[i+1:])
+	}
+
+	pt := core.Pawn
+	if t, ok := decodePieceType[text[0]]; ok {
+		pt = t
+		text = text[1:]
+	}
+
+	var promotion core.PieceType
+	if pt == core.Pawn {
+		text = strings.Replace(text, "=", "", 1)
+		if n := len(text); n > 0 {
+			if t, ok := decodePieceType[text[n-1]]; ok && (t != core.King || p.Variant == core.Antichess) {
+				text, promotion = text[:n-1], t
+			}
+		}
+	}
+
+	if len(text) < 2 {
+		return core.Move{}, fmt.Errorf("invalid move: %s", s)
+	}
+	to, ok := decodeSquare(text[len(text)-2:])
+	if !ok {
+		return core.Move{}, fmt.Errorf("invalid move: %s", s)
+	}
+
+	// What's left is an optional origin file, rank or square, and an optional
+	// capture marker.
+	from := strings.TrimSuffix(text[:len(text)-2], "x")
+	if len(from) > 2 {
+		return core.Move{}, fmt.Errorf("invalid move: %s", s)
+	}
+
+	var candidates []core.Move
+	for _, m := range movegen.LegalMoves(p) {
+		piece, _ := p.Board.Get(m.From)
+		if m.Drop || piece.Type() != pt || m.To != to || m.Promotion != promotion {
+			continue
+		}
+		if !matchesOrigin(m.From, from) {
+			continue
+		}
+		candidates = append(candidates, m)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return core.Move{}, fmt.Errorf("illegal move: %s", s)
+	case 1:
+		return candidates[0], nil
+	default:
+		return core.Move{}, fmt.Errorf("ambiguous move: %s", s)
+	}
+}
+
+// decodeCastle returns the legal castling move kingside or queenside.
+func decodeCastle(p core.Position, s string, kingside bool) (core.Move, error) {
+	for _, m := range movegen.LegalMoves(p) {
+		if p.IsCastle(m) && (m.To.File() > m.From.File()) == kingside {
+			return m, nil
+		}
+	}
+	return core.Move{}, fmt.Errorf("illegal move: %s", s)
+}
+
+// decodeDrop returns the legal drop of a piece, which is empty for pawns, on a
+// square.
+func decodeDrop(p core.Position, s, piece, square string) (core.Move, error) {
+	pt := core.Pawn
+	if piece != "" && piece != "P" {
+		t, ok := decodePieceType[piece[0]]
+		if len(piece) != 1 || !ok || t == core.King {
+			return core.Move{}, fmt.Errorf("invalid move: %s", s)
+		}
+		pt = t
+	}
+
+	if len(square) != 2 {
+		return core.Move{}, fmt.Errorf("invalid move: %s", s)
+	}
+	to, ok := decodeSquare(square)
+	if !ok {
+		return core.Move{}, fmt.Errorf("invalid move: %s", s)
+	}
+
+	m := core.Move{To: to, Drop: true, Dropped: pt}
+	if !slices.Contains(movegen.LegalMoves(p), m) {
+		return core.Move{}, fmt.Errorf("illegal move: %s", s)
+	}
+	return m, nil
+}
+
+// decodeSquare decodes a square in lower case.
+func decodeSquare(s string) (core.Square, bool) {
+	f := core.File(s[0] - 'a')
+	r := core.Rank(s[1] - '1')
+	if !f.Valid() || !r.Valid() {
+		return 0, false
+	}
+	return core.NewSquare(f, r), true
+}
+
+// matchesOrigin reports whether a square matches a disambiguating file, rank
+// or square, which may be empty.
+func matchesOrigin(sq core.Square, origin string) bool {
+	for _, c := range []byte(origin) {
+		switch {
+		case c >= 'a' && c <= 'h':
+			if sq.File() != core.File(c-'a') {
+				return false
+			}
+		case c >= '1' && c <= '8':
+			if sq.Rank() != core.Rank(c-'1') {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// DecodeMoves decodes a sequence of SAN strings, starting from a position, as
+// legal moves.
+func DecodeMoves(p core.Position, moves []string) ([]core.Move, error) {
+	res := make([]core.Move, len(moves))
+	for i, s := range moves {
+		m, err := Decode(p, s)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = m
+		p.Make(m)
+	}
+	return res, nil
+}
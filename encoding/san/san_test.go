@@ -0,0 +1,186 @@
+package san
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/movegen"
+	"github.com/google/go-cmp/cmp"
+)
+
+func ExampleEncodeMoves() {
+	p := core.NewPosition()
+	moves := []core.Move{
+		pcn.MustDecode("e2e4"),
+		pcn.MustDecode("e7e5"),
+		pcn.MustDecode("d1h5"),
+		pcn.MustDecode("b8c6"),
+		pcn.MustDecode("f1c4"),
+		pcn.MustDecode("g8f6"),
+		pcn.MustDecode("h5f7"),
+	}
+	fmt.Println(EncodeMoves(p, moves))
+	// Output:
+	// [e4 e5 Qh5 Nc6 Bc4 Nf6 Qxf7#]
+}
+
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		in   string
+		move string
+		want string
+	}{
+		// Pawn moves.
+		{fen.Starting, "e2e4", "e4"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "e4d5", "exd5"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "exd6"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", "b8=Q+"},
+		{"r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7a8n", "bxa8=N"},
+		// Piece moves.
+		{fen.Starting, "g1f3", "Nf3"},
+		{"4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", "a1d1", "Rad1"},
+		{"4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", "f1e1", "Rfe1+"},
+		{"4k3/R7/8/8/8/8/8/R3K3 w - - 0 1", "a1a4", "R1a4"},
+		{"k7/8/8/8/8/2Q1Q3/8/2Q1K3 w - - 0 1", "c3d2", "Qc3d2"},
+		{"4k3/8/8/8/8/8/3p4/3K1N2 b - - 0 1", "e8e7", "Ke7"},
+		// Castling.
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "O-O-O"},
+		{"1r4kr/8/8/8/8/8/8/1R4KR w HBhb - 0 1", "g1h1", "O-O"},
+		{"1r4kr/8/8/8/8/8/8/1R4KR b HBhb - 0 1", "g8b8", "O-O-O"},
+		// Checks and checkmates.
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8", "Ra8#"},
+		{"6k1/5pp1/8/8/8/8/8/R5K1 w - - 0 1", "a1a8", "Ra8+"},
+		// Drops.
+		{"4k3/8/8/8/8/8/8/4K3[N] w - - 0 1", "N@f6", "N@f6+"},
+		{"4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", "P@e4", "P@e4"},
+		{"4k3/8/8/7R/8/P7/8/R3K3[R] w - - 0 1", "h5a5", "Ra5"},
+	}
+
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.in)
+		if got := Encode(p, pcn.MustDecode(tc.move)); got != tc.want {
+			t.Errorf("%q, %s: got %q, want %q", tc.in, tc.move, got, tc.want)
+		}
+	}
+}
+
+func TestEncodeMoves(t *testing.T) {
+	p := fen.MustDecode("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	moves := []core.Move{
+		pcn.MustDecode("e1g1"),
+		pcn.MustDecode("e8c8"),
+		pcn.MustDecode("e5f7"),
+		pcn.MustDecode("h3g2"),
+		pcn.MustDecode("f3g2"),
+	}
+	want := []string{"O-O", "O-O-O", "Nxf7", "hxg2", "Qxg2"}
+	if diff := cmp.Diff(want, EncodeMoves(p, moves)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		in   string
+		san  string
+		want string
+	}{
+		// Pawn moves.
+		{fen.Starting, "e4", "e2e4"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "exd5", "e4d5"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "exd6", "e5d6"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8=Q+", "b7b8q"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8N", "b7b8n"},
+		{"r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "bxa8=N", "b7a8n"},
+		// Piece moves.
+		{fen.Starting, "Nf3", "g1f3"},
+		{fen.Starting, "Ngf3", "g1f3"},
+		{fen.Starting, "Ng1f3!?", "g1f3"},
+		{"4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", "Rad1", "a1d1"},
+		{"4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", "Rfe1+", "f1e1"},
+		{"4k3/R7/8/8/8/8/8/R3K3 w - - 0 1", "R1a4", "a1a4"},
+		{"k7/8/8/8/8/2Q1Q3/8/2Q1K3 w - - 0 1", "Qc3d2", "c3d2"},
+		// Castling.
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O", "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "O-O-O", "e8c8"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "0-0-0", "e8c8"},
+		{"1r4kr/8/8/8/8/8/8/1R4KR w HBhb - 0 1", "O-O", "g1h1"},
+		{"1r4kr/8/8/8/8/8/8/1R4KR b HBhb - 0 1", "O-O-O", "g8b8"},
+		// Checkmates.
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "Ra8#", "a1a8"},
+		// Drops.
+		{"4k3/8/8/8/8/8/8/4K3[N] w - - 0 1", "N@f6+", "N@f6"},
+		{"4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", "P@e4", "P@e4"},
+		{"4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", "@e4", "P@e4"},
+		{"4k3/8/8/7R/8/P7/8/R3K3[R] w - - 0 1", "Ra5", "h5a5"},
+	}
+
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.in)
+		got, err := Decode(p, tc.san)
+		if err != nil {
+			t.Errorf("%q, %s: error: %v", tc.in, tc.san, err)
+			continue
+		}
+		if pcn.Encode(got) != tc.want {
+			t.Errorf("%q, %s: got %s, want %s", tc.in, tc.san, pcn.Encode(got), tc.want)
+		}
+	}
+}
+
+func TestDecode_Error(t *testing.T) {
+	cases := []struct {
+		in  string
+		san string
+	}{
+		{fen.Starting, ""},
+		{fen.Starting, "e5"},
+		{fen.Starting, "Nd2"},
+		{fen.Starting, "O-O"},
+		{fen.Starting, "Zf3"},
+		{fen.Starting, "e9"},
+		{"4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", "Rd1"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8"},
+		{"4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", "N@e4"},
+		{"4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", "P@e8"},
+		{"4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", "K@e4"},
+		{"4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", "P@e"},
+	}
+
+	for _, tc := range cases {
+		if m, err := Decode(fen.MustDecode(tc.in), tc.san); err == nil {
+			t.Errorf("%q, %q: got %s, want error", tc.in, tc.san, pcn.Encode(m))
+		}
+	}
+}
+
+func TestDecode_RoundTrip(t *testing.T) {
+	p := fen.MustDecode("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	for _, m := range movegen.LegalMoves(p) {
+		s := Encode(p, m)
+		got, err := Decode(p, s)
+		if err != nil || got != m {
+			t.Errorf("%s: got %s, %v, want %s", s, pcn.Encode(got), err, pcn.Encode(m))
+		}
+	}
+}
+
+func TestDecode_KingPromotion(t *testing.T) {
+	p := fen.MustDecode("8/P7/8/8/8/8/8/7k w - - 0 1")
+	if _, err := Decode(p, "a8=K"); err == nil {
+		t.Errorf("a8=K in standard chess: got no error")
+	}
+
+	p.Variant = core.Antichess
+	m, err := Decode(p, "a8=K")
+	if err != nil || pcn.Encode(m) != "a7a8k" {
+		t.Errorf("a8=K in Antichess: got %s, %v, want a7a8k", pcn.Encode(m), err)
+	}
+	if got := Encode(p, m); got != "a8=K" {
+		t.Errorf("a7a8k in Antichess: got %q, want %q", got, "a8=K")
+	}
+}
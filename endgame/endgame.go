@@ -0,0 +1,329 @@
+// Package endgame implements evaluation of endgames with specific material.
+//
+// Evaluate scores endgames that a general evaluation gets wrong, like KBNK,
+// which is won but needs the defending king driven to the right corner, or
+// KPK, which it looks up in a bitbase. Scale recognizes endgames that are more
+// drawish than their material suggests.
+package endgame
+
+import (
+	"strings"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/movegen"
+)
+
+// KnownWin is added to the score of endgames known to be won, so that they
+// score higher than any position with an unclear result.
+const KnownWin = 10000
+
+// ScaleNormal is the scale factor of an evaluation that needs no scaling.
+const ScaleNormal = 64
+
+// Piece values, matching the general evaluation.
+const (
+	pawnValue   = 100
+	knightValue = 300
+	bishopValue = 300
+	rookValue   = 500
+	queenValue  = 900
+)
+
+// An evaluator scores an endgame from the point of view of the strong side.
+type evaluator func(p core.Position, strong core.Color) int
+
+// evaluators are keyed by material signature, with the strong side first.
+var evaluators = map[string]evaluator{
+	"KvK":   draw,
+	"KBvK":  draw,
+	"KNvK":  draw,
+	"KNNvK": draw,
+	"KPvK":  evalKPK,
+	"KRvK":  evalKXK,
+	"KQvK":  evalKXK,
+	"KBNvK": evalKBNK,
+	"KRvKP": evalKRKP,
+}
+
+// Signature returns the material signature of a position, like "KRvKP", with
+// the pieces of side c first.
+func Signature(p core.Position, c core.Color) string {
+	var b strings.Builder
+	for i, side := range []core.Color{c, c.Other()} {
+		if i > 0 {
+			b.WriteByte('v')
+		}
+		for _, pt := range []core.PieceType{core.King, core.Queen, core.Rook, core.Bishop, core.Knight, core.Pawn} {
+			b.WriteString(strings.Repeat(pieceLetters[pt], count(p, side, pt)))
+		}
+	}
+	return b.String()
+}
+
+var pieceLetters = [...]string{
+	core.Pawn:   "P",
+	core.Knight: "N",
+	core.Bishop: "B",
+	core.Rook:   "R",
+	core.Queen:  "Q",
+	core.King:   "K",
+}
+
+// Evaluate returns the score of a position relative to the side to move, if
+// there's an evaluator for its material.
+func Evaluate(p core.Position) (int, bool) {
+	for _, strong := range []core.Color{core.White, core.Black} {
+		f, ok := evaluators[Signature(p, strong)]
+		if !ok {
+			continue
+		}
+		v := f(p, strong)
+		if p.SideToMove != strong {
+			v = -v
+		}
+		return v, true
+	}
+	return 0, false
+}
+
+// draw scores endgames that can't be won.
+func draw(core.Position, core.Color) int {
+	return 0
+}
+
+// evalKPK uses the KPK bitbase. Won positions score higher as the pawn
+// advances.
+func evalKPK(p core.Position, strong core.Color) int {
+	if !probeKPK(p, strong) {
+		return 0
+	}
+	pawn := relative(p.Board[core.NewPiece(strong, core.Pawn)].First(), strong)
+	return KnownWin + pawnValue + 10*int(pawn.Rank())
+}
+
+// evalKXK scores KQK and KRK, which are won by driving the weak king to the
+// edge with the strong king close by.
+func evalKXK(p core.Position, strong core.Color) int {
+	// A lone king to move may be stalemated.
+	if p.SideToMove != strong && len(movegen.LegalMoves(p)) == 0 && !movegen.InCheck(p) {
+		return 0
+	}
+
+	var (
+		sk = p.Board[core.NewPiece(strong, core.King)].First()
+		wk = p.Board[core.NewPiece(strong.Other(), core.King)].First()
+	)
+	return KnownWin + material(p, strong) + pushToEdge(wk) + pushClose(sk, wk)
+}
+
+// evalKBNK scores KBNK, which is won by driving the weak king to a corner of
+// the bishop's color.
+func evalKBNK(p core.Position, strong core.Color) int {
+	var (
+		sk     = p.Board[core.NewPiece(strong, core.King)].First()
+		wk     = p.Board[core.NewPiece(strong.Other(), core.King)].First()
+		bishop = p.Board[core.NewPiece(strong, core.Bishop)].First()
+	)
+
+	// Mirror the board so that the bishop is on a dark square, like a1 and
+	// h8.
+	if !isDark(bishop) {
+		wk = wk ^ 7
+	}
+	corner := min(distance(wk, core.A1), distance(wk, core.H8))
+
+	return KnownWin + knightValue + bishopValue + pushClose(sk, wk) + 20*(7-corner)
+}
+
+// evalKRKP scores a rook against a pawn, which is won unless the pawn is far
+// advanced and supported by its king.
+func evalKRKP(p core.Position, strong core.Color) int {
+	var (
+		weak  = strong.Other()
+		sk    = relative(p.Board[core.NewPiece(strong, core.King)].First(), strong)
+		wk    = relative(p.Board[core.NewPiece(weak, core.King)].First(), strong)
+		rook  = relative(p.Board[core.NewPiece(strong, core.Rook)].First(), strong)
+		pawn  = relative(p.Board[core.NewPiece(weak, core.Pawn)].First(), strong)
+		queen = core.NewSquare(pawn.File(), core.Rank1)
+		push  = pawn.Below()
+	)
+
+	var (
+		strongToMove = 0
+		weakToMove   = 0
+	)
+	if p.SideToMove == strong {
+		strongToMove = 1
+	} else {
+		weakToMove = 1
+	}
+
+	switch {
+	case sk.File() == pawn.File() && sk.Rank() < pawn.Rank():
+		// The strong king is in front of the pawn.
+		return rookValue - distance(sk, pawn)
+	case distance(wk, pawn) >= 3+weakToMove && distance(wk, rook) >= 3:
+		// The weak king is too far from the pawn and the rook.
+		return rookValue - distance(sk, pawn)
+	case wk.Rank() <= core.Rank3 && distance(wk, pawn) == 1 &&
+		sk.Rank() >= core.Rank4 && distance(sk, pawn) > 2+strongToMove:
+		// The pawn is far advanced and supported.
+		return 80 - 8*distance(sk, pawn)
+	default:
+		return 200 - 8*(distance(sk, push)-distance(wk, push)-distance(pawn, queen))
+	}
+}
+
+// A scaler returns a scale factor for an evaluation favoring the strong
+// side, and whether it applies.
+type scaler func(p core.Position, strong core.Color) (int, bool)
+
+var scalers = []scaler{
+	scaleWrongBishop,
+	scaleOppositeBishops,
+}
+
+// Scale returns the factor, out of ScaleNormal, by which to scale an
+// evaluation that favors the strong side.
+func Scale(p core.Position, strong core.Color) int {
+	for _, f := range scalers {
+		if v, ok := f(p, strong); ok {
+			return v
+		}
+	}
+	return ScaleNormal
+}
+
+// scaleWrongBishop recognizes a bishop and rook pawns against a king that
+// reaches the queening square, when the bishop doesn't control it. The
+// defending king can't be driven out, so it's a draw.
+func scaleWrongBishop(p core.Position, strong core.Color) (int, bool) {
+	weak := strong.Other()
+	if nonPawnMaterial(p, strong) != bishopValue || count(p, strong, core.Bishop) != 1 ||
+		count(p, strong, core.Pawn) == 0 || nonPawnMaterial(p, weak) != 0 {
+		return 0, false
+	}
+
+	pawns := p.Board[core.NewPiece(strong, core.Pawn)]
+	var queen core.Square
+	switch {
+	case pawns&fileBitboard(core.FileA) == pawns:
+		queen = relative(core.A8, strong)
+	case pawns&fileBitboard(core.FileH) == pawns:
+		queen = relative(core.H8, strong)
+	default:
+		return 0, false
+	}
+
+	bishop := p.Board[core.NewPiece(strong, core.Bishop)].First()
+	wk := p.Board[core.NewPiece(weak, core.King)].First()
+	if isDark(bishop) != isDark(queen) && distance(wk, queen) <= 1 {
+		return 0, true
+	}
+	return 0, false
+}
+
+// scaleOppositeBishops recognizes endgames with only bishops of opposite
+// colors and pawns, which are drawish, especially if the strong side is up by
+// a single pawn.
+func scaleOppositeBishops(p core.Position, strong core.Color) (int, bool) {
+	weak := strong.Other()
+	if nonPawnMaterial(p, strong) != bishopValue || nonPawnMaterial(p, weak) != bishopValue ||
+		count(p, strong, core.Bishop) != 1 || count(p, weak, core.Bishop) != 1 {
+		return 0, false
+	}
+
+	var (
+		b1 = p.Board[core.NewPiece(strong, core.Bishop)].First()
+		b2 = p.Board[core.NewPiece(weak, core.Bishop)].First()
+	)
+	if isDark(b1) == isDark(b2) {
+		return 0, false
+	}
+
+	if count(p, strong, core.Pawn)-count(p, weak, core.Pawn) <= 1 {
+		return ScaleNormal / 4, true
+	}
+	return ScaleNormal / 2, true
+}
+
+// count returns the number of pieces of a color and type.
+func count(p core.Position, c core.Color, pt core.PieceType) int {
+	return p.Board[core.NewPiece(c, pt)].Count()
+}
+
+// material returns the value of the pieces of a color.
+func material(p core.Position, c core.Color) int {
+	return nonPawnMaterial(p, c) + pawnValue*count(p, c, core.Pawn)
+}
+
+// nonPawnMaterial returns the value of the pieces of a color, except pawns.
+func nonPawnMaterial(p core.Position, c core.Color) int {
+	return knightValue*count(p, c, core.Knight) +
+		bishopValue*count(p, c, core.Bishop) +
+		rookValue*count(p, c, core.Rook) +
+		queenValue*count(p, c, core.Queen)
+}
+
+// pushToEdge rewards a weak king near the edge of the board.
+func pushToEdge(s core.Square) int {
+	f, r := int(s.File()), int(s.Rank())
+	return 20 * (max(3-f, f-4) + max(3-r, r-4))
+}
+
+// pushClose rewards kings that are close together.
+func pushClose(a, b core.Square) int {
+	return 20 * (7 - distance(a, b))
+}
+
+// relative returns a square from the point of view of a color, so that each
+// side's pawns move up the board.
+func relative(s core.Square, c core.Color) core.Square {
+	if c == core.Black {
+		return s ^ 56
+	}
+	return s
+}
+
+// distance returns the number of king moves between two squares.
+func distance(a, b core.Square) int {
+	df := int(a.File()) - int(b.File())
+	dr := int(a.Rank()) - int(b.Rank())
+	return max(df, -df, dr, -dr)
+}
+
+// isDark reports whether a square is dark, like a1.
+func isDark(s core.Square) bool {
+	return (int(s.File())+int(s.Rank()))%2 == 0
+}
+
+// fileBitboard returns the squares on a file.
+func fileBitboard(f core.File) core.Bitboard {
+	return 0x0101010101010101 << f
+}
+
+// kingAttacks returns the squares a king attacks.
+func kingAttacks(s core.Square) core.Bitboard {
+	var b core.Bitboard
+	for t := core.A1; t <= core.H8; t++ {
+		if distance(s, t) == 1 {
+			b.Set(t)
+		}
+	}
+	return b
+}
+
+// pawnAttacks returns the squares a white pawn attacks.
+func pawnAttacks(s core.Square) core.Bitboard {
+	var b core.Bitboard
+	if s.Rank() == core.Rank8 {
+		return b
+	}
+	if s.File() > core.FileA {
+		b.Set(s.Above().Left())
+	}
+	if s.File() < core.FileH {
+		b.Set(s.Above().Right())
+	}
+	return b
+}
@@ -0,0 +1,145 @@
+package endgame
+
+import (
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+)
+
+func TestSignature(t *testing.T) {
+	cases := []struct {
+		in   string
+		c    core.Color
+		want string
+	}{
+		{"4k3/8/8/8/8/1R6/8/4K3 w - - 0 1", core.White, "KRvK"},
+		{"4k3/8/8/8/8/1R6/8/4K3 w - - 0 1", core.Black, "KvKR"},
+		{fen.Starting, core.White, "KQRRBBNNPPPPPPPPvKQRRBBNNPPPPPPPP"},
+	}
+
+	for _, tc := range cases {
+		if got := Signature(fen.MustDecode(tc.in), tc.c); got != tc.want {
+			t.Errorf("%q: got %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	cases := []struct {
+		in     string
+		win    bool // Won for the side to move.
+		loss   bool // Lost for the side to move.
+		noEval bool
+	}{
+		{in: "4k3/8/8/8/8/8/8/4K3 w - - 0 1"},
+		{in: "4k3/8/8/8/8/1B6/8/4K3 w - - 0 1"},
+		{in: "4k3/8/8/8/8/1n6/8/4K3 w - - 0 1"},
+		{in: "4k3/8/8/8/8/1R6/8/4K3 w - - 0 1", win: true},
+		{in: "4k3/8/8/8/8/1R6/8/4K3 b - - 0 1", loss: true},
+		{in: "4k3/8/8/8/8/1q6/8/4K3 w - - 0 1", loss: true},
+		{in: "4k3/8/8/8/8/1BN5/8/4K3 w - - 0 1", win: true},
+		{in: "k7/4P3/8/8/8/8/8/4K3 w - - 0 1", win: true},
+		{in: "k7/8/8/8/8/8/P7/K7 w - - 0 1"},
+		// Stalemate.
+		{in: "k7/1R6/1K6/8/8/8/8/8 b - - 0 1"},
+		{in: "4k3/8/8/8/8/1R6/8/3QK3 w - - 0 1", noEval: true},
+	}
+
+	for _, tc := range cases {
+		got, ok := Evaluate(fen.MustDecode(tc.in))
+		switch {
+		case ok == tc.noEval:
+			t.Errorf("%q: got ok %t", tc.in, ok)
+		case tc.win && got < KnownWin, tc.loss && got > -KnownWin, !tc.win && !tc.loss && got != 0:
+			t.Errorf("%q: got %d", tc.in, got)
+		}
+	}
+}
+
+func TestEvaluate_Progress(t *testing.T) {
+	// Each position is better for the strong side than the next.
+	cases := []struct {
+		name string
+		in   []string
+	}{
+		{
+			"KQvK edge",
+			[]string{
+				"k7/8/2K5/8/8/8/8/7Q w - - 0 1",
+				"8/8/2k5/8/4K3/8/8/7Q w - - 0 1",
+			},
+		},
+		{
+			"KBNvK corner",
+			[]string{
+				"k7/8/2K5/8/8/8/8/5BN1 w - - 0 1",
+				"7k/8/5K2/8/8/8/8/5BN1 w - - 0 1",
+			},
+		},
+		{
+			"KPvK advance",
+			[]string{
+				"k7/8/8/8/4P3/4K3/8/8 w - - 0 1",
+				"k7/8/8/8/8/4K3/4P3/8 w - - 0 1",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		for i := 1; i < len(tc.in); i++ {
+			a, _ := Evaluate(fen.MustDecode(tc.in[i-1]))
+			b, _ := Evaluate(fen.MustDecode(tc.in[i]))
+			if a <= b {
+				t.Errorf("%s: %q scores %d, %q scores %d", tc.name, tc.in[i-1], a, tc.in[i], b)
+			}
+		}
+	}
+}
+
+func TestEvaluate_KRKP(t *testing.T) {
+	cases := []struct {
+		in  string
+		win bool
+	}{
+		// The white king is in front of the pawn.
+		{"8/8/8/8/3p4/8/3K4/k6R w - - 0 1", true},
+		// The black king is too far away.
+		{"7k/8/8/8/8/3p4/8/K6R w - - 0 1", true},
+		// The pawn is far advanced and supported.
+		{"8/K7/8/8/8/8/1pk5/7R w - - 0 1", false},
+	}
+
+	for _, tc := range cases {
+		got, _ := Evaluate(fen.MustDecode(tc.in))
+		if win := got >= rookValue-pawnValue; win != tc.win {
+			t.Errorf("%q: got %d", tc.in, got)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	cases := []struct {
+		in     string
+		strong core.Color
+		want   int
+	}{
+		{fen.Starting, core.White, ScaleNormal},
+		// Opposite-colored bishops.
+		{"4k3/p7/3b4/8/8/1B6/PP6/4K3 w - - 0 1", core.White, ScaleNormal / 4},
+		{"4k3/p7/3b4/8/8/1B6/PPP5/4K3 w - - 0 1", core.White, ScaleNormal / 2},
+		// Same-colored bishops.
+		{"4k3/p7/2b5/8/8/1B6/PP6/4K3 w - - 0 1", core.White, ScaleNormal},
+		// The bishop doesn't control a8, and the black king is there.
+		{"k7/8/8/P7/8/8/P7/4K1B1 w - - 0 1", core.White, 0},
+		{"k7/8/8/P7/8/8/P7/4KB2 w - - 0 1", core.White, ScaleNormal},
+		{"8/8/8/8/8/P7/P7/1k2K1B1 w - - 0 1", core.White, ScaleNormal},
+		{"4k1b1/p7/8/8/8/8/8/K7 b - - 0 1", core.Black, 0},
+	}
+
+	for _, tc := range cases {
+		if got := Scale(fen.MustDecode(tc.in), tc.strong); got != tc.want {
+			t.Errorf("%q: got %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package endgame
+
+import "github.com/clfs/simple/core"
+
+// The KPK bitbase stores whether each position with a white king and pawn
+// against a black king is won for white. Positions have the pawn on files a-d;
+// the others are mirror images.
+//
+// It's generated by retrograde analysis: positions are classified as won or
+// drawn from their successors until no more can be.
+
+// kpkSize is the number of positions: 2 sides to move, 24 pawn squares and 64
+// squares for each king.
+const kpkSize = 2 * 24 * 64 * 64
+
+// kpkBits holds one bit per position, set if it's won for white.
+var kpkBits [kpkSize / 64]uint64
+
+func init() {
+	generateKPK()
+}
+
+// kpkIndex returns the index of a position, where white is true if white is
+// to move.
+func kpkIndex(white bool, bk, wk, pawn core.Square) int {
+	stm := 1
+	if white {
+		stm = 0
+	}
+	return int(wk) | int(bk)<<6 | stm<<12 | int(pawn.File())<<13 | int(core.Rank7-pawn.Rank())<<15
+}
+
+// kpkResult is the classification of a position during generation. Results
+// are bit flags, so that the results of successors can be combined.
+type kpkResult uint8
+
+const (
+	kpkInvalid kpkResult = 0
+	kpkUnknown kpkResult = 1
+	kpkDraw    kpkResult = 2
+	kpkWin     kpkResult = 4
+)
+
+// kpkPosition is a position during generation.
+type kpkPosition struct {
+	white        bool // White to move.
+	wk, bk, pawn core.Square
+	result       kpkResult
+}
+
+func newKPKPosition(idx int) kpkPosition {
+	p := kpkPosition{
+		wk:    core.Square(idx & 63),
+		bk:    core.Square(idx >> 6 & 63),
+		white: idx>>12&1 == 0,
+		pawn:  core.NewSquare(core.File(idx>>13&3), core.Rank7-core.Rank(idx>>15&7)),
+	}
+	push := p.pawn.Above()
+
+	switch {
+	case distance(p.wk, p.bk) <= 1 || p.wk == p.pawn || p.bk == p.pawn ||
+		(p.white && pawnAttacks(p.pawn)&p.bk.Bitboard() != 0):
+		// Pieces overlap, or a king can be captured.
+		p.result = kpkInvalid
+	case p.white && p.pawn.Rank() == core.Rank7 && p.wk != push &&
+		(distance(p.bk, push) > 1 || distance(p.wk, push) == 1):
+		// The pawn promotes without being captured.
+		p.result = kpkWin
+	case !p.white && (kingAttacks(p.bk)&^(kingAttacks(p.wk)|pawnAttacks(p.pawn)) == 0 ||
+		kingAttacks(p.bk)&^kingAttacks(p.wk)&p.pawn.Bitboard() != 0):
+		// Black is stalemated, or captures the pawn.
+		p.result = kpkDraw
+	default:
+		p.result = kpkUnknown
+	}
+
+	return p
+}
+
+// classify classifies a position from its successors. With white to move, a
+// position is won if any move wins, and drawn if every move draws. With black
+// to move, it's drawn if any move draws, and won if every move wins.
+func (p *kpkPosition) classify(db []kpkPosition) kpkResult {
+	good, bad := kpkWin, kpkDraw
+	if !p.white {
+		good, bad = kpkDraw, kpkWin
+	}
+
+	var r kpkResult
+	if p.white {
+		for b := kingAttacks(p.wk); b != 0; b.Clear(b.First()) {
+			r |= db[kpkIndex(false, p.bk, b.First(), p.pawn)].result
+		}
+		if p.pawn.Rank() < core.Rank7 {
+			r |= db[kpkIndex(false, p.bk, p.wk, p.pawn.Above())].result
+		}
+		if push := p.pawn.Above(); p.pawn.Rank() == core.Rank2 && push != p.wk && push != p.bk {
+			r |= db[kpkIndex(false, p.bk, p.wk, push.Above())].result
+		}
+	} else {
+		for b := kingAttacks(p.bk); b != 0; b.Clear(b.First()) {
+			r |= db[kpkIndex(true, b.First(), p.wk, p.pawn)].result
+		}
+	}
+
+	switch {
+	case r&good != 0:
+		p.result = good
+	case r&kpkUnknown != 0:
+		p.result = kpkUnknown
+	default:
+		p.result = bad
+	}
+	return p.result
+}
+
+// generateKPK fills in kpkBits.
+func generateKPK() {
+	db := make([]kpkPosition, kpkSize)
+	for i := range db {
+		db[i] = newKPKPosition(i)
+	}
+
+	// Classify positions until none changes. Positions still unknown then
+	// are draws.
+	for changed := true; changed; {
+		changed = false
+		for i := range db {
+			if db[i].result == kpkUnknown && db[i].classify(db) != kpkUnknown {
+				changed = true
+			}
+		}
+	}
+
+	for i, p := range db {
+		if p.result == kpkWin {
+			kpkBits[i/64] |= 1 << (i % 64)
+		}
+	}
+}
+
+// probeKPK returns true if a position with a king and pawn against a king is
+// won for the side with the pawn.
+func probeKPK(p core.Position, strong core.Color) bool {
+	var (
+		wk   = relative(p.Board[core.NewPiece(strong, core.King)].First(), strong)
+		bk   = relative(p.Board[core.NewPiece(strong.Other(), core.King)].First(), strong)
+		pawn = relative(p.Board[core.NewPiece(strong, core.Pawn)].First(), strong)
+	)
+	if pawn.File() > core.FileD {
+		wk, bk, pawn = wk^7, bk^7, pawn^7
+	}
+
+	i := kpkIndex(p.SideToMove == strong, bk, wk, pawn)
+	return kpkBits[i/64]&(1<<(i%64)) != 0
+}
@@ -0,0 +1,44 @@
+package endgame
+
+import (
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+)
+
+func TestProbeKPK(t *testing.T) {
+	cases := []struct {
+		in     string
+		strong core.Color
+		want   bool
+	}{
+		// The pawn promotes.
+		{"k7/4P3/8/8/8/8/8/4K3 w - - 0 1", core.White, true},
+		// The king is in front of the pawn on the sixth rank.
+		{"4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", core.White, true},
+		{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", core.White, true},
+		// The defending king is in front of a rook pawn.
+		{"k7/8/8/8/8/8/P7/K7 w - - 0 1", core.White, false},
+		// The pawn is captured.
+		{"8/8/8/8/8/8/3kP3/7K b - - 0 1", core.White, false},
+		// Black keeps the opposition.
+		{"4k3/8/4P3/4K3/8/8/8/8 w - - 0 1", core.White, false},
+		// Black is stalemated.
+		{"4k3/4P3/4K3/8/8/8/8/8 b - - 0 1", core.White, false},
+		// The same positions, with colors reversed.
+		{"4k3/8/8/8/8/8/4p3/K7 b - - 0 1", core.Black, true},
+		{"8/8/8/8/4p3/4k3/8/4K3 w - - 0 1", core.Black, true},
+		{"k7/p7/8/8/8/8/8/K7 b - - 0 1", core.Black, false},
+		{"7k/3Kp3/8/8/8/8/8/8 w - - 0 1", core.Black, false},
+		// The same positions, mirrored.
+		{"7k/3P4/8/8/8/8/8/3K4 w - - 0 1", core.White, true},
+		{"7k/8/8/8/8/8/7P/7K w - - 0 1", core.White, false},
+	}
+
+	for _, tc := range cases {
+		if got := probeKPK(fen.MustDecode(tc.in), tc.strong); got != tc.want {
+			t.Errorf("%q: got %t, want %t", tc.in, got, tc.want)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 // Package eval describes the signature of evaluation functions.
 package eval
 
-import "github.com/clfs/simple/core"
+import (
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/endgame"
+)
 
 var pieceTypeWeights = map[core.PieceType]int{
 	core.Pawn:   100,
@@ -15,7 +18,17 @@ var pieceTypeWeights = map[core.PieceType]int{
 //
 // Positive values indicate an advantage for the side to move, and negative
 // values indicate an advantage for the opponent.
+//
+// Endgames with specific material are scored by package endgame, except in
+// variants, where its knowledge doesn't hold.
 func Eval(p core.Position) int {
+	standard := p.Variant == core.Standard
+	if standard {
+		if v, ok := endgame.Evaluate(p); ok {
+			return v
+		}
+	}
+
 	var res int
 	for s := core.A1; s <= core.H8; s++ {
 		piece, ok := p.Board.Get(s)
@@ -28,5 +41,26 @@ func Eval(p core.Position) int {
 			res -= pieceTypeWeights[piece.Type()]
 		}
 	}
+
+	// Pieces in Crazyhouse pockets can be dropped, so they count as material.
+	if p.Variant == core.Crazyhouse {
+		for pt := core.Pawn; pt < core.King; pt++ {
+			n := p.Pocket(p.SideToMove)[pt] - p.Pocket(p.SideToMove.Other())[pt]
+			res += n * pieceTypeWeights[pt]
+		}
+	}
+
+	// Antichess is won by losing every piece.
+	if p.Variant == core.Antichess {
+		res = -res
+	}
+
+	switch {
+	case !standard:
+	case res > 0:
+		res = res * endgame.Scale(p, p.SideToMove) / endgame.ScaleNormal
+	case res < 0:
+		res = res * endgame.Scale(p, p.SideToMove.Other()) / endgame.ScaleNormal
+	}
 	return res
 }
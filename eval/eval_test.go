@@ -3,7 +3,9 @@ package eval
 import (
 	"testing"
 
+	"github.com/clfs/simple/core"
 	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/endgame"
 )
 
 func TestEval(t *testing.T) {
@@ -16,13 +18,32 @@ func TestEval(t *testing.T) {
 			0,
 		},
 		{
+			// KRvK is a known win.
 			"4k3/8/8/8/8/1R6/8/4K3 w - - 0 1",
-			500,
+			endgame.KnownWin + 560,
 		},
 		{
 			"4k3/8/8/8/8/1R6/8/4K3 b - - 0 1",
+			-endgame.KnownWin - 560,
+		},
+		{
+			"4k3/p7/8/8/8/1R6/P7/4K3 w - - 0 1",
+			500,
+		},
+		{
+			"4k3/p7/8/8/8/1R6/P7/4K3 b - - 0 1",
 			-500,
 		},
+		{
+			// KBvK is a draw.
+			"4k3/8/8/8/8/1B6/8/4K3 w - - 0 1",
+			0,
+		},
+		{
+			// Opposite-colored bishops, a pawn up.
+			"4k3/p7/3b4/8/8/1B6/PP6/4K3 w - - 0 1",
+			25,
+		},
 	}
 
 	for _, tc := range cases {
@@ -32,3 +53,25 @@ func TestEval(t *testing.T) {
 		}
 	}
 }
+
+func TestEval_Variants(t *testing.T) {
+	cases := []struct {
+		variant core.Variant
+		in      string
+		want    int
+	}{
+		// Pieces in pockets count.
+		{core.Crazyhouse, "4k3/p7/8/8/8/8/8/4K3[Nr] w - - 0 1", -300},
+		// No endgame knowledge, so KBvK isn't a draw.
+		{core.KingOfTheHill, "4k3/8/8/8/8/1B6/8/4K3 w - - 0 1", 300},
+		{core.Antichess, "4k3/p7/8/8/8/1R6/P7/4K3 w - - 0 1", -500},
+	}
+
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.in)
+		p.Variant = tc.variant
+		if got := Eval(p); got != tc.want {
+			t.Errorf("%s %q: want %d, got %d", tc.variant, tc.in, tc.want, got)
+		}
+	}
+}
@@ -12,7 +12,7 @@ import (
 func FuzzLegalMoves(f *testing.F) {
 	f.Add(fen.Starting, "e2e4 d7d5 e4d5")
 	f.Fuzz(func(t *testing.T, pos, moves string) {
-		p, err := fen.Decode(pos)
+		p, err := fen.DecodeStrict(pos)
 		if err != nil {
 			t.Skip() // invalid FEN
 		}
@@ -9,11 +9,24 @@ import (
 
 // LegalMoves returns all legal moves in a position.
 func LegalMoves(p core.Position) []core.Move {
-	if p.HalfMoveClock >= 75 {
+	moves := slices.DeleteFunc(PseudoLegalMoves(p), func(m core.Move) bool {
+		p2 := p
+		p2.Make(m)
+		return isEnemyKingTargeted(p2)
+	})
+
+	return moves
+}
+
+// PseudoLegalMoves returns all moves in a position, including those that leave
+// the king in check. Castling moves still account for checks.
+func PseudoLegalMoves(p core.Position) []core.Move {
+	// The game is drawn by the seventy-five-move rule.
+	if p.HalfMoveClock >= 150 {
 		return nil
 	}
 
-	moves := slices.Concat(
+	return slices.Concat(
 		pawnPushes(p),
 		pawnAttacks(p),
 		knightAttacks(p),
@@ -23,16 +36,51 @@ func LegalMoves(p core.Position) []core.Move {
 		kingAttacks(p),
 		castlingMoves(p),
 	)
+}
 
-	moves = slices.DeleteFunc(moves, func(m core.Move) bool {
-		p2 := p
-		p2.Make(m)
-		return isEnemyKingTargeted(p2)
-	})
+// LegalDrops returns all legal drops in a Crazyhouse position.
+func LegalDrops(p core.Position) []core.Move {
+	if p.HalfMoveClock >= 150 {
+		return nil
+	}
+
+	var moves []core.Move
+
+	pocket := p.Pocket(p.SideToMove)
+	for pt := core.Pawn; pt < core.King; pt++ {
+		if pocket[pt] == 0 {
+			continue
+		}
+		for to := core.A1; to <= core.H8; to++ {
+			if p.Board.IsOccupied(to) {
+				continue
+			}
+			// Pawns can't be dropped on the first or last rank.
+			if pt == core.Pawn && (to.Rank() == core.Rank1 || to.Rank() == core.Rank8) {
+				continue
+			}
+			moves = append(moves, core.Move{To: to, Drop: true, Dropped: pt})
+		}
+	}
+
+	// Drops never expose the king, but in check, only drops that block it
+	// are legal.
+	if InCheck(p) {
+		moves = slices.DeleteFunc(moves, func(m core.Move) bool {
+			p2 := p
+			p2.Make(m)
+			return isEnemyKingTargeted(p2)
+		})
+	}
 
 	return moves
 }
 
+// InCheck returns true if the side to move is in check.
+func InCheck(p core.Position) bool {
+	return isEnemyKingTargeted(switchSides(p))
+}
+
 // A translation describes movement in a direction.
 type translation struct {
 	df, dr int
@@ -72,7 +120,8 @@ func pawnPushes(p core.Position) []core.Move {
 		fromBB = p.Board[core.BlackPawn]
 	}
 
-	for from := core.A2; from <= core.H7; from++ {
+	// In Horde, white pawns start on the first rank too.
+	for from := core.A1; from <= core.H7; from++ {
 		if !fromBB.Get(from) {
 			continue // empty square
 		}
@@ -100,8 +149,8 @@ func pawnPushes(p core.Position) []core.Move {
 			continue // double push not possible
 		}
 
-		// double push
-		if p.SideToMove == core.White && from.Rank() == core.Rank2 {
+		// double push, which pawns on the first rank can also make in Horde
+		if p.SideToMove == core.White && (from.Rank() == core.Rank1 || from.Rank() == core.Rank2) {
 			to = from.Above().Above()
 			if p.Board.IsEmpty(to) {
 				moves = append(moves, core.Move{From: from, To: to})
@@ -129,7 +178,7 @@ func squaresAttackedByPawns(p core.Position) core.Bitboard {
 		fromBB = p.Board[core.BlackPawn]
 	}
 
-	for from := core.A2; from <= core.H7; from++ {
+	for from := core.A1; from <= core.H7; from++ {
 		if !fromBB.Get(from) {
 			continue // empty square
 		}
@@ -166,7 +215,7 @@ func pawnAttacks(p core.Position) []core.Move {
 
 	fromBB := p.Board[core.NewPiece(p.SideToMove, core.Pawn)]
 
-	for from := core.A2; from <= core.H7; from++ {
+	for from := core.A1; from <= core.H7; from++ {
 		// Skip non-starting squares.
 		if !fromBB.Get(from) {
 			continue
@@ -358,48 +407,83 @@ func switchSides(p core.Position) core.Position {
 // castlingMoves returns available castling moves.
 // It accounts for checks and enemy attacks.
 func castlingMoves(p core.Position) []core.Move {
+	// Sides without a king, like white in Horde, can't castle.
+	if p.Board[core.NewPiece(p.SideToMove, core.King)] == 0 {
+		return nil
+	}
+
 	attacked := attackedSquares(switchSides(p))
 
+	king := p.FriendlyKing()
+
 	// If the king is in check, castling isn't possible.
-	if attacked.Get(p.FriendlyKing()) {
+	if attacked.Get(king) {
 		return nil
 	}
 
+	rights := []bool{p.WhiteOO, p.WhiteOOO}
+	if p.SideToMove == core.Black {
+		rights = []bool{p.BlackOO, p.BlackOOO}
+	}
+
 	var moves []core.Move
 
-	if p.SideToMove == core.White {
-		if p.WhiteOO {
-			targets := core.NewBitboard(core.F1, core.G1)
-			if !attacked.Intersects(targets) && p.Board.AllEmpty(targets) {
-				moves = append(moves, core.Move{From: core.E1, To: core.G1})
-			}
+	for i, ok := range rights {
+		if !ok {
+			continue
 		}
-		if p.WhiteOOO {
-			targets := core.NewBitboard(core.C1, core.D1)
-			empties := core.NewBitboard(core.B1, core.C1, core.D1)
-			if !attacked.Intersects(targets) && p.Board.AllEmpty(empties) {
-				moves = append(moves, core.Move{From: core.E1, To: core.C1})
-			}
+		kingside := i == 0
+
+		// Rights are only usable with the king and rook on the back rank,
+		// and outside of Chess960, the king on the e-file.
+		rook := p.CastlingRook(p.SideToMove, kingside)
+		if piece, ok := p.Board.Get(rook); !ok || piece != core.NewPiece(p.SideToMove, core.Rook) || king.Rank() != rook.Rank() {
+			continue
 		}
-	} else {
-		if p.BlackOO {
-			targets := core.NewBitboard(core.F8, core.G8)
-			if !attacked.Intersects(targets) && p.Board.AllEmpty(targets) {
-				moves = append(moves, core.Move{From: core.E8, To: core.G8})
-			}
+		if !p.Chess960 && king.File() != core.FileE {
+			continue
 		}
-		if p.BlackOOO {
-			targets := core.NewBitboard(core.C8, core.D8)
-			empties := core.NewBitboard(core.B8, core.C8, core.D8)
-			if !attacked.Intersects(targets) && p.Board.AllEmpty(empties) {
-				moves = append(moves, core.Move{From: core.E8, To: core.C8})
-			}
+
+		kingFile, rookFile := core.FileG, core.FileF
+		if !kingside {
+			kingFile, rookFile = core.FileC, core.FileD
+		}
+		kingTo := core.NewSquare(kingFile, king.Rank())
+		rookTo := core.NewSquare(rookFile, king.Rank())
+
+		// The king's path can't be attacked, and the king's and rook's
+		// paths must be empty, except for the king and rook.
+		path := span(king, kingTo)
+		empties := path | span(rook, rookTo)
+		empties.Clear(king)
+		empties.Clear(rook)
+		if attacked.Intersects(path) || !p.Board.AllEmpty(empties) {
+			continue
+		}
+
+		if p.Chess960 {
+			moves = append(moves, core.Move{From: king, To: rook})
+		} else {
+			moves = append(moves, core.Move{From: king, To: kingTo})
 		}
 	}
 
 	return moves
 }
 
+// span returns the squares from one square to another on the same rank,
+// inclusive.
+func span(a, b core.Square) core.Bitboard {
+	if a > b {
+		a, b = b, a
+	}
+	var bb core.Bitboard
+	for s := a; s <= b; s++ {
+		bb.Set(s)
+	}
+	return bb
+}
+
 // attackedSquares returns all squares attacked by the side to move.
 // It does not consider checks.
 func attackedSquares(p core.Position) core.Bitboard {
@@ -2,12 +2,42 @@ package movegen
 
 import (
 	"github.com/clfs/simple/core"
-	"github.com/clfs/simple/movegen/internal/reference"
 )
 
-// LegalMoves returns all legal moves in a position.
+// LegalMoves returns all legal moves in a position. There are none if a
+// variant rule ended the game.
 func LegalMoves(p core.Position) []core.Move {
-	return reference.LegalMoves(p)
+	r := rulesOf(p)
+	if _, ok := r.end(p); ok {
+		return nil
+	}
+	return r.legalMoves(p)
+}
+
+// VariantEnd returns the outcome for the side to move if a variant rule ended
+// the game, like a king reaching the center in King of the Hill.
+func VariantEnd(p core.Position) (Outcome, bool) {
+	return rulesOf(p).end(p)
+}
+
+// GameOver returns the outcome for the side to move if the game is over, by
+// checkmate, stalemate, or a variant rule. It doesn't detect draws by
+// repetition, the fifty-move rule, or insufficient material.
+func GameOver(p core.Position) (Outcome, bool) {
+	r := rulesOf(p)
+	if o, ok := r.end(p); ok {
+		return o, true
+	}
+	if len(r.legalMoves(p)) > 0 {
+		return Draw, false
+	}
+	return r.noMoves(p), true
+}
+
+// InCheck returns true if the side to move is in check. There's no check in
+// Antichess, or in Atomic when the kings touch.
+func InCheck(p core.Position) bool {
+	return rulesOf(p).inCheck(p)
 }
 
 // Perft returns the number of leaf nodes at the selected depth in a position's
@@ -59,6 +59,39 @@ func TestPerft(t *testing.T) {
 	}
 }
 
+func TestPerft_Chess960(t *testing.T) {
+	// https://www.chessprogramming.org/Chess960_Perft_Results
+	cases := []struct {
+		in   string
+		want []int
+	}{
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []int{1, 21, 528, 12189, 326672}},
+		{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []int{1, 21, 807, 18002, 667366}},
+		{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []int{1, 20, 479, 10471, 273318}},
+		{"qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9", []int{1, 22, 593, 13440, 382958}},
+		{"1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9", []int{1, 28, 1120, 31058, 1171749}},
+		{"qnbnr1kr/ppp1b1pp/4p3/3p1p2/8/2NPP3/PPP1BPPP/QNB1R1KR w HEhe - 1 9", []int{1, 29, 899, 26578, 824055}},
+		{"q1bnrkr1/ppppp2p/2n2p2/4b1p1/2NP4/8/PPP1PPPP/QNB1RRKB w ge - 1 9", []int{1, 30, 860, 24566, 732757}},
+		{"qbn1brkr/ppp1p1p1/2n4p/3p1p2/P7/6PP/QPPPPP2/1BNNBRKR w HFhf - 0 9", []int{1, 25, 635, 17054, 465806}},
+		{"qn1rbbkr/ppp2p1p/1n1pp1p1/8/3P4/P6P/1PP1PPPK/QNNRBB1R w hd - 2 9", []int{1, 28, 811, 23175, 679699}},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			p := fen.MustDecode(tc.in)
+			if !p.Chess960 {
+				t.Fatalf("%q isn't a Chess960 position", tc.in)
+			}
+			for i, want := range tc.want {
+				if got := Perft(p, i); got != want {
+					t.Errorf("%q at depth %d: got %d, want %d", tc.in, i, got, want)
+				}
+			}
+		})
+	}
+}
+
 func TestPerft_NegativeDepth(t *testing.T) {
 	p := core.NewPosition()
 	if got := Perft(p, -1); got != 0 {
@@ -66,6 +99,45 @@ func TestPerft_NegativeDepth(t *testing.T) {
 	}
 }
 
+func TestInCheck(t *testing.T) {
+	cases := []struct {
+		in   string
+		want bool
+	}{
+		{fen.Starting, false},
+		{"rnbqkbnr/ppppp2p/5p2/6pQ/4P3/8/PPPP1PPP/RNB1KBNR b KQkq - 1 3", true},
+		{"4k3/8/8/8/8/8/8/R3K3 b - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/4K2R b - - 0 1", false},
+		{"4k3/8/8/8/8/8/3n4/4K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/5n2/4K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/3n4/8/4K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/3p4/4K3 w - - 0 1", true},
+	}
+
+	for _, tc := range cases {
+		if got := InCheck(fen.MustDecode(tc.in)); got != tc.want {
+			t.Errorf("%q: got %t, want %t", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestLegalMoves_SeventyFiveMoveRule(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int
+	}{
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 99 80", 15},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 149 100", 15},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 150 100", 0},
+	}
+
+	for _, tc := range cases {
+		if got := len(LegalMoves(fen.MustDecode(tc.in))); got != tc.want {
+			t.Errorf("%q: got %d moves, want %d", tc.in, got, tc.want)
+		}
+	}
+}
+
 // encodeMoves encodes moves as a sorted slice of PCN strings.
 func encodeMoveMap(t *testing.T, m map[core.Move]int) map[string]int {
 	t.Helper()
@@ -0,0 +1,284 @@
+package movegen
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/movegen/internal/reference"
+)
+
+// An Outcome is the outcome of a finished game for the side to move.
+type Outcome int
+
+// Outcome constants.
+const (
+	Loss Outcome = -1
+	Draw Outcome = 0
+	Win  Outcome = 1
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Loss:
+		return "loss"
+	case Draw:
+		return "draw"
+	case Win:
+		return "win"
+	default:
+		return fmt.Sprintf("Outcome(%d)", int(o))
+	}
+}
+
+// rules are the rules of a variant, as hooks into standard chess.
+type rules interface {
+	// end returns the outcome for the side to move if a variant rule ended
+	// the game before its move.
+	end(p core.Position) (Outcome, bool)
+
+	// legalMoves returns the legal moves in a position where the game
+	// hasn't ended.
+	legalMoves(p core.Position) []core.Move
+
+	// noMoves returns the outcome for the side to move if it has no legal
+	// moves.
+	noMoves(p core.Position) Outcome
+
+	// inCheck returns true if the side to move is in check.
+	inCheck(p core.Position) bool
+}
+
+var variants = [...]rules{
+	core.Standard:      standard{},
+	core.KingOfTheHill: kingOfTheHill{},
+	core.ThreeCheck:    threeCheck{},
+	core.Crazyhouse:    crazyhouse{},
+	core.Atomic:        atomic{},
+	core.Antichess:     antichess{},
+	core.Horde:         horde{},
+	core.RacingKings:   racingKings{},
+}
+
+// rulesOf returns the rules of a position's variant.
+func rulesOf(p core.Position) rules {
+	if !p.Variant.Valid() {
+		panic(fmt.Sprintf("movegen: invalid variant %s", p.Variant))
+	}
+	return variants[p.Variant]
+}
+
+// standard is standard chess, where the game ends by checkmate or stalemate.
+type standard struct{}
+
+func (standard) end(p core.Position) (Outcome, bool) {
+	return Draw, false
+}
+
+func (standard) legalMoves(p core.Position) []core.Move {
+	return reference.LegalMoves(p)
+}
+
+func (standard) noMoves(p core.Position) Outcome {
+	if reference.InCheck(p) {
+		return Loss
+	}
+	return Draw
+}
+
+func (standard) inCheck(p core.Position) bool {
+	return reference.InCheck(p)
+}
+
+// kingOfTheHill is King of the Hill, where a king reaching the center wins.
+type kingOfTheHill struct{ standard }
+
+func (kingOfTheHill) end(p core.Position) (Outcome, bool) {
+	switch {
+	case p.EnemyKing().Bitboard()&core.Center != 0:
+		return Loss, true
+	case p.FriendlyKing().Bitboard()&core.Center != 0:
+		return Win, true
+	default:
+		return Draw, false
+	}
+}
+
+// threeCheck is Three-check, where checking the opponent three times wins.
+type threeCheck struct{ standard }
+
+func (threeCheck) end(p core.Position) (Outcome, bool) {
+	friendly, enemy := p.WhiteChecks, p.BlackChecks
+	if p.SideToMove == core.Black {
+		friendly, enemy = enemy, friendly
+	}
+	switch {
+	case enemy >= 3:
+		return Loss, true
+	case friendly >= 3:
+		return Win, true
+	default:
+		return Draw, false
+	}
+}
+
+// crazyhouse is Crazyhouse, where captured pieces can be dropped back on the
+// board.
+type crazyhouse struct{ standard }
+
+func (crazyhouse) legalMoves(p core.Position) []core.Move {
+	return append(reference.LegalMoves(p), reference.LegalDrops(p)...)
+}
+
+// atomic is Atomic, where a capture explodes the capturing piece and every
+// piece other than a pawn next to it. Exploding the enemy king wins.
+type atomic struct{ standard }
+
+func (atomic) end(p core.Position) (Outcome, bool) {
+	if p.Board[core.NewPiece(p.SideToMove, core.King)] == 0 {
+		return Loss, true
+	}
+	return Draw, false
+}
+
+func (a atomic) legalMoves(p core.Position) []core.Move {
+	friendly := core.NewPiece(p.SideToMove, core.King)
+	enemy := core.NewPiece(p.SideToMove.Other(), core.King)
+
+	return slices.DeleteFunc(reference.PseudoLegalMoves(p), func(m core.Move) bool {
+		// Kings can't capture, since they would explode.
+		if piece, _ := p.Board.Get(m.From); piece == friendly {
+			if target, ok := p.Board.Get(m.To); ok && target.Color() != p.SideToMove {
+				return true
+			}
+		}
+
+		child := p
+		child.Make(m)
+		switch {
+		case child.Board[friendly] == 0:
+			return true
+		case child.Board[enemy] == 0:
+			return false
+		}
+		child.SideToMove = p.SideToMove
+		return a.inCheck(child)
+	})
+}
+
+func (a atomic) noMoves(p core.Position) Outcome {
+	if a.inCheck(p) {
+		return Loss
+	}
+	return Draw
+}
+
+func (atomic) inCheck(p core.Position) bool {
+	return !p.KingsTouch() && reference.InCheck(p)
+}
+
+// antichess is Antichess, where captures are compulsory, and the king is an
+// ordinary piece without check or castling. Losing every piece, or having no
+// legal moves, wins.
+type antichess struct{}
+
+func (antichess) end(p core.Position) (Outcome, bool) {
+	return Draw, false
+}
+
+func (antichess) legalMoves(p core.Position) []core.Move {
+	p.WhiteOO, p.WhiteOOO, p.BlackOO, p.BlackOOO = false, false, false, false
+
+	moves := reference.PseudoLegalMoves(p)
+	for _, m := range moves {
+		// Pawns can also promote to kings.
+		if m.Promotion == core.Queen {
+			m.Promotion = core.King
+			moves = append(moves, m)
+		}
+	}
+
+	captures := slices.DeleteFunc(slices.Clone(moves), func(m core.Move) bool {
+		if p.Board.IsOccupied(m.To) {
+			return false
+		}
+		piece, _ := p.Board.Get(m.From)
+		return piece.Type() != core.Pawn || p.EnPassant == 0 || m.To != p.EnPassant
+	})
+	if len(captures) > 0 {
+		return captures
+	}
+	return moves
+}
+
+func (antichess) noMoves(p core.Position) Outcome {
+	return Win
+}
+
+func (antichess) inCheck(p core.Position) bool {
+	return false
+}
+
+// horde is Horde, where white has many pawns and no king, and loses once it
+// has no pieces left. Black plays by the standard rules.
+type horde struct{ standard }
+
+func (horde) legalMoves(p core.Position) []core.Move {
+	if p.SideToMove == core.White {
+		return reference.PseudoLegalMoves(p)
+	}
+	return reference.LegalMoves(p)
+}
+
+func (horde) noMoves(p core.Position) Outcome {
+	if p.SideToMove == core.Black {
+		return standard{}.noMoves(p)
+	}
+	if p.Board.WhitePieces() == 0 {
+		return Loss
+	}
+	return Draw
+}
+
+func (horde) inCheck(p core.Position) bool {
+	return p.SideToMove == core.Black && reference.InCheck(p)
+}
+
+// racingKings is Racing Kings, where a king reaching the eighth rank wins, and
+// moves can't give check. If the white king gets there first, black has a
+// move to draw by getting there too.
+type racingKings struct{ standard }
+
+func (r racingKings) end(p core.Position) (Outcome, bool) {
+	white := p.Board.WhiteKing().Rank() == core.Rank8
+	black := p.Board.BlackKing().Rank() == core.Rank8
+
+	switch {
+	case white && black:
+		return Draw, true
+	case black && p.SideToMove == core.White:
+		return Loss, true
+	case black:
+		return Win, true
+	case white && p.SideToMove == core.White:
+		return Win, true
+	case white:
+		king := p.FriendlyKing()
+		for _, m := range r.legalMoves(p) {
+			if m.From == king && m.To.Rank() == core.Rank8 {
+				return Draw, false
+			}
+		}
+		return Loss, true
+	default:
+		return Draw, false
+	}
+}
+
+func (racingKings) legalMoves(p core.Position) []core.Move {
+	return slices.DeleteFunc(reference.LegalMoves(p), func(m core.Move) bool {
+		child := p
+		child.Make(m)
+		return reference.InCheck(child)
+	})
+}
@@ -0,0 +1,106 @@
+package movegen
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+)
+
+func TestPerft_Variants(t *testing.T) {
+	cases := []struct {
+		variant core.Variant
+		in      string
+		want    []int
+	}{
+		{core.KingOfTheHill, fen.Starting, []int{1, 20, 400, 8902, 197281}},
+		{core.KingOfTheHill, "8/8/8/8/8/4k3/8/4K3 b - - 0 1", []int{1, 5, 11, 70}},
+		{core.KingOfTheHill, "8/8/8/3k4/8/8/8/4K3 w - - 0 1", []int{1, 0, 0}},
+		{core.ThreeCheck, fen.Starting + " +0+0", []int{1, 20, 400, 8902, 197281}},
+		{
+			// Kiwipete, with each side a check away from winning.
+			core.ThreeCheck,
+			"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1 +2+2",
+			[]int{1, 48, 2039, 97848},
+		},
+		{core.ThreeCheck, "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2 +3+0", []int{1, 0, 0}},
+		{core.Crazyhouse, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1", []int{1, 20, 400, 8902, 197281}},
+		{core.Crazyhouse, "2k5/8/8/8/8/8/8/4K3[QRBNPqrbnp] w - - 0 1", []int{1, 301, 75353}},
+		{
+			core.Crazyhouse,
+			"r1bqk2r/pppp1ppp/2n1p3/4P3/1b1Pn3/2NB1N2/PPP2PPP/R1BQK2R[] b KQkq - 0 1",
+			[]int{1, 42, 1347, 58057},
+		},
+		// A promoted queen is captured as a pawn.
+		{core.Crazyhouse, "4k3/1Q~6/8/8/4b3/8/Kpp5/8[] b - - 0 1", []int{1, 20, 360, 5445, 132758}},
+		// Check can be blocked by a drop.
+		{core.Crazyhouse, "4r2k/8/8/8/8/8/8/4K3[N] w - - 0 1", []int{1, 10}},
+		{core.Atomic, fen.Starting, []int{1, 20, 400, 8902, 197326}},
+		{core.Atomic, "rn2kb1r/1pp1p2p/p2q1pp1/3P4/2P3b1/4PN2/PP3PPP/R2QKB1R b KQkq - 0 1", []int{1, 40, 1238, 45237}},
+		{core.Atomic, "rn1qkb1r/p5pp/2p5/3p4/N3P3/5P2/PPP4P/R1BQK3 w Qkq - 0 1", []int{1, 28, 833, 23353}},
+		{core.Antichess, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1", []int{1, 20, 400, 8067, 153299}},
+		{core.Antichess, "8/1p6/8/8/8/8/P7/8 w - - 0 1", []int{1, 2, 4, 4, 3, 1, 0}},
+		// Pawns promote to kings too.
+		{core.Antichess, "8/P7/8/8/8/8/8/7k w - - 0 1", []int{1, 5}},
+		{core.Horde, fen.HordeStarting, []int{1, 8, 128, 1274, 23310}},
+		{core.Horde, "4k3/pp4q1/3P2p1/8/P3PP2/PPP2r2/PPP5/PPPP4 b - - 0 1", []int{1, 30, 241, 6633}},
+		{core.Horde, "k7/5p2/4p2P/3p2P1/2p2P2/1p2P2P/p2P2P1/2P2P2 w - - 0 1", []int{1, 13, 172, 2205}},
+		{core.RacingKings, fen.RacingKingsStarting, []int{1, 21, 421, 11264}},
+		{core.RacingKings, "4brn1/2K2k2/8/8/8/8/8/8 w - - 0 1", []int{1, 6, 33, 178, 3151}},
+		// Black can still draw by reaching the eighth rank.
+		{core.RacingKings, "5K2/1k6/8/8/8/8/8/8 b - - 0 1", []int{1, 8, 0}},
+		{core.RacingKings, "5K2/8/8/1k6/8/8/8/8 b - - 0 1", []int{1, 0}},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			p := fen.MustDecode(tc.in)
+			p.Variant = tc.variant
+			for i, want := range tc.want {
+				if got := Perft(p, i); got != want {
+					t.Errorf("%s %q at depth %d: got %d, want %d", tc.variant, tc.in, i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGameOver(t *testing.T) {
+	cases := []struct {
+		variant core.Variant
+		in      string
+		want    Outcome
+		over    bool
+	}{
+		{core.Standard, fen.Starting, Draw, false},
+		{core.Standard, "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", Loss, true},
+		{core.Standard, "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", Draw, true},
+		{core.KingOfTheHill, "8/8/8/3k4/8/8/8/4K3 w - - 0 1", Loss, true},
+		{core.KingOfTheHill, "8/8/8/8/4K3/8/8/k7 w - - 0 1", Win, true},
+		{core.KingOfTheHill, "8/8/8/8/8/4k3/8/4K3 b - - 0 1", Draw, false},
+		{core.ThreeCheck, "4k3/8/8/8/8/8/8/4K3 b - - 0 1 +3+0", Loss, true},
+		{core.ThreeCheck, "4k3/8/8/8/8/8/8/4K3 b - - 0 1 +2+2", Draw, false},
+		{core.Atomic, "4k3/8/8/8/8/8/8/8 w - - 0 1", Loss, true},
+		{core.Atomic, "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", Loss, true},
+		// Touching kings aren't in check.
+		{core.Atomic, "8/8/8/8/8/8/1kq5/K7 w - - 0 1", Draw, false},
+		{core.Antichess, "8/8/8/8/8/8/8/k7 w - - 0 1", Win, true},
+		{core.Antichess, "8/8/8/8/8/p7/P7/k7 w - - 0 1", Win, true},
+		{core.Horde, "4k3/8/8/8/8/8/8/8 w - - 0 1", Loss, true},
+		{core.Horde, "4k3/8/8/8/8/p7/P7/8 w - - 0 1", Draw, true},
+		{core.RacingKings, "5K2/8/8/1k6/8/8/8/8 b - - 0 1", Loss, true},
+		{core.RacingKings, "1k3K2/8/8/8/8/8/8/8 w - - 0 1", Draw, true},
+		{core.RacingKings, "1k6/8/5K2/8/8/8/8/8 w - - 0 1", Loss, true},
+	}
+
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.in)
+		p.Variant = tc.variant
+		got, over := GameOver(p)
+		if got != tc.want || over != tc.over {
+			t.Errorf("%s %q: got %s, %t, want %s, %t", tc.variant, tc.in, got, over, tc.want, tc.over)
+		}
+	}
+}
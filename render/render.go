@@ -0,0 +1,201 @@
+// Package render draws chess positions as text diagrams, for terminals, logs
+// and test failure messages.
+//
+// Pieces are drawn as their letters in FEN, as Unicode chess glyphs, or as
+// glyphs on a colored board using ANSI escape codes. Squares can be
+// highlighted, like those of the last move or those attacked by a piece.
+package render
+
+import (
+	"strings"
+
+	"github.com/clfs/simple/core"
+)
+
+// A Style is a way of drawing a board.
+type Style int
+
+const (
+	// ASCII draws pieces as their letters in FEN, like "N" and "n", and
+	// empty squares as dots. Highlighted squares are followed by an asterisk.
+	ASCII Style = iota
+
+	// Unicode draws pieces as chess glyphs, like "♘" and "♞", and empty
+	// squares as middle dots. Highlighted squares are followed by an
+	// asterisk.
+	Unicode
+
+	// ANSI draws pieces as solid chess glyphs on a board colored with ANSI
+	// escape codes, for terminals that support 256 colors. Highlighted
+	// squares have a different background color.
+	ANSI
+)
+
+// Options configure a diagram. The zero value draws an ASCII board from
+// white's side, without coordinates or highlighted squares.
+type Options struct {
+	Style Style
+
+	// Coordinates labels the ranks on the left and the files below.
+	Coordinates bool
+
+	// Flip draws the board from black's side.
+	Flip bool
+
+	// Highlight holds the squares to highlight. See Move for the squares of
+	// a move.
+	Highlight core.Bitboard
+}
+
+// Move returns the squares a move is played from and to, for highlighting
+// it. Drops only have the square they're played to.
+func Move(m core.Move) core.Bitboard {
+	b := m.To.Bitboard()
+	if !m.Drop {
+		b.Set(m.From)
+	}
+	return b
+}
+
+// ANSI escape codes for colors in the 256-color palette.
+const (
+	ansiReset     = "\x1b[0m"
+	ansiLight     = "\x1b[48;5;223m"
+	ansiDark      = "\x1b[48;5;137m"
+	ansiLightHigh = "\x1b[48;5;186m"
+	ansiDarkHigh  = "\x1b[48;5;143m"
+	ansiWhite     = "\x1b[38;5;231m"
+	ansiBlack     = "\x1b[38;5;16m"
+)
+
+// glyphs holds the Unicode glyphs of the pieces.
+var glyphs = [...]string{
+	core.WhitePawn:   "♙",
+	core.WhiteKnight: "♘",
+	core.WhiteBishop: "♗",
+	core.WhiteRook:   "♖",
+	core.WhiteQueen:  "♕",
+	core.WhiteKing:   "♔",
+	core.BlackPawn:   "♟",
+	core.BlackKnight: "♞",
+	core.BlackBishop: "♝",
+	core.BlackRook:   "♜",
+	core.BlackQueen:  "♛",
+	core.BlackKing:   "♚",
+}
+
+// Render draws the board of a position, a rank per line, ending with a
+// newline.
+func Render(p core.Position, opts Options) string {
+	ranks, files := ranksAndFiles(opts.Flip)
+
+	var b strings.Builder
+	for _, r := range ranks {
+		if opts.Coordinates {
+			b.WriteString(rankLabel(r) + " ")
+		}
+		var row strings.Builder
+		for _, f := range files {
+			s := core.NewSquare(f, r)
+			piece, ok := p.Board.Get(s)
+			if opts.Style == ANSI {
+				row.WriteString(ansiSquare(s, piece, ok, opts.Highlight.Get(s)))
+				continue
+			}
+			row.WriteString(symbol(opts.Style, piece, ok))
+			if opts.Highlight.Get(s) {
+				row.WriteByte('*')
+			} else {
+				row.WriteByte(' ')
+			}
+		}
+		if opts.Style == ANSI {
+			row.WriteString(ansiReset)
+		}
+		b.WriteString(strings.TrimRight(row.String(), " "))
+		b.WriteByte('\n')
+	}
+
+	if opts.Coordinates {
+		// Files are labeled under the middle of their squares.
+		sep := " "
+		if opts.Style == ANSI {
+			sep = "  "
+		}
+		var labels []string
+		for _, f := range files {
+			labels = append(labels, fileLabel(f))
+		}
+		b.WriteString(" " + sep + strings.Join(labels, sep) + "\n")
+	}
+
+	return b.String()
+}
+
+// ranksAndFiles returns the ranks from top to bottom and the files from left
+// to right.
+func ranksAndFiles(flip bool) ([]core.Rank, []core.File) {
+	var (
+		ranks []core.Rank
+		files []core.File
+	)
+	for i := range 8 {
+		ranks = append(ranks, core.Rank8-core.Rank(i))
+		files = append(files, core.FileA+core.File(i))
+	}
+	if flip {
+		for i := range 8 {
+			ranks[i], files[i] = core.Rank1+core.Rank(i), core.FileH-core.File(i)
+		}
+	}
+	return ranks, files
+}
+
+// symbol returns the symbol of a piece, or of an empty square if ok is false.
+func symbol(style Style, piece core.Piece, ok bool) string {
+	switch {
+	case !ok && style == Unicode:
+		return "·"
+	case !ok:
+		return "."
+	case style == Unicode:
+		return glyphs[piece]
+	default:
+		text, _ := piece.MarshalText()
+		return string(text)
+	}
+}
+
+// ansiSquare returns a square colored with ANSI escape codes, three columns
+// wide.
+func ansiSquare(s core.Square, piece core.Piece, ok, highlight bool) string {
+	light := (int(s.File())+int(s.Rank()))%2 == 1
+	var bg string
+	switch {
+	case light && highlight:
+		bg = ansiLightHigh
+	case light:
+		bg = ansiLight
+	case highlight:
+		bg = ansiDarkHigh
+	default:
+		bg = ansiDark
+	}
+	if !ok {
+		return bg + "   "
+	}
+	fg := ansiWhite
+	if piece.Color() == core.Black {
+		fg = ansiBlack
+	}
+	// Solid glyphs read better than outlined ones on a colored board.
+	return bg + fg + " " + glyphs[core.NewPiece(core.Black, piece.Type())] + " "
+}
+
+func rankLabel(r core.Rank) string {
+	return string(rune('1' + r))
+}
+
+func fileLabel(f core.File) string {
+	return string(rune('a' + f))
+}
@@ -0,0 +1,127 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+)
+
+func ExampleRender() {
+	p := fen.MustDecode("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	opts := Options{
+		Coordinates: true,
+		Highlight:   Move(pcn.MustDecode("e2e4")),
+	}
+	fmt.Print(Render(p, opts))
+	// Output:
+	// 8 r n b q k b n r
+	// 7 p p p p p p p p
+	// 6 . . . . . . . .
+	// 5 . . . . . . . .
+	// 4 . . . . P*. . .
+	// 3 . . . . . . . .
+	// 2 P P P P .*P P P
+	// 1 R N B Q K B N R
+	//   a b c d e f g h
+}
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		fen  string
+		opts Options
+		want string
+	}{
+		{
+			"4k3/8/8/8/8/8/8/R3K3 w Q - 0 1",
+			Options{},
+			`. . . . k . . .
+. . . . . . . .
+. . . . . . . .
+. . . . . . . .
+. . . . . . . .
+. . . . . . . .
+. . . . . . . .
+R . . . K . . .
+`,
+		},
+		{
+			"4k3/8/8/8/8/8/8/R3K3 w Q - 0 1",
+			Options{Style: Unicode, Coordinates: true, Flip: true},
+			`1 · · · ♔ · · · ♖
+2 · · · · · · · ·
+3 · · · · · · · ·
+4 · · · · · · · ·
+5 · · · · · · · ·
+6 · · · · · · · ·
+7 · · · · · · · ·
+8 · · · ♚ · · · ·
+  h g f e d c b a
+`,
+		},
+		{
+			// Squares attacked by the rook.
+			"4k3/8/8/8/8/8/8/R3K3 w Q - 0 1",
+			Options{Highlight: 0x01010101010101fe &^ 0xe0},
+			`.*. . . k . . .
+.*. . . . . . .
+.*. . . . . . .
+.*. . . . . . .
+.*. . . . . . .
+.*. . . . . . .
+.*. . . . . . .
+R .*.*.*K*. . .
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		got := Render(fen.MustDecode(tc.fen), tc.opts)
+		if got != tc.want {
+			t.Errorf("%q %+v: got\n%s\nwant\n%s", tc.fen, tc.opts, got, tc.want)
+		}
+	}
+}
+
+func TestRender_ANSI(t *testing.T) {
+	p := fen.MustDecode("7k/8/8/8/8/8/8/K7 w - - 0 1")
+	got := Render(p, Options{Style: ANSI, Coordinates: true, Highlight: Move(core.Move{From: core.B1, To: core.A1})})
+	lines := strings.Split(got, "\n")
+
+	wantTop := "8 " +
+		ansiLight + "   " + ansiDark + "   " + ansiLight + "   " + ansiDark + "   " +
+		ansiLight + "   " + ansiDark + "   " + ansiLight + "   " + ansiDark + ansiBlack + " ♚ " + ansiReset
+	if lines[0] != wantTop {
+		t.Errorf("got top rank %q, want %q", lines[0], wantTop)
+	}
+
+	wantBottom := "1 " +
+		ansiDarkHigh + ansiWhite + " ♚ " + ansiLightHigh + "   " + ansiDark + "   " + ansiLight + "   " +
+		ansiDark + "   " + ansiLight + "   " + ansiDark + "   " + ansiLight + "   " + ansiReset
+	if lines[7] != wantBottom {
+		t.Errorf("got bottom rank %q, want %q", lines[7], wantBottom)
+	}
+
+	if want := "   a  b  c  d  e  f  g  h"; lines[8] != want {
+		t.Errorf("got files %q, want %q", lines[8], want)
+	}
+}
+
+func TestMove(t *testing.T) {
+	cases := []struct {
+		in   string
+		want core.Bitboard
+	}{
+		{"e2e4", 1<<core.E2 | 1<<core.E4},
+		{"a7a8q", 1<<core.A7 | 1<<core.A8},
+		{"N@f3", 1 << core.F3},
+	}
+	for _, tc := range cases {
+		if got := Move(pcn.MustDecode(tc.in)); got != tc.want {
+			t.Errorf("Move(%s) = %#x, want %#x", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Package svg draws chess positions as standalone SVG images, for reports and
+// puzzle sheets.
+//
+// Diagrams are drawn with embedded piece artwork, so they don't depend on
+// fonts, and can show coordinates, highlighted squares, arrows and a king in
+// check. They're 360 units wide and tall, with squares 45 units wide.
+package svg
+
+import (
+	"embed"
+	"fmt"
+	"html"
+	"math"
+	"strings"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/movegen"
+)
+
+// pieces holds the artwork of each piece type, as SVG fragments drawn in a
+// 45 by 45 square. Parts drawn in currentColor contrast with the piece.
+//
+//go:embed pieces/*.svg
+var pieces embed.FS
+
+// squareSize is the size of a square in SVG units.
+const squareSize = 45
+
+// Colors of the board.
+const (
+	lightColor     = "#f0d9b5"
+	darkColor      = "#b58863"
+	highlightColor = "#9bc700"
+	checkColor     = "#ff0000"
+
+	// DefaultArrowColor is the color of arrows that don't have one.
+	DefaultArrowColor = "#15781b"
+)
+
+// An Arrow points from one square to another.
+type Arrow struct {
+	From, To core.Square
+	Color    string // Any SVG color. The zero value means DefaultArrowColor.
+}
+
+// Options configure a diagram. The zero value draws a board from white's
+// side, without coordinates, highlighted squares or arrows.
+type Options struct {
+	// Size is the width and height of the image. Zero means 360.
+	Size int
+
+	// Coordinates labels the ranks and files on the edge squares.
+	Coordinates bool
+
+	// Flip draws the board from black's side.
+	Flip bool
+
+	// Highlight holds the squares to highlight. See render.Move for the
+	// squares of a move.
+	Highlight core.Bitboard
+
+	// Arrows are drawn over the pieces, in order.
+	Arrows []Arrow
+
+	// NoCheck turns off highlighting the king of the side to move if it's in
+	// check.
+	NoCheck bool
+}
+
+// Render draws a position as an SVG image.
+func Render(p core.Position, opts Options) string {
+	size := opts.Size
+	if size == 0 {
+		size = 8 * squareSize
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		size, size, 8*squareSize, 8*squareSize)
+
+	writeDefs(&b, p, opts.Arrows)
+
+	// Squares.
+	for s := core.A1; s <= core.H8; s++ {
+		x, y := position(s, opts.Flip)
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+			x, y, squareSize, squareSize, squareColor(s))
+		if opts.Highlight.Get(s) {
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" fill-opacity="0.4"/>`+"\n",
+				x, y, squareSize, squareSize, highlightColor)
+		}
+	}
+
+	if !opts.NoCheck && p.Board[core.NewPiece(p.SideToMove, core.King)].Count() == 1 && movegen.InCheck(p) {
+		x, y := position(p.FriendlyKing(), opts.Flip)
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="url(#check)"/>`+"\n",
+			x, y, squareSize, squareSize)
+	}
+
+	if opts.Coordinates {
+		writeCoordinates(&b, opts.Flip)
+	}
+
+	// Pieces.
+	for s := core.A1; s <= core.H8; s++ {
+		piece, ok := p.Board.Get(s)
+		if !ok {
+			continue
+		}
+		x, y := position(s, opts.Flip)
+		fmt.Fprintf(&b, `<use href="#%s" x="%d" y="%d"/>`+"\n", pieceID(piece), x, y)
+	}
+
+	for i, a := range opts.Arrows {
+		writeArrow(&b, i, a, opts.Flip)
+	}
+
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// writeDefs writes the artwork of the pieces on the board, the gradient of a
+// king in check, and the heads of the arrows.
+func writeDefs(b *strings.Builder, p core.Position, arrows []Arrow) {
+	b.WriteString("<defs>\n")
+	for piece := core.WhitePawn; piece <= core.BlackKing; piece++ {
+		if p.Board[piece] == 0 {
+			continue
+		}
+		fill, detail := "#fff", "#000"
+		if piece.Color() == core.Black {
+			fill, detail = "#000", "#fff"
+		}
+		fmt.Fprintf(b, `<g id="%s" fill="%s" stroke="#000" stroke-width="1.5" stroke-linejoin="round" color="%s">`+"\n",
+			pieceID(piece), fill, detail)
+		b.Write(artwork(piece.Type()))
+		b.WriteString("</g>\n")
+	}
+	fmt.Fprintf(b, `<radialGradient id="check"><stop offset="0%%" stop-color="%s"/><stop offset="100%%" stop-color="%s" stop-opacity="0"/></radialGradient>`+"\n",
+		checkColor, checkColor)
+	for i, a := range arrows {
+		fmt.Fprintf(b, `<marker id="arrow%d" markerWidth="4" markerHeight="4" refX="2" refY="2" orient="auto"><path d="M0 0L4 2 0 4z" fill="%s"/></marker>`+"\n",
+			i, arrowColor(a))
+	}
+	b.WriteString("</defs>\n")
+}
+
+// writeCoordinates labels the ranks in the top left corner of the squares on
+// the left edge, and the files in the bottom right corner of the squares on
+// the bottom edge, in the color of the other squares.
+func writeCoordinates(b *strings.Builder, flip bool) {
+	for i := range 8 {
+		r := core.Rank(i)
+		s := core.NewSquare(core.FileA, r)
+		if flip {
+			s = core.NewSquare(core.FileH, r)
+		}
+		x, y := position(s, flip)
+		fmt.Fprintf(b, `<text x="%d" y="%d" font-family="sans-serif" font-size="10" fill="%s">%c</text>`+"\n",
+			x+2, y+10, otherColor(s), '1'+rune(r))
+
+		f := core.File(i)
+		s = core.NewSquare(f, core.Rank1)
+		if flip {
+			s = core.NewSquare(f, core.Rank8)
+		}
+		x, y = position(s, flip)
+		fmt.Fprintf(b, `<text x="%d" y="%d" font-family="sans-serif" font-size="10" fill="%s" text-anchor="end">%c</text>`+"\n",
+			x+squareSize-2, y+squareSize-3, otherColor(s), 'a'+rune(f))
+	}
+}
+
+// writeArrow writes the ith arrow. It ends short of the center of its last
+// square, so its head points at the center.
+func writeArrow(b *strings.Builder, i int, a Arrow, flip bool) {
+	const (
+		width = squareSize / 5
+		head  = 2 * width // The length of the head beyond the line.
+	)
+	x1, y1 := center(a.From, flip)
+	x2, y2 := center(a.To, flip)
+	if d := math.Hypot(x2-x1, y2-y1); d > head {
+		x2 -= (x2 - x1) * head / d
+		y2 -= (y2 - y1) * head / d
+	}
+	fmt.Fprintf(b, `<line x1="%g" y1="%g" x2="%g" y2="%g" stroke="%s" stroke-width="%d" stroke-linecap="round" stroke-opacity="0.8" marker-end="url(#arrow%d)"/>`+"\n",
+		round(x1), round(y1), round(x2), round(y2), arrowColor(a), width, i)
+}
+
+// artwork returns the artwork of a piece type.
+func artwork(pt core.PieceType) []byte {
+	name, _ := pt.MarshalText()
+	b, err := pieces.ReadFile("pieces/" + string(name) + ".svg")
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// pieceID returns the ID of a piece's artwork, like "white-knight".
+func pieceID(piece core.Piece) string {
+	c, _ := piece.Color().MarshalText()
+	pt, _ := piece.Type().MarshalText()
+	return string(c) + "-" + string(pt)
+}
+
+// position returns the top left corner of a square.
+func position(s core.Square, flip bool) (x, y int) {
+	f, r := int(s.File()), 7-int(s.Rank())
+	if flip {
+		f, r = 7-f, 7-r
+	}
+	return f * squareSize, r * squareSize
+}
+
+// center returns the center of a square.
+func center(s core.Square, flip bool) (x, y float64) {
+	px, py := position(s, flip)
+	return float64(px) + squareSize/2.0, float64(py) + squareSize/2.0
+}
+
+func squareColor(s core.Square) string {
+	if (int(s.File())+int(s.Rank()))%2 == 1 {
+		return lightColor
+	}
+	return darkColor
+}
+
+// otherColor returns the color of the squares that s isn't.
+func otherColor(s core.Square) string {
+	if squareColor(s) == lightColor {
+		return darkColor
+	}
+	return lightColor
+}
+
+func arrowColor(a Arrow) string {
+	if a.Color == "" {
+		return DefaultArrowColor
+	}
+	return html.EscapeString(a.Color)
+}
+
+// round rounds to two decimal places, to keep diagrams short.
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,91 @@
+package svg
+
+import (
+	"encoding/xml"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/render"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		name string
+		fen  string
+		opts Options
+	}{
+		{"starting", fen.Starting, Options{}},
+		{
+			"coordinates",
+			"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+			Options{
+				Coordinates: true,
+				Highlight:   render.Move(pcn.MustDecode("b8c6")),
+				Arrows: []Arrow{
+					{From: core.F1, To: core.B5},
+					{From: core.D2, To: core.D4, Color: "#882020"},
+				},
+			},
+		},
+		{
+			"flipped",
+			"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+			Options{Size: 200, Coordinates: true, Flip: true, Arrows: []Arrow{{From: core.G8, To: core.F6}}},
+		},
+		{"check", "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", Options{}},
+		{"nocheck", "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", Options{NoCheck: true}},
+	}
+
+	for _, tc := range cases {
+		got := Render(fen.MustDecode(tc.fen), tc.opts)
+
+		golden := filepath.Join("testdata", tc.name+".svg")
+		if *update {
+			if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(want) {
+			t.Errorf("%s: output doesn't match %s", tc.name, golden)
+		}
+
+		// The output must be well-formed XML.
+		d := xml.NewDecoder(strings.NewReader(got))
+		for {
+			_, err := d.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("%s: invalid XML: %v", tc.name, err)
+				break
+			}
+		}
+	}
+}
+
+func TestRender_Artwork(t *testing.T) {
+	// Only pieces on the board have their artwork included.
+	got := Render(fen.MustDecode("4k3/8/8/8/8/8/8/4K2N w - - 0 1"), Options{})
+	for _, id := range []string{"white-king", "black-king", "white-knight"} {
+		if !strings.Contains(got, `<g id="`+id+`"`) {
+			t.Errorf("missing artwork for %s", id)
+		}
+	}
+	if strings.Contains(got, `<g id="black-knight"`) {
+		t.Error("unexpected artwork for black-knight")
+	}
+}
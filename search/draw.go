@@ -0,0 +1,103 @@
+package search
+
+import (
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/movegen"
+)
+
+// fiftyMovePlies is the number of plies without captures or pawn moves after
+// which the game is drawn by the fifty-move rule.
+const fiftyMovePlies = 100
+
+// darkSquares is the set of dark squares.
+const darkSquares core.Bitboard = 0xaa55aa55aa55aa55
+
+// isDraw returns true if a position in the search tree is drawn, either by
+// repetition, the fifty-move rule, or insufficient material in standard chess.
+// The position's key must not be pushed yet.
+func (s *searcher) isDraw(p core.Position, key uint64, inCheck bool) bool {
+	// Checkmate takes precedence over the fifty-move rule.
+	if p.HalfMoveClock >= fiftyMovePlies && (!inCheck || len(movegen.LegalMoves(p)) > 0) {
+		return true
+	}
+	return (p.Variant == core.Standard && insufficientMaterial(p)) || s.repeated(key, p.HalfMoveClock)
+}
+
+// repeated returns true if a position is drawn by repetition. A position that
+// repeats one in the search tree is treated as a draw, since the side that can
+// claim the draw could repeat it again. A position that only repeats the game
+// history must have occurred twice already.
+func (s *searcher) repeated(key uint64, halfMoveClock int) bool {
+	var count int
+
+	// Only positions with the same side to move and no captures or pawn moves
+	// since can be repetitions.
+	for i := len(s.keys) - 2; i >= max(0, len(s.keys)-halfMoveClock); i -= 2 {
+		if s.keys[i] != key {
+			continue
+		}
+		if i >= s.root {
+			return true
+		}
+		if count++; count == 2 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// outcomeScore returns the score of a finished game at some ply.
+func (s *searcher) outcomeScore(o movegen.Outcome, ply int) int {
+	switch o {
+	case movegen.Loss:
+		return -Mate + ply
+	case movegen.Win:
+		return Mate - ply
+	default:
+		return s.drawScore(ply)
+	}
+}
+
+// drawScore returns the score of a draw at some ply. Contempt makes draws
+// worse for the side to move at the root.
+func (s *searcher) drawScore(ply int) int {
+	if ply%2 == 0 {
+		return -s.contempt
+	}
+	return s.contempt
+}
+
+// insufficientMaterial returns true if neither side has enough material to
+// checkmate.
+func insufficientMaterial(p core.Position) bool {
+	b := p.Board
+
+	if b[core.WhitePawn]|b[core.BlackPawn]|
+		b[core.WhiteRook]|b[core.BlackRook]|
+		b[core.WhiteQueen]|b[core.BlackQueen] != 0 {
+		return false
+	}
+
+	var (
+		knights = b[core.WhiteKnight] | b[core.BlackKnight]
+		bishops = b[core.WhiteBishop] | b[core.BlackBishop]
+		minors  = knights | bishops
+	)
+
+	if minors.Count() <= 1 {
+		return true
+	}
+
+	// Bishops that are all on squares of the same color can't checkmate.
+	return knights == 0 && (bishops&darkSquares == 0 || bishops&^darkSquares == 0)
+}
+
+// hashes returns the hashes of positions.
+func hashes(positions []core.Position) []uint64 {
+	keys := make([]uint64, len(positions))
+	for i, p := range positions {
+		keys[i] = hash(p)
+	}
+	return keys
+}
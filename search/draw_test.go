@@ -0,0 +1,164 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/movegen"
+)
+
+// play plays moves from p. It returns the positions before each move, and the
+// final position.
+func play(t *testing.T, p core.Position, moves ...string) ([]core.Position, core.Position) {
+	t.Helper()
+
+	var history []core.Position
+	for _, s := range moves {
+		history = append(history, p)
+		p.Make(pcn.MustDecode(s))
+	}
+	return history, p
+}
+
+// lineScore returns the score of the line starting with a move.
+func lineScore(t *testing.T, p core.Position, opts Options, move string) int {
+	t.Helper()
+
+	opts.MultiPV = len(movegen.LegalMoves(p))
+	for _, i := range runLines(t, p, opts) {
+		if pcn.Encode(i.Move) == move {
+			return i.Score
+		}
+	}
+	t.Fatalf("no line starting with %s", move)
+	return 0
+}
+
+func TestInsufficientMaterial(t *testing.T) {
+	cases := []struct {
+		in   string
+		want bool
+	}{
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/8/4KN2 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/8/4KB2 w - - 0 1", true},
+		{"4kb2/8/8/8/8/8/8/2B1K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1", false},
+		{"4kb2/8/8/8/8/8/8/3BK3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/4KNN1 w - - 0 1", false},
+		{"4kn2/8/8/8/8/8/8/4KB2 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", false},
+		{fen.Starting, false},
+	}
+
+	for _, tc := range cases {
+		if got := insufficientMaterial(fen.MustDecode(tc.in)); got != tc.want {
+			t.Errorf("%q: got %t, want %t", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestSearcher_Repeated(t *testing.T) {
+	cases := []struct {
+		name          string
+		keys          []uint64
+		root          int
+		key           uint64
+		halfMoveClock int
+		want          bool
+	}{
+		{"no repetition", []uint64{1, 2, 3, 4}, 0, 5, 10, false},
+		{"twofold in tree", []uint64{1, 2, 3, 4}, 0, 3, 10, true},
+		{"twofold with root", []uint64{1, 2, 3, 4}, 2, 3, 10, true},
+		{"twofold in history", []uint64{1, 2, 3, 4}, 3, 3, 10, false},
+		{"threefold in history", []uint64{3, 2, 3, 4, 5, 6}, 4, 3, 10, true},
+		{"wrong side to move", []uint64{1, 2, 3, 4}, 0, 4, 10, false},
+		{"before irreversible move", []uint64{1, 2, 3, 4}, 0, 3, 1, false},
+	}
+
+	for _, tc := range cases {
+		s := &searcher{keys: tc.keys, root: tc.root}
+		if got := s.repeated(tc.key, tc.halfMoveClock); got != tc.want {
+			t.Errorf("%s: got %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRun_Repetition(t *testing.T) {
+	// White is a rook up, but playing Ra2 again repeats a position for the
+	// third time.
+	history, p := play(t, fen.MustDecode("4k3/8/8/8/8/8/8/R3K3 w - - 0 1"),
+		"a1a2", "e8d8", "a2a1", "d8e8",
+		"a1a2", "e8d8", "a2a1", "d8e8",
+	)
+
+	cases := []struct {
+		opts Options
+		want int
+	}{
+		{Options{History: history}, 0},
+		{Options{History: history, Contempt: 50}, -50},
+		{Options{History: history, Contempt: -50}, 50},
+	}
+
+	for _, tc := range cases {
+		tc.opts.Depth = 2
+		if got := lineScore(t, p, tc.opts, "a1a2"); got != tc.want {
+			t.Errorf("contempt %d: got score %d, want %d", tc.opts.Contempt, got, tc.want)
+		}
+	}
+
+	// Without history, there's no repetition.
+	if got := lineScore(t, p, Options{Depth: 2}, "a1a2"); got <= 0 {
+		t.Errorf("without history: got score %d, want a winning score", got)
+	}
+
+	// The best move avoids the draw.
+	if got := runDepth(t, p, Options{Depth: 2, History: history}); pcn.Encode(got.Move) == "a1a2" {
+		t.Errorf("got move a1a2, want a move that avoids repetition")
+	}
+}
+
+func TestRun_FiftyMoveRule(t *testing.T) {
+	cases := []struct {
+		in   string
+		move string
+		want int
+	}{
+		// A reversible move reaches the hundredth ply.
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 99 80", "a1a2", 0},
+		// A pawn move resets the count.
+		{"4k3/8/8/8/8/8/P7/R3K3 w - - 99 80", "a2a3", 600},
+		// Checkmate takes precedence.
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 99 80", "a1a8", Mate - 1},
+	}
+
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.in)
+		if got := lineScore(t, p, Options{Depth: 1}, tc.move); got != tc.want {
+			t.Errorf("%q: %s got score %d, want %d", tc.in, tc.move, got, tc.want)
+		}
+	}
+}
+
+func TestRun_InsufficientMaterial(t *testing.T) {
+	cases := []struct {
+		in       string
+		contempt int
+		want     int
+	}{
+		{"4k3/8/8/8/8/8/8/4KN2 w - - 0 1", 0, 0},
+		{"4k3/8/8/8/8/8/8/4KN2 w - - 0 1", 25, -25},
+	}
+
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.in)
+		got := runDepth(t, p, Options{Depth: 4, Contempt: tc.contempt})
+		if got.Score != tc.want {
+			t.Errorf("%q with contempt %d: got score %d, want %d", tc.in, tc.contempt, got.Score, tc.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package search
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/movegen"
+)
+
+// Mate is the score for delivering checkmate immediately. Checkmate in n plies
+// scores Mate-n, and being checkmated in n plies scores -(Mate-n).
+const Mate = 1_000_000
+
+// maxMatePly is the longest checkmate distance that's representable.
+const maxMatePly = 1000
+
+// mateThreshold is the smallest absolute score that indicates checkmate.
+const mateThreshold = Mate - maxMatePly
+
+// MateIn converts a score to the number of moves until checkmate. The result
+// is positive if the side to move delivers checkmate, and negative if it's
+// checkmated. It returns false if the score isn't a checkmate score.
+func MateIn(score int) (int, bool) {
+	switch {
+	case score >= mateThreshold:
+		return (Mate - score + 1) / 2, true
+	case score <= -mateThreshold:
+		return -(Mate + score) / 2, true
+	default:
+		return 0, false
+	}
+}
+
+// scoreToTT converts a score relative to the root into a score relative to the
+// node at ply, for storage in the transposition table. Checkmate and tablebase
+// scores depend on the distance from the root.
+func scoreToTT(score, ply int) int {
+	switch {
+	case score >= tbThreshold:
+		return score + ply
+	case score <= -tbThreshold:
+		return score - ply
+	default:
+		return score
+	}
+}
+
+// scoreFromTT is the inverse of scoreToTT.
+func scoreFromTT(score, ply int) int {
+	switch {
+	case score >= tbThreshold:
+		return score - ply
+	case score <= -tbThreshold:
+		return score + ply
+	default:
+		return score
+	}
+}
+
+// ErrNoMate is returned by Run when a mate search doesn't find checkmate.
+var ErrNoMate = errors.New("no mate found")
+
+// A mateKey identifies a mate search result.
+type mateKey struct {
+	hash  uint64
+	moves int
+}
+
+// solveMate returns true if the side to move can force checkmate within the
+// given number of moves. If so, it leaves the mating line in the principal
+// variation at ply.
+//
+// Only checking moves can deliver checkmate, so the last move of the attacker
+// is restricted to checks, and to moves that win by a variant rule.
+func (s *searcher) solveMate(p core.Position, moves, ply int) bool {
+	if s.visit() {
+		return false
+	}
+
+	s.clearPV(ply)
+
+	// Failed proofs are cached. Successful ones aren't, so that the principal
+	// variation can be recovered.
+	key := mateKey{hash(p), moves}
+	if s.disproved[key] {
+		return false
+	}
+
+	legal := movegen.LegalMoves(p)
+
+	// Try checks first, since they're the most forcing.
+	type candidate struct {
+		child core.Position
+		move  core.Move
+	}
+	var checks, quiets []candidate
+	for _, m := range legal {
+		child := p
+		child.Make(m)
+		if _, ok := movegen.VariantEnd(child); ok || movegen.InCheck(child) {
+			checks = append(checks, candidate{child, m})
+		} else if moves > 1 {
+			quiets = append(quiets, candidate{child, m})
+		}
+	}
+
+	for _, c := range append(checks, quiets...) {
+		if s.cannotEscape(c.child, moves, ply+1) {
+			s.updatePV(ply, c.move)
+			return true
+		}
+		if s.stopped {
+			return false
+		}
+	}
+
+	s.disproved[key] = true
+	return false
+}
+
+// cannotEscape returns true if every reply by the side to move allows the
+// opponent to force checkmate within the given number of moves, counting the
+// move just played. If so, it leaves the most stubborn defense found in the
+// principal variation at ply.
+func (s *searcher) cannotEscape(p core.Position, moves, ply int) bool {
+	s.clearPV(ply)
+
+	legal := movegen.LegalMoves(p)
+	if len(legal) == 0 {
+		o, _ := movegen.GameOver(p)
+		return o == movegen.Loss
+	}
+
+	if moves == 1 {
+		return false
+	}
+
+	var (
+		best core.Move
+		line []core.Move
+	)
+	for _, m := range legal {
+		child := p
+		child.Make(m)
+		if !s.solveMate(child, moves-1, ply+1) {
+			return false
+		}
+		if line == nil || len(s.pv[ply+1]) > len(line) {
+			best, line = m, slices.Clone(s.pv[ply+1])
+		}
+	}
+
+	s.pv[ply+1] = append(s.pv[ply+1][:0], line...)
+	s.updatePV(ply, best)
+	return true
+}
+
+// runMate searches for a checkmate within n moves, reporting the shortest one
+// found.
+func runMate(s *searcher, p core.Position, n int, report func(Info) error) error {
+	for moves := 1; moves <= n; moves++ {
+		found := s.solveMate(p, moves, 0)
+		if s.stopped {
+			return s.ctx.Err()
+		}
+		if found {
+			plies := 2*moves - 1
+			pv := slices.Clone(s.pv[0])
+			return report(Info{
+				Depth: plies,
+				Score: Mate - plies,
+				Move:  pv[0],
+				PV:    pv,
+				Rank:  1,
+			})
+		}
+	}
+	return ErrNoMate
+}
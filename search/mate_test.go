@@ -0,0 +1,172 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/san"
+	"github.com/clfs/simple/movegen"
+)
+
+// A puzzle is a position with a forced checkmate.
+type puzzle struct {
+	fen   string
+	moves int
+}
+
+// readPuzzles reads EPD records with a "dm" (direct mate) operation.
+func readPuzzles(t *testing.T, name string) []puzzle {
+	t.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var puzzles []puzzle
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(strings.TrimSuffix(s.Text(), ";"))
+		if len(fields) != 6 || fields[4] != "dm" {
+			t.Fatalf("invalid record: %q", s.Text())
+		}
+		n, err := strconv.Atoi(fields[5])
+		if err != nil {
+			t.Fatalf("invalid record: %q", s.Text())
+		}
+		puzzles = append(puzzles, puzzle{
+			fen:   strings.Join(fields[:4], " ") + " 0 1",
+			moves: n,
+		})
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return puzzles
+}
+
+// runMateSearch runs a mate search and returns the result.
+func runMateSearch(p core.Position, n int) (Info, error) {
+	var (
+		info = make(chan Info, 1)
+		errc = make(chan error, 1)
+	)
+
+	go func() {
+		errc <- Run(context.Background(), p, Options{Mate: n}, info)
+	}()
+
+	err := <-errc
+	select {
+	case i := <-info:
+		return i, err
+	default:
+		return Info{}, err
+	}
+}
+
+func TestMateIn(t *testing.T) {
+	cases := []struct {
+		in     int
+		want   int
+		wantOK bool
+	}{
+		{0, 0, false},
+		{350, 0, false},
+		{-350, 0, false},
+		{Mate - 1, 1, true},
+		{Mate - 3, 2, true},
+		{Mate - 5, 3, true},
+		{-Mate + 2, -1, true},
+		{-Mate + 4, -2, true},
+		{mateThreshold, (maxMatePly + 1) / 2, true},
+		{mateThreshold - 1, 0, false},
+	}
+
+	for _, tc := range cases {
+		got, ok := MateIn(tc.in)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("MateIn(%d) = %d, %t, want %d, %t", tc.in, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestScoreTT(t *testing.T) {
+	for _, score := range []int{0, 120, -120, Mate - 3, -Mate + 4, tbWin - 5, -tbWin + 2} {
+		for _, ply := range []int{0, 1, 7} {
+			if got := scoreFromTT(scoreToTT(score, ply), ply); got != score {
+				t.Errorf("scoreFromTT(scoreToTT(%d, %d), %d) = %d", score, ply, ply, got)
+			}
+		}
+	}
+}
+
+func TestRun_Mate(t *testing.T) {
+	for _, pz := range readPuzzles(t, "testdata/mate.epd") {
+		p := fen.MustDecode(pz.fen)
+
+		got, err := runMateSearch(p, pz.moves)
+		if err != nil {
+			t.Errorf("%q: error: %v", pz.fen, err)
+			continue
+		}
+		if n, ok := MateIn(got.Score); !ok || n != pz.moves {
+			t.Errorf("%q: got score %d, want mate in %d", pz.fen, got.Score, pz.moves)
+		}
+		if len(got.PV) != 2*pz.moves-1 {
+			t.Errorf("%q: got PV %v, want %d plies", pz.fen, san.EncodeMoves(p, got.PV), 2*pz.moves-1)
+		}
+
+		end := p
+		for _, m := range got.PV {
+			end.Make(m)
+		}
+		if len(movegen.LegalMoves(end)) != 0 || !movegen.InCheck(end) {
+			t.Errorf("%q: PV %v doesn't end in checkmate", pz.fen, san.EncodeMoves(p, got.PV))
+		}
+
+		// The mate is the shortest possible.
+		if pz.moves > 1 {
+			if _, err := runMateSearch(p, pz.moves-1); !errors.Is(err, ErrNoMate) {
+				t.Errorf("%q: mate in %d: got error %v, want %v", pz.fen, pz.moves-1, err, ErrNoMate)
+			}
+		}
+	}
+}
+
+func TestRun_MateNotFound(t *testing.T) {
+	_, err := runMateSearch(core.NewPosition(), 2)
+	if !errors.Is(err, ErrNoMate) {
+		t.Errorf("got error %v, want %v", err, ErrNoMate)
+	}
+}
+
+func TestRun_MateScore(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int
+	}{
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 1},
+		{"6k1/8/8/8/8/8/R7/1R4K1 w - - 0 1", 2},
+		{"1r4k1/r7/8/8/8/8/8/6K1 b - - 0 1", 2},
+		{"6k1/1R6/R7/8/8/8/8/6K1 b - - 0 1", -1},
+	}
+
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.in)
+		got := runDepth(t, p, Options{Threads: 1, Depth: 4})
+		if n, ok := MateIn(got.Score); !ok || n != tc.want {
+			t.Errorf("%q: got score %d, want mate in %d", tc.in, got.Score, tc.want)
+		}
+	}
+}
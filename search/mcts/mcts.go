@@ -0,0 +1,363 @@
+// Package mcts implements Monte Carlo tree search with PUCT selection, as an
+// alternative to the alpha-beta search in package search.
+//
+// Each simulation walks down the tree from the root, picking the child with
+// the highest PUCT score, until it reaches a leaf. The leaf is evaluated by a
+// Provider, expanded, and its value is backed up to the root.
+package mcts
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/movegen"
+	"github.com/clfs/simple/search"
+)
+
+// DefaultCPUCT is the default exploration constant.
+const DefaultCPUCT = 1.5
+
+// depthSimulations is the number of simulations a search to depth 1 runs. It
+// doubles for each additional ply.
+const depthSimulations = 64
+
+// Reporting intervals, in simulations. Reports are more frequent early on.
+const (
+	minReportInterval = 16
+	maxReportInterval = 4096
+)
+
+// fiftyMovePlies is the number of plies without captures or pawn moves after
+// which the game is drawn by the fifty-move rule.
+const fiftyMovePlies = 100
+
+// A Searcher searches positions with Monte Carlo tree search. It keeps its
+// tree between searches, so that searching a position that follows the last
+// one searched, such as the position after the opponent's reply, reuses the
+// work already done.
+//
+// A Searcher must not run more than one search at a time. The zero value is
+// ready to use.
+type Searcher struct {
+	// Provider evaluates positions. If nil, EvalProvider{} is used.
+	Provider Provider
+
+	// CPUCT controls how much the search explores moves with few visits
+	// rather than exploiting moves with high values. If zero, DefaultCPUCT
+	// is used.
+	CPUCT float64
+
+	root *node
+	pos  core.Position // Position at the root.
+}
+
+// Run searches for the best move in a position. It follows the same contract
+// as search.Run, sending information about the search to info at intervals.
+//
+// Since the search has no notion of depth, opts.Depth is converted into a
+// number of simulations, which are limited by opts.Nodes. Without a depth,
+// node or time limit, the search runs until ctx is done. Mate searches are
+// delegated to search.Run. A search restricted by opts.SearchMoves starts
+// from a new tree, which isn't kept. The other options that only apply to
+// alpha-beta search are ignored.
+func (s *Searcher) Run(ctx context.Context, p core.Position, opts search.Options, info chan<- search.Info) error {
+	if opts.Mate > 0 {
+		return search.Run(ctx, p, opts, info)
+	}
+
+	moves := rootMoves(p, opts.SearchMoves)
+	if len(moves) == 0 {
+		return search.ErrNoLegalMoves
+	}
+
+	t := &tree{
+		root:     s.reuse(p),
+		pos:      p,
+		provider: s.provider(),
+		cpuct:    s.cpuct(),
+		limit:    simulations(opts),
+	}
+	if len(opts.SearchMoves) > 0 {
+		// A root with only some of its moves can't be reused.
+		t.root, t.moves = new(node), moves
+		s.Reset()
+	} else {
+		s.root, s.pos = t.root, p
+	}
+
+	var (
+		searchCtx context.Context
+		cancel    context.CancelFunc
+	)
+	if opts.MoveTime > 0 {
+		searchCtx, cancel = context.WithTimeout(ctx, opts.MoveTime)
+	} else {
+		searchCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	return t.run(searchCtx, ctx, opts, info)
+}
+
+// Run searches for the best move in a position with a new Searcher.
+func Run(ctx context.Context, p core.Position, opts search.Options, info chan<- search.Info) error {
+	return new(Searcher).Run(ctx, p, opts, info)
+}
+
+// Reset discards the search tree.
+func (s *Searcher) Reset() {
+	s.root, s.pos = nil, core.Position{}
+}
+
+func (s *Searcher) provider() Provider {
+	if s.Provider == nil {
+		return EvalProvider{}
+	}
+	return s.Provider
+}
+
+func (s *Searcher) cpuct() float64 {
+	if s.CPUCT == 0 {
+		return DefaultCPUCT
+	}
+	return s.CPUCT
+}
+
+// reuse returns the node for a position in the tree from the last search, if
+// it's the root or within two plies of it, or a new node otherwise.
+func (s *Searcher) reuse(p core.Position) *node {
+	if n := s.find(p); n != nil && !n.terminal {
+		return n
+	}
+	return new(node)
+}
+
+// find returns the node for a position within two plies of the root, if any.
+func (s *Searcher) find(p core.Position) *node {
+	if s.root == nil {
+		return nil
+	}
+	if s.pos == p {
+		return s.root
+	}
+
+	for _, c := range s.root.children {
+		child := s.pos
+		child.Make(c.move)
+		if child == p {
+			return c
+		}
+		for _, gc := range c.children {
+			grandchild := child
+			grandchild.Make(gc.move)
+			if grandchild == p {
+				return gc
+			}
+		}
+	}
+
+	return nil
+}
+
+// rootMoves returns the legal moves in p that are in searchMoves, or all of
+// them if searchMoves is empty.
+func rootMoves(p core.Position, searchMoves []core.Move) []core.Move {
+	moves := movegen.LegalMoves(p)
+	if len(searchMoves) > 0 {
+		moves = slices.DeleteFunc(moves, func(m core.Move) bool {
+			return !slices.Contains(searchMoves, m)
+		})
+	}
+	return moves
+}
+
+// simulations returns the maximum number of simulations for a search, or zero
+// for no limit.
+func simulations(opts search.Options) int {
+	var limit int
+	if opts.Depth > 0 {
+		limit = depthSimulations << min(opts.Depth-1, 30)
+	}
+	if opts.Nodes > 0 && (limit == 0 || opts.Nodes < limit) {
+		limit = opts.Nodes
+	}
+	return limit
+}
+
+// A tree is the state of a single search.
+type tree struct {
+	mu   sync.Mutex // Guards root and its descendants.
+	root *node
+	pos  core.Position
+
+	provider Provider
+	cpuct    float64
+	limit    int         // Maximum number of simulations, or zero.
+	moves    []core.Move // Moves searched at the root, or nil for all.
+
+	sims atomic.Int64 // Completed simulations.
+}
+
+// run runs simulations until searchCtx is done or the simulation limit is
+// reached. It returns ctx.Err() if ctx is done, and nil otherwise.
+func (t *tree) run(searchCtx, ctx context.Context, opts search.Options, info chan<- search.Info) error {
+	var (
+		start = time.Now()
+		wg    sync.WaitGroup
+	)
+
+	helperCtx, cancel := context.WithCancel(searchCtx)
+
+	// Start the helpers, and stop them when the main goroutine returns.
+	for range max(opts.Threads, 1) - 1 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for helperCtx.Err() == nil && t.simulate() {
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	report := func(ctx context.Context) error {
+		for _, i := range t.lines(max(opts.MultiPV, 1)) {
+			i.Nodes = int(t.sims.Load())
+			i.Time = time.Since(start)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case info <- i:
+			}
+		}
+		return nil
+	}
+
+	for next := minReportInterval; searchCtx.Err() == nil && t.simulate(); {
+		if n := int(t.sims.Load()); n >= next {
+			if report(searchCtx) != nil {
+				break
+			}
+			next = n + min(max(n, minReportInterval), maxReportInterval)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// The search was stopped by its time or simulation limit, so report the
+	// final result.
+	cancel()
+	wg.Wait()
+	return report(ctx)
+}
+
+// simulate runs a single simulation. It returns false if the simulation
+// limit has been reached.
+func (t *tree) simulate() bool {
+	if t.limit > 0 && t.sims.Load() >= int64(t.limit) {
+		return false
+	}
+
+	// Walk down the tree, marking simulations in progress.
+	t.mu.Lock()
+	var (
+		n    = t.root
+		p    = t.pos
+		path = []*node{n}
+	)
+	n.virtual++
+	for n.expanded && !n.terminal {
+		n = n.selectChild(t.cpuct)
+		p.Make(n.move)
+		n.virtual++
+		path = append(path, n)
+	}
+	terminal, value := n.terminal, n.value
+	t.mu.Unlock()
+
+	// Evaluate the leaf without holding the lock.
+	var (
+		moves  []core.Move
+		priors []float64
+	)
+	if !terminal {
+		moves, priors, value = t.evaluate(p, n == t.root)
+	}
+
+	// Expand the leaf and back up its value.
+	t.mu.Lock()
+	if len(moves) > 0 {
+		n.expand(moves, priors)
+	} else if !n.expanded {
+		n.terminal, n.value, n.expanded = true, value, true
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		value = -value
+		path[i].visits++
+		path[i].virtual--
+		path[i].valueSum += value
+	}
+	t.mu.Unlock()
+
+	t.sims.Add(1)
+	return true
+}
+
+// evaluate evaluates a position. It returns no moves if the game is over.
+// The game can't be over at the root, which has legal moves.
+func (t *tree) evaluate(p core.Position, root bool) ([]core.Move, []float64, float64) {
+	moves := movegen.LegalMoves(p)
+	if root && t.moves != nil {
+		moves = t.moves
+	}
+	if len(moves) == 0 {
+		o, _ := movegen.GameOver(p) // checkmate, stalemate, or a variant rule
+		return nil, nil, float64(o)
+	}
+	if p.HalfMoveClock >= fiftyMovePlies && !root {
+		return nil, nil, 0
+	}
+
+	priors, value := t.provider.Evaluate(p, moves)
+	return moves, priors, value
+}
+
+// lines returns information about the best n moves at the root.
+func (t *tree) lines(n int) []search.Info {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var (
+		lines   []search.Info
+		scale   float64 = DefaultEvalScale
+		visited         = t.root.ranked()
+	)
+	if e, ok := t.provider.(EvalProvider); ok {
+		scale = e.scale()
+	}
+
+	for rank, c := range visited[:min(n, len(visited))] {
+		pv := c.pv()
+
+		score := toScore(c.q(), scale)
+		if c.terminal && c.value < 0 {
+			score = search.Mate - 1
+		}
+
+		lines = append(lines, search.Info{
+			Depth: len(pv),
+			Score: score,
+			Move:  c.move,
+			PV:    pv,
+			Rank:  rank + 1,
+		})
+	}
+
+	return lines
+}
@@ -0,0 +1,270 @@
+package mcts
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/movegen"
+	"github.com/clfs/simple/search"
+	"github.com/google/go-cmp/cmp"
+)
+
+// run runs a search and returns the last line for each rank.
+func run(t *testing.T, s *Searcher, p core.Position, opts search.Options) []search.Info {
+	t.Helper()
+
+	var (
+		info  = make(chan search.Info)
+		errc  = make(chan error, 1)
+		lines []search.Info
+	)
+
+	go func() {
+		errc <- s.Run(context.Background(), p, opts, info)
+	}()
+
+	for {
+		select {
+		case err := <-errc:
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			return lines
+		case i := <-info:
+			if i.Rank == 1 {
+				lines = nil
+			}
+			lines = append(lines, i)
+		}
+	}
+}
+
+// walk calls f for every node in the tree.
+func walk(n *node, f func(*node)) {
+	f(n)
+	for _, c := range n.children {
+		walk(c, f)
+	}
+}
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1", "d2d5"},
+		{"4k3/8/8/8/8/1r6/8/1R2K3 w - - 0 1", "b1b3"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8"},
+	}
+
+	for _, tc := range cases {
+		for _, threads := range []int{1, 4} {
+			p := fen.MustDecode(tc.in)
+			lines := run(t, new(Searcher), p, search.Options{Threads: threads, Nodes: 2000})
+			if got := pcn.Encode(lines[0].Move); got != tc.want {
+				t.Errorf("%q with %d threads: got %s, want %s", tc.in, threads, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestRun_MateScore(t *testing.T) {
+	p := fen.MustDecode("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1")
+	lines := run(t, new(Searcher), p, search.Options{Nodes: 500})
+	if n, ok := search.MateIn(lines[0].Score); !ok || n != 1 {
+		t.Errorf("got score %d, want mate in 1", lines[0].Score)
+	}
+}
+
+func TestRun_Deterministic(t *testing.T) {
+	p := core.NewPosition()
+	opts := search.Options{Threads: 1, Nodes: 1000, MultiPV: 3}
+
+	want := run(t, new(Searcher), p, opts)
+	for range 3 {
+		got := run(t, new(Searcher), p, opts)
+		ignore := func(i search.Info) search.Info {
+			i.Time = 0
+			return i
+		}
+		if diff := cmp.Diff(want, got, cmp.Transformer("ignoreTime", ignore)); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestRun_MultiPV(t *testing.T) {
+	p := core.NewPosition()
+	lines := run(t, new(Searcher), p, search.Options{Nodes: 1000, MultiPV: 4})
+
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4", len(lines))
+	}
+
+	var moves []core.Move
+	for rank, i := range lines {
+		if i.Rank != rank+1 {
+			t.Errorf("line %d: got rank %d", rank, i.Rank)
+		}
+		if slices.Contains(moves, i.Move) {
+			t.Errorf("line %d: got repeated move %s", rank, pcn.Encode(i.Move))
+		}
+		moves = append(moves, i.Move)
+	}
+}
+
+func TestRun_Depth(t *testing.T) {
+	lines := run(t, new(Searcher), core.NewPosition(), search.Options{Depth: 3})
+	if got, want := lines[0].Nodes, depthSimulations<<2; got != want {
+		t.Errorf("got %d simulations, want %d", got, want)
+	}
+}
+
+func TestRun_MoveTime(t *testing.T) {
+	start := time.Now()
+	lines := run(t, new(Searcher), core.NewPosition(), search.Options{MoveTime: 100 * time.Millisecond})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %v, want about 100ms", elapsed)
+	}
+	if len(lines) == 0 {
+		t.Error("got no lines")
+	}
+}
+
+func TestRun_Cancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	info := make(chan search.Info)
+	go func() {
+		for range info {
+		}
+	}()
+	defer close(info)
+
+	err := Run(ctx, core.NewPosition(), search.Options{Threads: 2}, info)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRun_NoLegalMoves(t *testing.T) {
+	p := fen.MustDecode("7k/5Q2/6K1/8/8/8/8/8 b - - 0 1")
+	err := Run(context.Background(), p, search.Options{Nodes: 100}, nil)
+	if !errors.Is(err, search.ErrNoLegalMoves) {
+		t.Errorf("got error %v, want %v", err, search.ErrNoLegalMoves)
+	}
+}
+
+func TestRun_SearchMoves(t *testing.T) {
+	// d2d5 wins the queen, but it isn't one of the moves searched.
+	var (
+		s           = new(Searcher)
+		p           = fen.MustDecode("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+		searchMoves = []core.Move{pcn.MustDecode("e1f1"), pcn.MustDecode("e1f2")}
+	)
+
+	lines := run(t, s, p, search.Options{Nodes: 500, SearchMoves: searchMoves})
+	if !slices.Contains(searchMoves, lines[0].Move) {
+		t.Errorf("got %s, want e1f1 or e1f2", pcn.Encode(lines[0].Move))
+	}
+	if s.root != nil {
+		t.Error("kept a restricted tree")
+	}
+
+	err := Run(context.Background(), p, search.Options{Nodes: 100, SearchMoves: []core.Move{pcn.MustDecode("a1a2")}}, nil)
+	if !errors.Is(err, search.ErrNoLegalMoves) {
+		t.Errorf("got error %v, want %v", err, search.ErrNoLegalMoves)
+	}
+}
+
+func TestRun_VirtualLoss(t *testing.T) {
+	s := new(Searcher)
+	run(t, s, core.NewPosition(), search.Options{Threads: 8, Nodes: 2000})
+
+	walk(s.root, func(n *node) {
+		if n.virtual != 0 {
+			t.Fatalf("node for %s has virtual loss %d after search", pcn.Encode(n.move), n.virtual)
+		}
+	})
+	if s.root.visits < 2000 {
+		t.Errorf("got %d root visits, want at least 2000", s.root.visits)
+	}
+}
+
+func TestSearcher_Reuse(t *testing.T) {
+	s := new(Searcher)
+	p := core.NewPosition()
+	run(t, s, p, search.Options{Nodes: 1000})
+
+	for _, moves := range [][]string{{}, {"e2e4"}, {"e2e4", "e7e5"}} {
+		q := p
+		for _, m := range moves {
+			q.Make(pcn.MustDecode(m))
+		}
+		// Positions within two plies of the root are usually in the tree.
+		if n := s.find(q); n == nil && len(moves) < 2 {
+			t.Errorf("%v: not found in tree", moves)
+		}
+	}
+
+	// Reusing a subtree keeps its visits.
+	best := s.root.ranked()[0]
+	q := p
+	q.Make(best.move)
+	visits := best.visits
+
+	lines := run(t, s, q, search.Options{Nodes: 100})
+	if s.root != best {
+		t.Fatal("didn't reuse the subtree")
+	}
+	if s.root.visits < visits+100 {
+		t.Errorf("got %d visits, want at least %d", s.root.visits, visits+100)
+	}
+	if !slices.Contains(movegen.LegalMoves(q), lines[0].Move) {
+		t.Errorf("got illegal move %s", pcn.Encode(lines[0].Move))
+	}
+
+	// Unrelated positions start a new tree.
+	run(t, s, fen.MustDecode("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1"), search.Options{Nodes: 10})
+	if s.root.visits > 10 {
+		t.Errorf("got %d visits, want a new tree", s.root.visits)
+	}
+
+	s.Reset()
+	if s.root != nil {
+		t.Error("Reset didn't discard the tree")
+	}
+}
+
+// A biasedProvider has a policy that strongly prefers one move.
+type biasedProvider struct {
+	move core.Move
+}
+
+func (b biasedProvider) Evaluate(p core.Position, moves []core.Move) ([]float64, float64) {
+	priors := make([]float64, len(moves))
+	for i, m := range moves {
+		if m == b.move {
+			priors[i] = 0.99
+		} else {
+			priors[i] = 0.01 / float64(len(moves)-1)
+		}
+	}
+	return priors, 0
+}
+
+func TestSearcher_Provider(t *testing.T) {
+	want := pcn.MustDecode("a2a3")
+	s := &Searcher{Provider: biasedProvider{want}}
+	lines := run(t, s, core.NewPosition(), search.Options{Nodes: 200})
+	if got := lines[0].Move; got != want {
+		t.Errorf("got %s, want %s", pcn.Encode(got), pcn.Encode(want))
+	}
+}
@@ -0,0 +1,86 @@
+package mcts
+
+import (
+	"math"
+	"slices"
+
+	"github.com/clfs/simple/core"
+)
+
+// A node is a node in the search tree. Nodes are guarded by their tree's
+// mutex.
+type node struct {
+	move     core.Move // Move that leads to the node.
+	prior    float64   // Prior probability of move.
+	visits   int       // Completed simulations through the node.
+	virtual  int       // Simulations in progress through the node.
+	valueSum float64   // Sum of values for the side that played move.
+
+	expanded bool    // Whether children holds every legal move.
+	terminal bool    // Whether the game is over at the node.
+	value    float64 // Value for the side to move, if terminal.
+	children []*node
+}
+
+// q returns the mean value of a node for the side that played its move.
+// Simulations in progress count as losses, which steers other goroutines
+// toward different nodes.
+func (n *node) q() float64 {
+	visits := n.visits + n.virtual
+	if visits == 0 {
+		return 0
+	}
+	return (n.valueSum - float64(n.virtual)) / float64(visits)
+}
+
+// selectChild returns the child with the highest PUCT score.
+func (n *node) selectChild(cpuct float64) *node {
+	var (
+		sqrtVisits = math.Sqrt(float64(max(n.visits+n.virtual, 1)))
+		best       *node
+		bestScore  = math.Inf(-1)
+	)
+
+	for _, c := range n.children {
+		score := c.q() + cpuct*c.prior*sqrtVisits/float64(1+c.visits+c.virtual)
+		if score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+
+	return best
+}
+
+// expand adds children for moves, unless another goroutine already has.
+func (n *node) expand(moves []core.Move, priors []float64) {
+	if n.expanded {
+		return
+	}
+	n.children = make([]*node, len(moves))
+	for i, m := range moves {
+		n.children[i] = &node{move: m, prior: priors[i]}
+	}
+	n.expanded = true
+}
+
+// ranked returns the children with the most visits first.
+func (n *node) ranked() []*node {
+	children := slices.Clone(n.children)
+	slices.SortStableFunc(children, func(a, b *node) int {
+		return b.visits - a.visits
+	})
+	return children
+}
+
+// pv returns the principal variation starting with the move to a node.
+func (n *node) pv() []core.Move {
+	pv := []core.Move{n.move}
+	for n.visits > 0 && len(n.children) > 0 {
+		n = n.ranked()[0]
+		if n.visits == 0 {
+			break
+		}
+		pv = append(pv, n.move)
+	}
+	return pv
+}
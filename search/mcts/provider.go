@@ -0,0 +1,64 @@
+package mcts
+
+import (
+	"math"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/eval"
+)
+
+// A Provider evaluates positions for a search.
+type Provider interface {
+	// Evaluate returns the prior probability of each legal move in a
+	// position, and the value of the position for the side to move, from -1
+	// (lost) to 1 (won). The priors should sum to 1.
+	Evaluate(p core.Position, moves []core.Move) (priors []float64, value float64)
+}
+
+// DefaultEvalScale is the default scale of an EvalProvider.
+const DefaultEvalScale = 400
+
+// An EvalProvider is a Provider with a uniform policy, which values positions
+// by passing eval.Eval through a sigmoid.
+type EvalProvider struct {
+	// Scale is the evaluation, in centipawns, at which the value reaches
+	// about 0.46. If zero, DefaultEvalScale is used.
+	Scale float64
+}
+
+// Evaluate implements Provider.
+func (e EvalProvider) Evaluate(p core.Position, moves []core.Move) ([]float64, float64) {
+	return uniform(moves), toValue(float64(eval.Eval(p)), e.scale())
+}
+
+func (e EvalProvider) scale() float64 {
+	if e.Scale == 0 {
+		return DefaultEvalScale
+	}
+	return e.Scale
+}
+
+// uniform returns equal priors for moves.
+func uniform(moves []core.Move) []float64 {
+	priors := make([]float64, len(moves))
+	for i := range priors {
+		priors[i] = 1 / float64(len(moves))
+	}
+	return priors
+}
+
+// toValue converts a score in centipawns to a value from -1 to 1, using a
+// sigmoid stretched to -1 to 1.
+func toValue(score, scale float64) float64 {
+	return 2/(1+math.Exp(-score/scale)) - 1
+}
+
+// maxValue bounds values when converting them to scores, which would
+// otherwise be infinite at -1 and 1.
+const maxValue = 0.999
+
+// toScore is the inverse of toValue.
+func toScore(value, scale float64) int {
+	value = min(max(value, -maxValue), maxValue)
+	return int(math.Round(scale * math.Log((1+value)/(1-value))))
+}
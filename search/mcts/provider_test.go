@@ -0,0 +1,42 @@
+package mcts
+
+import (
+	"math"
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/movegen"
+)
+
+func TestToScore(t *testing.T) {
+	for _, score := range []int{-1000, -400, -35, 0, 1, 35, 400, 1000} {
+		v := toValue(float64(score), DefaultEvalScale)
+		if v <= -1 || v >= 1 {
+			t.Errorf("toValue(%d) = %v, want a value in (-1, 1)", score, v)
+		}
+		if got := toScore(v, DefaultEvalScale); got != score {
+			t.Errorf("toScore(toValue(%d)) = %d", score, got)
+		}
+	}
+}
+
+func TestEvalProvider(t *testing.T) {
+	p := core.NewPosition()
+	moves := movegen.LegalMoves(p)
+
+	priors, value := EvalProvider{}.Evaluate(p, moves)
+	if len(priors) != len(moves) {
+		t.Fatalf("got %d priors, want %d", len(priors), len(moves))
+	}
+
+	var sum float64
+	for _, prior := range priors {
+		sum += prior
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("priors sum to %v, want 1", sum)
+	}
+	if math.Abs(value) > 0.1 {
+		t.Errorf("got value %v for the starting position, want about 0", value)
+	}
+}
@@ -0,0 +1,60 @@
+package search
+
+import (
+	"slices"
+
+	"github.com/clfs/simple/core"
+)
+
+// Move ordering scores.
+const (
+	hashMoveScore  = 1 << 20
+	captureScore   = 1 << 10
+	promotionScore = 1 << 9
+)
+
+// isCapture returns true if a move captures a piece.
+func isCapture(p core.Position, m core.Move) bool {
+	if p.Board.IsOccupied(m.To) {
+		return true
+	}
+	piece, _ := p.Board.Get(m.From)
+	return piece.Type() == core.Pawn && p.EnPassant != 0 && m.To == p.EnPassant
+}
+
+// isQuiet returns true if a move is neither a capture nor a promotion.
+func isQuiet(p core.Position, m core.Move) bool {
+	return m.Promotion == 0 && !isCapture(p, m)
+}
+
+// moveScore scores a move for move ordering. Captures are ordered by most
+// valuable victim, then least valuable attacker.
+func moveScore(p core.Position, m, hashMove core.Move) int {
+	if m == hashMove {
+		return hashMoveScore
+	}
+
+	var score int
+
+	if isCapture(p, m) {
+		victim, ok := p.Board.Get(m.To)
+		if !ok {
+			victim = core.NewPiece(p.SideToMove.Other(), core.Pawn) // en passant
+		}
+		attacker, _ := p.Board.Get(m.From)
+		score += captureScore + 8*int(victim.Type()) - int(attacker.Type())
+	}
+
+	if m.Promotion != 0 {
+		score += promotionScore + int(m.Promotion)
+	}
+
+	return score
+}
+
+// orderMoves sorts moves so that the most promising are searched first.
+func orderMoves(p core.Position, moves []core.Move, hashMove core.Move) {
+	slices.SortStableFunc(moves, func(a, b core.Move) int {
+		return moveScore(p, b, hashMove) - moveScore(p, a, hashMove)
+	})
+}
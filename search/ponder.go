@@ -0,0 +1,89 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/movegen"
+)
+
+// ErrIllegalMove is returned by Ponder when the ponder move is illegal.
+var ErrIllegalMove = errors.New("illegal move")
+
+// PonderMove returns the expected reply to the best move, if the principal
+// variation has one.
+func (i Info) PonderMove() (core.Move, bool) {
+	if len(i.PV) < 2 {
+		return core.Move{}, false
+	}
+	return i.PV[1], true
+}
+
+// Ponder searches the position after move, which is usually the opponent's
+// expected reply from a previous search (see Info.PonderMove), while waiting
+// for the opponent to play.
+//
+// Until hit is ready, the search ignores opts.MoveTime and doesn't return,
+// even if the maximum depth in opts has been searched. If the opponent plays
+// the expected move, the caller should close hit or send a value on it, after
+// which Ponder behaves like Run, with the time limit starting then. If the
+// opponent plays a different move, the caller should cancel ctx.
+//
+// opts.History holds the positions of the game before p. Ponder adds p to it.
+//
+// A nil hit is never ready, so the search runs until ctx is done.
+func Ponder(ctx context.Context, p core.Position, move core.Move, opts Options, hit <-chan struct{}, info chan<- Info) error {
+	if !slices.Contains(movegen.LegalMoves(p), move) {
+		return ErrIllegalMove
+	}
+	opts.History = append(slices.Clip(opts.History), p)
+	p.Make(move)
+
+	if hit == nil {
+		hit = make(chan struct{})
+	}
+
+	return runTimed(ctx, p, opts, hit, info)
+}
+
+// limit cancels a search when its time limit d has elapsed. If hit is
+// non-nil, the time limit starts when hit is ready. Zero means no limit.
+func limit(ctx context.Context, cancel context.CancelFunc, d time.Duration, hit <-chan struct{}) {
+	if d <= 0 {
+		return
+	}
+
+	if hit != nil {
+		select {
+		case <-hit:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		cancel()
+	case <-ctx.Done():
+	}
+}
+
+// latch returns a channel that's closed when c is ready, or never if ctx is
+// done first.
+func latch(ctx context.Context, c <-chan struct{}) <-chan struct{} {
+	l := make(chan struct{})
+	go func() {
+		select {
+		case <-c:
+			close(l)
+		case <-ctx.Done():
+		}
+	}()
+	return l
+}
@@ -0,0 +1,161 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/movegen"
+)
+
+// A ponderer runs Ponder in the background.
+type ponderer struct {
+	hit    chan struct{}
+	cancel context.CancelFunc
+	errc   chan error
+	last   Info // Last Info sent, valid after Ponder returns.
+}
+
+func startPonder(t *testing.T, p core.Position, move core.Move, opts Options) *ponderer {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	pd := &ponderer{
+		hit:    make(chan struct{}),
+		cancel: cancel,
+		errc:   make(chan error, 1),
+	}
+
+	var (
+		info    = make(chan Info)
+		drained = make(chan struct{})
+	)
+
+	go func() {
+		defer close(drained)
+		for i := range info {
+			pd.last = i
+		}
+	}()
+
+	go func() {
+		err := Ponder(ctx, p, move, opts, pd.hit, info)
+		close(info)
+		<-drained
+		pd.errc <- err
+	}()
+
+	return pd
+}
+
+// running returns true if Ponder hasn't returned after a short wait.
+func (pd *ponderer) running() bool {
+	select {
+	case err := <-pd.errc:
+		pd.errc <- err
+		return false
+	case <-time.After(100 * time.Millisecond):
+		return true
+	}
+}
+
+// wait waits for Ponder to return.
+func (pd *ponderer) wait(t *testing.T) error {
+	t.Helper()
+	select {
+	case err := <-pd.errc:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatal("Ponder didn't return")
+		return nil
+	}
+}
+
+func TestPonder_Hit(t *testing.T) {
+	p := core.NewPosition()
+	move := pcn.MustDecode("e2e4")
+	pd := startPonder(t, p, move, Options{Threads: 1, Depth: 2})
+
+	if !pd.running() {
+		t.Fatal("Ponder returned before ponderhit")
+	}
+
+	close(pd.hit)
+	if err := pd.wait(t); err != nil {
+		t.Fatalf("Ponder() error: %v", err)
+	}
+
+	p.Make(move)
+	if got := pd.last; got.Depth != 2 || !slices.Contains(movegen.LegalMoves(p), got.Move) {
+		t.Errorf("got depth %d, move %s, want depth 2 and a legal reply", got.Depth, pcn.Encode(got.Move))
+	}
+}
+
+func TestPonder_HitMoveTime(t *testing.T) {
+	pd := startPonder(t, core.NewPosition(), pcn.MustDecode("e2e4"), Options{MoveTime: 50 * time.Millisecond})
+
+	// The time limit doesn't apply while pondering.
+	if !pd.running() {
+		t.Fatal("Ponder returned before ponderhit")
+	}
+
+	pd.hit <- struct{}{}
+	if err := pd.wait(t); err != nil {
+		t.Errorf("Ponder() error: %v", err)
+	}
+}
+
+func TestPonder_Miss(t *testing.T) {
+	pd := startPonder(t, core.NewPosition(), pcn.MustDecode("e2e4"), Options{})
+
+	if !pd.running() {
+		t.Fatal("Ponder returned before ponder miss")
+	}
+
+	pd.cancel()
+	if err := pd.wait(t); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestPonder_IllegalMove(t *testing.T) {
+	pd := startPonder(t, core.NewPosition(), pcn.MustDecode("e2e5"), Options{})
+	if err := pd.wait(t); !errors.Is(err, ErrIllegalMove) {
+		t.Errorf("got error %v, want %v", err, ErrIllegalMove)
+	}
+}
+
+func TestInfo_PonderMove(t *testing.T) {
+	cases := []struct {
+		pv     []string
+		want   string
+		wantOK bool
+	}{
+		{nil, "", false},
+		{[]string{"e2e4"}, "", false},
+		{[]string{"e2e4", "e7e5"}, "e7e5", true},
+		{[]string{"e2e4", "e7e5", "g1f3"}, "e7e5", true},
+	}
+
+	for _, tc := range cases {
+		var i Info
+		for _, s := range tc.pv {
+			i.PV = append(i.PV, pcn.MustDecode(s))
+		}
+
+		got, ok := i.PonderMove()
+		var want core.Move
+		if tc.wantOK {
+			want = pcn.MustDecode(tc.want)
+		}
+		if got != want || ok != tc.wantOK {
+			t.Errorf("%v: got %s, %t, want %s, %t", tc.pv, pcn.Encode(got), ok, tc.want, tc.wantOK)
+		}
+	}
+}
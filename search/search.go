@@ -5,57 +5,722 @@ import (
 	"context"
 	"errors"
 	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/clfs/simple/core"
 	"github.com/clfs/simple/eval"
 	"github.com/clfs/simple/movegen"
 )
 
-func negamax(p core.Position, depth int) int {
+// Options configures a search.
+type Options struct {
+	// Threads is the number of goroutines searching in parallel. Values less
+	// than 1 are treated as 1. A single-threaded search is deterministic.
+	Threads int
+
+	// Depth is the maximum search depth in plies. Zero means no limit.
+	Depth int
+
+	// Disable turns off selective search techniques, which are all enabled
+	// by default.
+	Disable Technique
+
+	// MultiPV is the number of principal variations to search for. Values
+	// less than 1 are treated as 1.
+	MultiPV int
+
+	// Mate, if positive, replaces the normal search with a search for
+	// checkmate in at most this many moves.
+	Mate int
+
+	// MoveTime is the maximum time to search for. Zero means no limit.
+	MoveTime time.Duration
+
+	// Nodes is the maximum number of nodes searched by all threads
+	// together, except that the first iteration always completes. Zero means
+	// no limit. It doesn't apply to mate searches.
+	Nodes int
+
+	// SearchMoves, if not empty, restricts the search to these moves at the
+	// root. Moves that aren't legal are ignored. It doesn't apply to mate
+	// searches.
+	SearchMoves []core.Move
+
+	// Skill is the playing strength, from 1 to MaxSkill. Zero means
+	// MaxSkill, which is full strength. Below that, the search is limited
+	// and the best move is picked at random among several candidates. See
+	// MaxSkill.
+	Skill int
+
+	// Seed seeds the random choices of a search with limited skill. Equal
+	// seeds and options give equal results.
+	Seed uint64
+
+	// History holds the positions of the game before the one searched,
+	// oldest first. It's used to detect draws by repetition.
+	History []core.Position
+
+	// Contempt is how much worse a draw is than an even position for the
+	// side to move in the position searched, in centipawns. Negative values
+	// make draws better.
+	Contempt int
+
+	// Tablebase, if not nil, is probed for positions with few enough
+	// pieces. At the root, only moves that preserve the tablebase result
+	// are searched, and the tablebase isn't probed further.
+	Tablebase Tablebase
+
+	// ProbeDepth is the minimum remaining depth at which the tablebase is
+	// probed during search. Values less than 1 are treated as 1.
+	ProbeDepth int
+}
+
+// Info describes a completed search iteration.
+type Info struct {
+	Depth  int           // Search depth in plies.
+	Score  int           // Score relative to the side to move. See MateIn.
+	Move   core.Move     // Best move.
+	PV     []core.Move   // Principal variation, starting with the best move.
+	Rank   int           // Rank of the principal variation, starting at 1.
+	Nodes  int           // Nodes searched by all threads.
+	Time   time.Duration // Time elapsed since the search started.
+	TBHits int           // Tablebase probes by all threads.
+}
+
+// NPS returns the number of nodes searched per second.
+func (i Info) NPS() int {
+	if i.Time <= 0 {
+		return 0
+	}
+	return int(float64(i.Nodes) / i.Time.Seconds())
+}
+
+// infinity is greater than any score.
+const infinity = math.MaxInt32
+
+// A searcher is a single search thread.
+type searcher struct {
+	ctx      context.Context
+	tt       *table
+	disable  Technique
+	nodes    atomic.Int64
+	total    *atomic.Int64 // Nodes searched by all threads, if there's a node limit.
+	maxNodes int64         // Node limit, or zero for no limit.
+	contempt int           // Score of a draw for the opponent of the side to move at the root.
+	stopped  bool
+	depth    int           // Depth of the current iteration.
+	partial  bool          // Whether some root moves are excluded.
+	pv       [][]core.Move // Triangular principal variation table, by ply.
+
+	keys []uint64 // Hashes of the game history and the current search path.
+	root int      // Index of the root position in keys.
+
+	disproved map[mateKey]bool // Failed mate search proofs.
+
+	tb       Tablebase // Tablebase to probe, or nil.
+	tbPieces int       // Largest number of pieces to probe.
+	tbDepth  int       // Minimum depth to probe.
+	tbHits   atomic.Int64
+}
+
+// checkInterval is how many nodes a searcher visits between checks for
+// cancellation.
+const checkInterval = 1024
+
+// visit counts a node, and returns true if the search should stop.
+func (s *searcher) visit() bool {
+	n := s.nodes.Add(1)
+	if n%checkInterval == 0 && s.ctx.Err() != nil {
+		s.stopped = true
+	}
+	// The first iteration always completes, so that there's a move to play.
+	if s.maxNodes > 0 && s.total.Add(1) >= s.maxNodes && s.depth > 1 {
+		s.stopped = true
+	}
+	return s.stopped
+}
+
+// enabled returns true if a selective search technique is enabled.
+func (s *searcher) enabled(t Technique) bool {
+	return s.disable&t == 0
+}
+
+// clearPV empties the principal variation at a ply.
+func (s *searcher) clearPV(ply int) {
+	for len(s.pv) <= ply+1 {
+		s.pv = append(s.pv, nil)
+	}
+	s.pv[ply] = s.pv[ply][:0]
+}
+
+// updatePV sets the principal variation at a ply to a move followed by the
+// principal variation at the next ply.
+func (s *searcher) updatePV(ply int, m core.Move) {
+	s.pv[ply] = append(append(s.pv[ply][:0], m), s.pv[ply+1]...)
+}
+
+// negamax searches a position with alpha-beta pruning and returns its score.
+//
+// The score is fail-soft: it may lie outside the window when the search fails
+// high or low.
+func (s *searcher) negamax(p core.Position, depth, ply, alpha, beta int, inCheck, allowNull bool) int {
+	if s.visit() {
+		return 0
+	}
+
+	s.clearPV(ply)
+
+	// Variant rules can end the game before checkmate.
+	if o, ok := movegen.VariantEnd(p); ok {
+		return s.outcomeScore(o, ply)
+	}
+
+	key := hash(p)
+	if s.isDraw(p, key, inCheck) {
+		return s.drawScore(ply)
+	}
+
+	s.keys = append(s.keys, key)
+	defer func() { s.keys = s.keys[:len(s.keys)-1] }()
+
 	if depth <= 0 {
 		return eval.Eval(p)
 	}
 
-	score := math.MinInt
-	for _, m := range movegen.LegalMoves(p) {
+	// Mate distance pruning.
+	alpha = max(alpha, -Mate+ply)
+	beta = min(beta, Mate-ply-1)
+	if alpha >= beta {
+		return alpha
+	}
+
+	pvNode := beta-alpha > 1
+
+	// Transposition table cutoffs are skipped in PV nodes, since they would
+	// truncate the principal variation.
+	e, ok := s.tt.load(key)
+	if !ok {
+		e = entry{}
+	}
+	e.score = scoreFromTT(e.score, ply)
+	if ok && !pvNode && e.cutoff(depth, alpha, beta) {
+		return e.score
+	}
+
+	// Tablebase probes are stored in the transposition table, and end the
+	// search if they're exact or cause a cutoff. Otherwise, PV nodes are
+	// searched within the bound, so that the principal variation is known.
+	var (
+		tbFloor = -infinity
+		tbCeil  = infinity
+	)
+	if depth >= s.tbDepth {
+		if v, b, ok := s.probe(p, ply); ok {
+			if b == exact || (b == lower && v >= beta) || (b == upper && v <= alpha) {
+				s.tt.store(entry{key: key, depth: depth + tbDepthBonus, score: scoreToTT(v, ply), bound: b})
+				return v
+			}
+			if pvNode && b == lower {
+				tbFloor = v
+			}
+			if pvNode && b == upper {
+				tbCeil = v
+			}
+		}
+	}
+
+	staticEval := eval.Eval(p)
+
+	// Reverse futility pruning.
+	if s.enabled(ReverseFutility) && !pvNode && !inCheck &&
+		depth <= reverseFutilityMaxDepth &&
+		staticEval-reverseFutilityMargin*depth >= beta {
+		return staticEval
+	}
+
+	// Null move pruning.
+	if s.enabled(NullMove) && allowNull && !pvNode && !inCheck &&
+		depth >= nullMoveMinDepth && staticEval >= beta && beta < mateThreshold &&
+		hasNonPawnMaterial(p) {
+		child := p
+		makeNull(&child)
+		v := -s.negamax(child, depth-1-nullMoveReduction(depth), ply+1, -beta, -beta+1, false, false)
+		if s.stopped {
+			return 0
+		}
+		if v >= beta {
+			return beta
+		}
+	}
+
+	moves := movegen.LegalMoves(p)
+	if len(moves) == 0 {
+		o, _ := movegen.GameOver(p) // checkmate or stalemate
+		return s.outcomeScore(o, ply)
+	}
+
+	orderMoves(p, moves, e.move.Unpack())
+
+	var (
+		bestScore = tbFloor
+		bestMove  = moves[0]
+		origAlpha = alpha
+		quiets    int
+	)
+	alpha = max(alpha, tbFloor)
+
+	for i, m := range moves {
+		quiet := isQuiet(p, m)
+
+		child := p
+		child.Make(m)
+		givesCheck := movegen.InCheck(child)
+
+		if quiet && !inCheck && !givesCheck && bestScore > -mateThreshold {
+			// Late move pruning.
+			if s.enabled(LateMovePruning) && depth <= lateMovePruningMaxDepth &&
+				quiets >= lateMoveCount(depth) {
+				continue
+			}
+
+			// Futility pruning.
+			if s.enabled(Futility) && depth <= futilityMaxDepth &&
+				staticEval+futilityMargin*depth <= alpha {
+				continue
+			}
+		}
+
+		if quiet {
+			quiets++
+		}
+
+		newDepth := depth - 1
+
+		// Check extensions.
+		if s.enabled(CheckExtensions) && givesCheck && ply < 2*s.depth {
+			newDepth++
+		}
+
+		var v int
+		if i == 0 {
+			v = -s.negamax(child, newDepth, ply+1, -beta, -alpha, givesCheck, true)
+		} else {
+			// Late move reductions.
+			var r int
+			if s.enabled(LateMoveReductions) && quiet && !inCheck && !givesCheck &&
+				depth >= lmrMinDepth && i >= lmrMinMoves {
+				r = min(reduction(depth, i), newDepth-1)
+			}
+
+			// Search with a null window, then re-search if the move might
+			// improve alpha.
+			v = -s.negamax(child, newDepth-r, ply+1, -alpha-1, -alpha, givesCheck, true)
+			if r > 0 && v > alpha {
+				v = -s.negamax(child, newDepth, ply+1, -alpha-1, -alpha, givesCheck, true)
+			}
+			if v > alpha && v < beta {
+				v = -s.negamax(child, newDepth, ply+1, -beta, -alpha, givesCheck, true)
+			}
+		}
+
+		if s.stopped {
+			return 0
+		}
+
+		if v > bestScore {
+			bestScore, bestMove = v, m
+		}
+		if v > alpha {
+			alpha = v
+			s.updatePV(ply, m)
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+
+	bestScore = min(bestScore, tbCeil)
+
+	b := exact
+	switch {
+	case bestScore <= origAlpha:
+		b = upper
+	case bestScore >= beta:
+		b = lower
+	}
+	s.tt.store(entry{key: key, depth: depth, score: scoreToTT(bestScore, ply), bound: b, move: bestMove.Pack()})
+
+	return bestScore
+}
+
+// searchRoot searches the root moves to the given depth and window, starting
+// with the move at index first. It returns the score, and leaves the principal
+// variation at ply 0.
+func (s *searcher) searchRoot(p core.Position, moves []core.Move, depth, first, alpha, beta int) int {
+	s.depth = depth
+	s.clearPV(0)
+
+	key := hash(p)
+	s.keys = append(s.keys[:s.root], key)
+
+	// Search the best move from the previous iteration first.
+	if e, ok := s.tt.load(key); ok {
+		moves = slices.Clone(moves)
+		orderMoves(p, moves, e.move.Unpack())
+	}
+
+	var (
+		bestScore = -infinity
+		bestMove  = moves[first]
+		origAlpha = alpha
+	)
+
+	for i := range moves {
+		m := moves[(first+i)%len(moves)]
+
 		child := p
 		child.Make(m)
-		score = max(score, -negamax(child, depth-1))
+		givesCheck := movegen.InCheck(child)
+
+		newDepth := depth - 1
+		if s.enabled(CheckExtensions) && givesCheck {
+			newDepth++
+		}
+
+		var v int
+		if i == 0 {
+			v = -s.negamax(child, newDepth, 1, -beta, -alpha, givesCheck, true)
+		} else {
+			v = -s.negamax(child, newDepth, 1, -alpha-1, -alpha, givesCheck, true)
+			if v > alpha && v < beta {
+				v = -s.negamax(child, newDepth, 1, -beta, -alpha, givesCheck, true)
+			}
+		}
+
+		if s.stopped {
+			return 0
+		}
+
+		if v > bestScore {
+			bestScore, bestMove = v, m
+		}
+		if v > alpha || len(s.pv[0]) == 0 {
+			alpha = max(alpha, v)
+			s.updatePV(0, m)
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+
+	// The score of a partial search isn't the score of the position.
+	if !s.partial {
+		b := exact
+		switch {
+		case bestScore <= origAlpha:
+			b = upper
+		case bestScore >= beta:
+			b = lower
+		}
+		s.tt.store(entry{key: key, depth: depth, score: bestScore, bound: b, move: bestMove.Pack()})
+	}
+
+	return bestScore
+}
+
+// Aspiration window parameters.
+const (
+	aspirationMinDepth = 4  // Minimum depth for aspiration windows.
+	aspirationWindow   = 50 // Initial half-width of the window.
+)
+
+// aspirate searches the root with an aspiration window around the score from
+// the previous iteration. The window widens each time the search fails high
+// or low.
+func (s *searcher) aspirate(p core.Position, moves []core.Move, depth, first, prev int) int {
+	var (
+		alpha = -infinity
+		beta  = infinity
+		delta = aspirationWindow
+	)
+
+	if depth >= aspirationMinDepth {
+		alpha = max(prev-delta, -infinity)
+		beta = min(prev+delta, infinity)
+	}
+
+	for {
+		score := s.searchRoot(p, moves, depth, first, alpha, beta)
+
+		switch {
+		case s.stopped:
+			return 0
+		case score <= alpha && alpha > -infinity:
+			alpha = max(score-delta, -infinity)
+		case score >= beta && beta < infinity:
+			beta = min(score+delta, infinity)
+		default:
+			return score
+		}
+
+		delta *= 2
+	}
+}
+
+// helper runs a helper thread until it's stopped or reaches the maximum depth.
+//
+// Helpers search at slightly varied depths and root move orders, so that
+// their transposition table entries are useful to the main thread.
+func (s *searcher) helper(p core.Position, moves []core.Move, maxDepth, id int) {
+	var score int
+	for depth := 1 + id%2; maxDepth == 0 || depth <= maxDepth; depth++ {
+		score = s.aspirate(p, moves, depth, id%len(moves), score)
+		if s.stopped {
+			return
+		}
 	}
-	return score
 }
 
 // ErrNoLegalMoves is returned by Search when there are no legal moves in a
-// position.
+// position, or none of the moves in Options.SearchMoves are legal.
 var ErrNoLegalMoves = errors.New("no legal moves")
 
-// Search searches for the best move in a position.
-func Search(ctx context.Context, p core.Position, best chan<- core.Move) error {
-	for depth := 3; ; depth++ {
+// Run searches for the best move in a position, sending information about
+// each completed iteration to info.
+//
+// If opts.Mate is positive, Run instead searches for the shortest checkmate
+// within opts.Mate moves. It sends a single Info if it finds one, and returns
+// ErrNoMate otherwise.
+//
+// If opts.MultiPV is greater than 1, each iteration also searches for the next
+// best moves, excluding moves already found, and sends one Info per principal
+// variation in order of rank.
+//
+// Run returns when ctx is done, when opts.MoveTime has elapsed, or when the
+// maximum depth in opts has been searched. In the latter two cases, it returns
+// nil.
+func Run(ctx context.Context, p core.Position, opts Options, info chan<- Info) error {
+	return runTimed(ctx, p, opts, nil, info)
+}
+
+// runTimed runs a search with a time limit. If hit is non-nil, the search is
+// in ponder mode: the time limit starts when hit is ready, and the search
+// doesn't return before then unless ctx is done.
+func runTimed(ctx context.Context, p core.Position, opts Options, hit <-chan struct{}, info chan<- Info) error {
+	moves := movegen.LegalMoves(p)
+	if len(opts.SearchMoves) > 0 {
+		moves = slices.DeleteFunc(moves, func(m core.Move) bool {
+			return !slices.Contains(opts.SearchMoves, m)
+		})
+	}
+	if len(moves) == 0 {
+		return ErrNoLegalMoves
+	}
+
+	searchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// hit may deliver a single value, but it's waited on more than once.
+	if hit != nil {
+		hit = latch(searchCtx, hit)
+	}
+
+	go limit(searchCtx, cancel, opts.MoveTime, hit)
+
+	err := run(searchCtx, p, moves, opts, info)
+
+	// The search was stopped by the time limit, not by the caller.
+	if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+		err = nil
+	}
+
+	if err == nil && hit != nil {
+		select {
+		case <-hit:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return err
+}
+
+// run runs a search.
+func run(ctx context.Context, p core.Position, moves []core.Move, opts Options, info chan<- Info) error {
+	start := time.Now()
+
+	if opts.Mate > 0 {
+		s := &searcher{ctx: ctx, disproved: make(map[mateKey]bool)}
+		return runMate(s, p, opts.Mate, func(i Info) error {
+			i.Nodes = int(s.nodes.Load())
+			i.Time = time.Since(start)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case info <- i:
+				return nil
+			}
+		})
+	}
+
+	// If the root is in the tablebase, search only the moves that preserve
+	// its result, without probing further.
+	var tbHits int
+	moves, rootInTB := filterRootMoves(p, moves, opts.Tablebase)
+	if rootInTB {
+		opts.Tablebase = nil
+		tbHits = 1
+	}
+
+	var (
+		reported   = min(max(opts.MultiPV, 1), len(moves))
+		candidates = reported
+	)
+
+	sk := newSkill(opts.Skill, opts.Seed)
+	if sk != nil {
+		opts.Threads = 1
+		opts.Depth = atMost(opts.Depth, sk.depth())
+		opts.Nodes = atMost(opts.Nodes, sk.nodes())
+		candidates = min(max(reported, skillMultiPV), len(moves))
+	}
+
+	history := hashes(opts.History)
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	var (
+		tt        = newTable()
+		searchers = make([]*searcher, max(opts.Threads, 1))
+		total     atomic.Int64
+		wg        sync.WaitGroup
+	)
+
+	for i := range searchers {
+		searchers[i] = &searcher{
+			ctx:      ctx,
+			tt:       tt,
+			disable:  opts.Disable,
+			total:    &total,
+			maxNodes: int64(opts.Nodes),
+			contempt: opts.Contempt,
+			keys:     slices.Clone(history),
+			root:     len(history),
+		}
+		if opts.Tablebase != nil {
+			searchers[i].tb = opts.Tablebase
+			searchers[i].tbPieces = opts.Tablebase.MaxPieces()
+			searchers[i].tbDepth = max(opts.ProbeDepth, 1)
+		}
+	}
+
+	// Start the helpers, and stop them when the main thread returns.
+	for i, s := range searchers[1:] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.helper(p, moves, opts.Depth, i+1)
+		}()
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	var (
+		primary = searchers[0]
+		scores  = make([]int, candidates)
+	)
+
+	for depth := 1; opts.Depth == 0 || depth <= opts.Depth; depth++ {
 		var (
-			bestScore = math.MinInt
-			bestMove  core.Move
+			lines     []Info
+			remaining = moves
 		)
 
-		moves := movegen.LegalMoves(p)
+		for rank := range scores {
+			primary.partial = rank > 0
+			scores[rank] = primary.aspirate(p, remaining, depth, 0, scores[rank])
+			if primary.stopped {
+				return ctx.Err()
+			}
+
+			pv := slices.Clone(primary.pv[0])
+
+			lines = append(lines, Info{
+				Depth: depth,
+				Score: scores[rank],
+				Move:  pv[0],
+				PV:    pv,
+				Rank:  rank + 1,
+			})
+
+			remaining = slices.DeleteFunc(slices.Clone(remaining), func(m core.Move) bool {
+				return m == pv[0]
+			})
+		}
+
+		// At limited skill, the line to play is reported first.
+		if sk != nil {
+			i := sk.pick(lines)
+			picked := lines[i]
+			lines = slices.Insert(slices.Delete(lines, i, i+1), 0, picked)
+			for rank := range lines {
+				lines[rank].Rank = rank + 1
+			}
+		}
 
-		if len(moves) == 0 {
-			return ErrNoLegalMoves
+		var nodes, hits int
+		for _, s := range searchers {
+			nodes += int(s.nodes.Load())
+			hits += int(s.tbHits.Load())
 		}
 
-		for _, m := range moves {
-			child := p
-			child.Make(m)
+		for _, i := range lines[:reported] {
+			i.Nodes = nodes
+			i.TBHits = tbHits + hits
+			i.Time = time.Since(start)
 
-			if s := -negamax(child, depth-1); s > bestScore {
-				bestScore, bestMove = s, m
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case info <- i:
 			}
 		}
+	}
+
+	return nil
+}
 
+// Search searches for the best move in a position, given the positions of the
+// game before it, oldest first.
+//
+// It sends the best move found so far after each iteration, and returns when
+// ctx is done.
+func Search(ctx context.Context, p core.Position, best chan<- core.Move, history ...core.Position) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		info = make(chan Info)
+		errc = make(chan error, 1)
+	)
+
+	go func() {
+		errc <- Run(ctx, p, Options{History: history}, info)
+	}()
+
+	for {
 		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case best <- bestMove:
+		case err := <-errc:
+			return err
+		case i := <-info:
+			select {
+			case <-ctx.Done():
+				return <-errc
+			case best <- i.Move:
+			}
 		}
 	}
 }
@@ -0,0 +1,393 @@
+package search
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/encoding/san"
+	"github.com/clfs/simple/eval"
+	"github.com/clfs/simple/movegen"
+	"github.com/google/go-cmp/cmp"
+)
+
+// runDepth runs a search to a fixed depth and returns the final iteration.
+func runDepth(t *testing.T, p core.Position, opts Options) Info {
+	t.Helper()
+
+	var (
+		info = make(chan Info)
+		errc = make(chan error, 1)
+		last Info
+	)
+
+	go func() {
+		errc <- Run(context.Background(), p, opts, info)
+	}()
+
+	for {
+		select {
+		case err := <-errc:
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			return last
+		case last = <-info:
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1", "d2d5"},
+		{"4k3/8/8/8/8/1r6/8/1R2K3 w - - 0 1", "b1b3"},
+	}
+
+	for _, tc := range cases {
+		for _, threads := range []int{1, 4} {
+			p := fen.MustDecode(tc.in)
+			got := runDepth(t, p, Options{Threads: threads, Depth: 2})
+			if got.Depth != 2 {
+				t.Errorf("%q with %d threads: got depth %d, want 2", tc.in, threads, got.Depth)
+			}
+			if move := pcn.Encode(got.Move); move != tc.want {
+				t.Errorf("%q with %d threads: got %s, want %s", tc.in, threads, move, tc.want)
+			}
+		}
+	}
+}
+
+func TestRun_Variants(t *testing.T) {
+	cases := []struct {
+		variant core.Variant
+		in      string
+		depth   int
+		want    int
+	}{
+		{core.KingOfTheHill, "8/8/8/8/8/4k3/8/4K3 b - - 0 1", 2, Mate - 1},
+		{core.KingOfTheHill, "8/8/8/8/8/8/k7/4K3 w - - 0 1", 5, Mate - 5}, // Not a draw by insufficient material.
+		{core.ThreeCheck, "4k3/8/8/8/8/8/8/R3K3 w - - 0 1 +2+0", 2, Mate - 1},
+		{core.Crazyhouse, "6k1/5ppp/8/8/8/8/8/6K1[R] w - - 0 1", 2, Mate - 1},
+		{core.Atomic, "4k3/4p3/8/8/8/8/8/4R1K1 w - - 0 1", 2, Mate - 1},
+		{core.Antichess, "8/8/8/8/8/8/1r6/R7 w - - 0 1", 3, Mate - 2},
+		{core.Horde, "4k3/8/8/8/8/8/3P4/3r4 b - - 0 1", 2, Mate - 1},
+		{core.RacingKings, "8/5K2/8/8/8/8/8/k7 w - - 0 1", 2, Mate - 1},
+	}
+
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.in)
+		p.Variant = tc.variant
+		got := runDepth(t, p, Options{Depth: tc.depth})
+		if got.Score != tc.want {
+			t.Errorf("%s %q: got score %d, want %d", tc.variant, tc.in, got.Score, tc.want)
+		}
+	}
+}
+
+// minimax returns the score of a position without pruning.
+func minimax(p core.Position, depth, ply int) int {
+	if depth == 0 {
+		return eval.Eval(p)
+	}
+
+	moves := movegen.LegalMoves(p)
+	if len(moves) == 0 {
+		if movegen.InCheck(p) {
+			return -Mate + ply
+		}
+		return 0
+	}
+
+	score := -infinity
+	for _, m := range moves {
+		child := p
+		child.Make(m)
+		score = max(score, -minimax(child, depth-1, ply+1))
+	}
+	return score
+}
+
+func TestRun_AlphaBeta(t *testing.T) {
+	cases := []struct {
+		in    string
+		depth int
+	}{
+		{fen.Starting, 3},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 2},
+		{"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 3},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 3},
+	}
+
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.in)
+		got := runDepth(t, p, Options{Threads: 1, Depth: tc.depth, Disable: AllTechniques})
+		if want := minimax(p, tc.depth, 0); got.Score != want {
+			t.Errorf("%q at depth %d: got score %d, want %d", tc.in, tc.depth, got.Score, want)
+		}
+	}
+}
+
+func TestRun_Techniques(t *testing.T) {
+	cases := []struct {
+		in    string
+		depth int
+		want  string
+	}{
+		{"4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1", 3, "d2d5"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 3, "a1a8"},
+		{"r5k1/5ppp/8/8/8/8/5PPP/6K1 b - - 0 1", 3, "a8a1"},
+		{"4k3/8/8/8/8/1r6/8/1R2K3 w - - 0 1", 4, "b1b3"},
+	}
+
+	techniques := []Technique{
+		0,
+		NullMove,
+		LateMoveReductions,
+		ReverseFutility,
+		Futility,
+		LateMovePruning,
+		CheckExtensions,
+		AllTechniques,
+	}
+
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.in)
+		for _, disable := range techniques {
+			got := runDepth(t, p, Options{Threads: 1, Depth: tc.depth, Disable: disable})
+			if move := pcn.Encode(got.Move); move != tc.want {
+				t.Errorf("%q with %b disabled: got %s, want %s", tc.in, disable, move, tc.want)
+			}
+		}
+	}
+}
+
+func TestRun_Deterministic(t *testing.T) {
+	p := core.NewPosition()
+	opts := Options{Threads: 1, Depth: 3}
+
+	a := runDepth(t, p, opts)
+	b := runDepth(t, p, opts)
+
+	a.Time, b.Time = 0, 0
+	if diff := cmp.Diff(a, b); diff != "" {
+		t.Errorf("mismatch (-first +second):\n%s", diff)
+	}
+}
+
+func TestRun_PV(t *testing.T) {
+	cases := []struct {
+		in    string
+		depth int
+		want  []string // Leading SAN moves of the principal variation.
+	}{
+		{fen.Starting, 4, nil},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 3, nil},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 3, []string{"Ra8#"}},
+		{"6k1/5ppp/8/8/8/8/6PP/r5K1 w - - 0 1", 3, []string{"Kf2"}},
+	}
+
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.in)
+		got := runDepth(t, p, Options{Threads: 1, Depth: tc.depth})
+
+		if len(got.PV) == 0 || got.PV[0] != got.Move {
+			t.Errorf("%q: PV %v doesn't start with best move %v", tc.in, got.PV, got.Move)
+			continue
+		}
+
+		// Every move in the PV must be legal.
+		child := p
+		for _, m := range got.PV {
+			if !slices.Contains(movegen.LegalMoves(child), m) {
+				t.Errorf("%q: PV %v contains illegal move %s", tc.in, got.PV, pcn.Encode(m))
+				break
+			}
+			child.Make(m)
+		}
+
+		if tc.want == nil && len(got.PV) < tc.depth {
+			t.Errorf("%q: got PV length %d, want at least %d", tc.in, len(got.PV), tc.depth)
+		}
+
+		if moves := san.EncodeMoves(p, got.PV); len(moves) < len(tc.want) ||
+			!slices.Equal(tc.want, moves[:len(tc.want)]) {
+			t.Errorf("%q: got PV %v, want it to start with %v", tc.in, moves, tc.want)
+		}
+	}
+}
+
+func TestRun_Aspiration(t *testing.T) {
+	// Scores must not depend on the aspiration window, even when the first
+	// window fails high.
+	p := fen.MustDecode("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+	opts := Options{Threads: 1, Depth: aspirationMinDepth, Disable: AllTechniques}
+
+	got := runDepth(t, p, opts)
+	if want := minimax(p, opts.Depth, 0); got.Score != want {
+		t.Errorf("got score %d, want %d", got.Score, want)
+	}
+}
+
+func TestRun_Threads(t *testing.T) {
+	p := core.NewPosition()
+
+	got := runDepth(t, p, Options{Threads: 4, Depth: 6})
+	if !slices.Contains(movegen.LegalMoves(p), got.Move) {
+		t.Errorf("got illegal move %s", pcn.Encode(got.Move))
+	}
+	if got.Depth != 6 {
+		t.Errorf("got depth %d, want 6", got.Depth)
+	}
+
+	// The node limit is for all threads together, and each thread may visit
+	// one more node before it stops.
+	const limit = 20000
+	got = runDepth(t, p, Options{Threads: 4, Nodes: limit})
+	if got.Nodes > limit+4 {
+		t.Errorf("got %d nodes, want at most %d", got.Nodes, limit+4)
+	}
+}
+
+// runLines runs a search to a fixed depth and returns the principal
+// variations of the final iteration.
+func runLines(t *testing.T, p core.Position, opts Options) []Info {
+	t.Helper()
+
+	var (
+		info  = make(chan Info)
+		errc  = make(chan error, 1)
+		lines []Info
+	)
+
+	go func() {
+		errc <- Run(context.Background(), p, opts, info)
+	}()
+
+	for {
+		select {
+		case err := <-errc:
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			return lines
+		case i := <-info:
+			if i.Rank == 1 {
+				lines = nil
+			}
+			lines = append(lines, i)
+		}
+	}
+}
+
+func TestRun_MultiPVOne(t *testing.T) {
+	cases := []string{
+		fen.Starting,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1",
+	}
+
+	for _, in := range cases {
+		p := fen.MustDecode(in)
+		want := runDepth(t, p, Options{Threads: 1, Depth: 3})
+		got := runDepth(t, p, Options{Threads: 1, Depth: 3, MultiPV: 1})
+		if got.Move != want.Move || got.Rank != 1 {
+			t.Errorf("%q: got %s with rank %d, want %s with rank 1", in, pcn.Encode(got.Move), got.Rank, pcn.Encode(want.Move))
+		}
+	}
+}
+
+func TestRun_MultiPV(t *testing.T) {
+	p := fen.MustDecode("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+	moves := movegen.LegalMoves(p)
+	depth := 2
+
+	// Without pruning, each line's score is the minimax score of its move.
+	var want []int
+	for _, m := range moves {
+		child := p
+		child.Make(m)
+		want = append(want, -minimax(child, depth-1, 1))
+	}
+	slices.Sort(want)
+	slices.Reverse(want)
+
+	lines := runLines(t, p, Options{Threads: 1, Depth: depth, Disable: AllTechniques, MultiPV: len(moves) + 1})
+
+	var (
+		got  []int
+		seen = make(map[core.Move]bool)
+	)
+	for i, line := range lines {
+		if line.Rank != i+1 {
+			t.Errorf("line %d: got rank %d", i, line.Rank)
+		}
+		if seen[line.Move] {
+			t.Errorf("line %d: duplicate move %s", i, pcn.Encode(line.Move))
+		}
+		seen[line.Move] = true
+		got = append(got, line.Score)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("scores mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRun_NoLegalMoves(t *testing.T) {
+	p := fen.MustDecode("7k/5Q2/6K1/8/8/8/8/8 b - - 0 1")
+	if err := Run(context.Background(), p, Options{}, nil); err != ErrNoLegalMoves {
+		t.Errorf("got error %v, want %v", err, ErrNoLegalMoves)
+	}
+}
+
+func TestRun_SearchMoves(t *testing.T) {
+	// d2d5 wins the queen, but it isn't one of the moves searched.
+	p := fen.MustDecode("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+	searchMoves := []core.Move{pcn.MustDecode("e1f1"), pcn.MustDecode("e1f2"), pcn.MustDecode("a1a2")}
+
+	got := runDepth(t, p, Options{Depth: 3, SearchMoves: searchMoves})
+	if !slices.Contains(searchMoves[:2], got.Move) {
+		t.Errorf("got %s, want e1f1 or e1f2", pcn.Encode(got.Move))
+	}
+
+	err := Run(context.Background(), p, Options{SearchMoves: searchMoves[2:]}, nil)
+	if err != ErrNoLegalMoves {
+		t.Errorf("got error %v, want %v", err, ErrNoLegalMoves)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	p := core.NewPosition()
+	best := make(chan core.Move, 1)
+
+	go func() {
+		for range best {
+		}
+	}()
+
+	if err := Search(ctx, p, best); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(best)
+}
+
+func TestRun_MoveTime(t *testing.T) {
+	start := time.Now()
+	got := runDepth(t, core.NewPosition(), Options{MoveTime: 100 * time.Millisecond})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %v, want about 100ms", elapsed)
+	}
+	if got.Depth < 1 {
+		t.Errorf("got depth %d, want at least 1", got.Depth)
+	}
+}
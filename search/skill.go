@@ -0,0 +1,72 @@
+package search
+
+import "math/rand/v2"
+
+// MaxSkill is the highest skill level, which plays at full strength.
+//
+// At lower levels, the search depth and the nodes searched are limited, and
+// the move played is picked at random among at least skillMultiPV principal
+// variations. Moves that score worse are less likely to be picked, and the
+// lower the level, the more likely it is that a worse move is picked anyway.
+const MaxSkill = 20
+
+// skillMultiPV is the minimum number of candidate moves at limited skill.
+const skillMultiPV = 4
+
+// A skill is a limited skill level.
+type skill struct {
+	level int
+	rng   *rand.Rand
+}
+
+// newSkill returns a skill level, or nil for full strength.
+func newSkill(level int, seed uint64) *skill {
+	if level <= 0 || level >= MaxSkill {
+		return nil
+	}
+	return &skill{
+		level: level,
+		rng:   rand.New(rand.NewPCG(seed, 0x736b696c6c)),
+	}
+}
+
+// depth returns the maximum search depth.
+func (sk *skill) depth() int {
+	return 1 + sk.level/4
+}
+
+// nodes returns the maximum number of nodes to search.
+func (sk *skill) nodes() int {
+	return 200 << (sk.level / 2)
+}
+
+// atMost lowers a limit to at most n, where a limit of zero means no limit.
+func atMost(limit, n int) int {
+	if limit <= 0 {
+		return n
+	}
+	return min(limit, n)
+}
+
+// pick picks the index of a line to play, given lines in order of rank.
+//
+// Each line's score is pushed up by a random amount, and by a share of the
+// gap between it and the best score. Both grow as the level goes down.
+func (sk *skill) pick(lines []Info) int {
+	var (
+		weakness  = 120 - 2*sk.level
+		top       = lines[0].Score
+		delta     = min(top-lines[len(lines)-1].Score, 100)
+		best      int
+		bestScore = -infinity
+	)
+
+	for i, l := range lines {
+		score := l.Score + (weakness*(top-l.Score)+delta*sk.rng.IntN(weakness))/128
+		if score >= bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	return best
+}
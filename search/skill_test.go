@@ -0,0 +1,146 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/eval"
+	"github.com/clfs/simple/movegen"
+	"github.com/google/go-cmp/cmp"
+)
+
+// maxGamePlies is the length at which self-play games are adjudicated.
+const maxGamePlies = 60
+
+// adjudicationMargin is the material advantage that wins an adjudicated game.
+const adjudicationMargin = 300
+
+// playGame plays a game between two skill levels. It returns 1 if white wins,
+// -1 if black wins, and 0 for a draw.
+func playGame(t *testing.T, white, black int, seed uint64) int {
+	t.Helper()
+
+	p := core.NewPosition()
+
+	for ply := range maxGamePlies {
+		if len(movegen.LegalMoves(p)) == 0 {
+			if !movegen.InCheck(p) {
+				return 0
+			}
+			if p.SideToMove == core.White {
+				return -1
+			}
+			return 1
+		}
+
+		level := white
+		if p.SideToMove == core.Black {
+			level = black
+		}
+		p.Make(runDepth(t, p, Options{Skill: level, Seed: seed + uint64(ply)}).Move)
+	}
+
+	score := eval.Eval(p)
+	if p.SideToMove == core.Black {
+		score = -score
+	}
+	switch {
+	case score >= adjudicationMargin:
+		return 1
+	case score <= -adjudicationMargin:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func TestSkill_Calibration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping self-play in short mode")
+	}
+
+	const games = 4
+
+	// Each level must beat the one before it.
+	levels := []int{1, 4, 8, 12}
+	for i, level := range levels[1:] {
+		weaker := levels[i]
+
+		// Alternate colors, counting wins as 1 and losses as -1.
+		var score int
+		for g := range games {
+			if g%2 == 0 {
+				score += playGame(t, level, weaker, uint64(g))
+			} else {
+				score -= playGame(t, weaker, level, uint64(g))
+			}
+		}
+
+		if score <= 0 {
+			t.Errorf("level %d scored %+d in %d games against level %d, want a positive score",
+				level, score, games, weaker)
+		}
+	}
+}
+
+func TestSkill_Pick(t *testing.T) {
+	lines := []Info{{Score: 100}, {Score: 90}, {Score: -400}, {Score: -800}}
+
+	picks := func(level int) map[int]int {
+		sk := newSkill(level, 1)
+		got := make(map[int]int)
+		for range 200 {
+			got[sk.pick(lines)]++
+		}
+		return got
+	}
+
+	// The strongest level never blunders material.
+	if got := picks(MaxSkill - 1); got[2]+got[3] > 0 {
+		t.Errorf("level %d picked losing lines: %v", MaxSkill-1, got)
+	}
+	// The weakest level picks worse moves.
+	if got := picks(1); got[0] == 200 {
+		t.Errorf("level 1 always picked the best line")
+	}
+}
+
+func TestRun_Skill(t *testing.T) {
+	p := core.NewPosition()
+
+	for _, level := range []int{1, 10, MaxSkill - 1} {
+		sk := newSkill(level, 0)
+		got := runLines(t, p, Options{Skill: level, Seed: 7})
+		if len(got) != 1 {
+			t.Fatalf("level %d: got %d lines, want 1", level, len(got))
+		}
+		if got[0].Depth > sk.depth() {
+			t.Errorf("level %d: got depth %d, want at most %d", level, got[0].Depth, sk.depth())
+		}
+	}
+}
+
+func TestRun_SkillDeterministic(t *testing.T) {
+	p := core.NewPosition()
+
+	for _, level := range []int{1, 10} {
+		opts := Options{Skill: level, Seed: 42, Threads: 4}
+		var moves []core.Move
+		for range 3 {
+			moves = append(moves, runDepth(t, p, opts).Move)
+		}
+		for _, m := range moves[1:] {
+			if diff := cmp.Diff(moves[0], m); diff != "" {
+				t.Errorf("level %d: moves differ (-first +later):\n%s", level, diff)
+			}
+		}
+	}
+}
+
+func TestNewSkill(t *testing.T) {
+	for _, level := range []int{-1, 0, MaxSkill, MaxSkill + 1} {
+		if sk := newSkill(level, 0); sk != nil {
+			t.Errorf("newSkill(%d) = %v, want nil", level, sk)
+		}
+	}
+}
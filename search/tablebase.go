@@ -0,0 +1,82 @@
+package search
+
+import (
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/tablebase"
+)
+
+// A Tablebase probes endgame tablebases. *tablebase.Tablebase implements it.
+type Tablebase interface {
+	// MaxPieces returns the largest number of pieces in a position that
+	// can be probed.
+	MaxPieces() int
+
+	// ProbeWDL returns the result of a position, assuming the fifty-move
+	// counter is zero.
+	ProbeWDL(p core.Position) (tablebase.WDL, error)
+
+	// RootMoves returns the moves among moves that preserve the result of
+	// a position.
+	RootMoves(p core.Position, moves []core.Move) ([]core.Move, error)
+}
+
+// tbWin is the score of a tablebase win at the root. It's less than any
+// checkmate score, and wins found deeper score one less per ply.
+const tbWin = mateThreshold - 1
+
+// tbThreshold is the smallest absolute score that indicates a tablebase win
+// or checkmate.
+const tbThreshold = tbWin - maxMatePly
+
+// tbDepthBonus is added to the depth of tablebase results in the
+// transposition table, since they're better than searching.
+const tbDepthBonus = 6
+
+// canProbe returns true if a position can be probed. Tablebases are only for
+// standard chess.
+func canProbe(p core.Position, maxPieces int) bool {
+	if p.Variant != core.Standard || p.WhiteOO || p.WhiteOOO || p.BlackOO || p.BlackOOO {
+		return false
+	}
+	occupied := p.Board.WhitePieces() | p.Board.BlackPieces()
+	return occupied.Count() <= maxPieces
+}
+
+// probe probes the tablebase in a position with a zero fifty-move counter.
+// It returns the score and whether it's exact, a lower bound, or an upper
+// bound.
+func (s *searcher) probe(p core.Position, ply int) (int, bound, bool) {
+	if s.tb == nil || p.HalfMoveClock != 0 || !canProbe(p, s.tbPieces) {
+		return 0, exact, false
+	}
+
+	wdl, err := s.tb.ProbeWDL(p)
+	if err != nil {
+		return 0, exact, false
+	}
+	s.tbHits.Add(1)
+
+	// Cursed wins and blessed losses are draws, but slightly better and
+	// worse than other draws.
+	switch wdl {
+	case tablebase.Win:
+		return tbWin - ply, lower, true
+	case tablebase.Loss:
+		return -tbWin + ply, upper, true
+	default:
+		return s.drawScore(ply) + int(wdl), exact, true
+	}
+}
+
+// filterRootMoves returns the root moves that preserve the tablebase result
+// of a position, and whether the tablebase was probed.
+func filterRootMoves(p core.Position, moves []core.Move, tb Tablebase) ([]core.Move, bool) {
+	if tb == nil || !canProbe(p, tb.MaxPieces()) {
+		return moves, false
+	}
+	kept, err := tb.RootMoves(p, moves)
+	if err != nil || len(kept) == 0 {
+		return moves, false
+	}
+	return kept, true
+}
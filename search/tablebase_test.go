@@ -0,0 +1,86 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/tablebase"
+)
+
+// fakeTablebase has positions with up to three pieces. The side with more
+// pieces wins, and at the root, only moves in keep preserve the result.
+type fakeTablebase struct {
+	keep []string
+}
+
+func (fakeTablebase) MaxPieces() int { return 3 }
+
+func (fakeTablebase) ProbeWDL(p core.Position) (tablebase.WDL, error) {
+	us, them := p.Board.WhitePieces(), p.Board.BlackPieces()
+	if p.SideToMove == core.Black {
+		us, them = them, us
+	}
+	switch {
+	case us.Count() > them.Count():
+		return tablebase.Win, nil
+	case us.Count() < them.Count():
+		return tablebase.Loss, nil
+	default:
+		return tablebase.Draw, nil
+	}
+}
+
+func (tb fakeTablebase) RootMoves(p core.Position, moves []core.Move) ([]core.Move, error) {
+	var kept []core.Move
+	for _, m := range moves {
+		for _, k := range tb.keep {
+			if pcn.Encode(m) == k {
+				kept = append(kept, m)
+			}
+		}
+	}
+	return kept, nil
+}
+
+func TestRun_TablebaseRoot(t *testing.T) {
+	p := fen.MustDecode("4k3/8/8/8/8/8/8/4K2R w - - 0 1")
+	opts := Options{Depth: 3, MultiPV: 3, Tablebase: fakeTablebase{keep: []string{"h1h2"}}}
+
+	lines := runLines(t, p, opts)
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1", len(lines))
+	}
+	if got := pcn.Encode(lines[0].Move); got != "h1h2" {
+		t.Errorf("got %s, want h1h2", got)
+	}
+	if lines[0].TBHits != 1 {
+		t.Errorf("got %d tablebase hits, want 1", lines[0].TBHits)
+	}
+}
+
+func TestRun_TablebaseProbe(t *testing.T) {
+	// Capturing the rook reaches a won three-piece position.
+	p := fen.MustDecode("4k3/8/8/8/8/8/3r4/3R1K2 w - - 0 1")
+
+	got := runDepth(t, p, Options{Depth: 2, Tablebase: fakeTablebase{}})
+	if move := pcn.Encode(got.Move); move != "d1d2" {
+		t.Errorf("got %s, want d1d2", move)
+	}
+	if got.Score != tbWin-1 {
+		t.Errorf("got score %d, want %d", got.Score, tbWin-1)
+	}
+	if got.TBHits == 0 {
+		t.Error("got no tablebase hits")
+	}
+	if _, ok := MateIn(got.Score); ok {
+		t.Errorf("tablebase win %d is a mate score", got.Score)
+	}
+
+	// Probes are skipped below the probe depth.
+	got = runDepth(t, p, Options{Depth: 2, Tablebase: fakeTablebase{}, ProbeDepth: 2})
+	if got.TBHits != 0 {
+		t.Errorf("got %d tablebase hits, want 0", got.TBHits)
+	}
+}
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"math"
+
+	"github.com/clfs/simple/core"
+)
+
+// A Technique is a selective search technique. Techniques can be combined with
+// bitwise OR.
+type Technique uint
+
+// Selective search techniques.
+const (
+	NullMove           Technique = 1 << iota // Adaptive null move pruning.
+	LateMoveReductions                       // Log-based late move reductions.
+	ReverseFutility                          // Reverse futility pruning.
+	Futility                                 // Futility pruning.
+	LateMovePruning                          // Late move pruning.
+	CheckExtensions                          // Check extensions.
+
+	AllTechniques = NullMove | LateMoveReductions | ReverseFutility | Futility | LateMovePruning | CheckExtensions
+)
+
+// Tuning parameters for selective search techniques.
+const (
+	nullMoveMinDepth = 3 // Minimum depth for null move pruning.
+
+	lmrMinDepth = 3 // Minimum depth for late move reductions.
+	lmrMinMoves = 3 // Moves searched at full depth before reducing.
+
+	reverseFutilityMaxDepth = 3   // Maximum depth for reverse futility pruning.
+	reverseFutilityMargin   = 120 // Margin per ply of depth.
+
+	futilityMaxDepth = 2   // Maximum depth for futility pruning.
+	futilityMargin   = 150 // Margin per ply of depth.
+
+	lateMovePruningMaxDepth = 3 // Maximum depth for late move pruning.
+)
+
+// reductions holds late move reductions indexed by depth and move number.
+var reductions [64][64]int
+
+func init() {
+	for d := 1; d < len(reductions); d++ {
+		for m := 1; m < len(reductions[d]); m++ {
+			reductions[d][m] = int(0.75 + math.Log(float64(d))*math.Log(float64(m))/2.25)
+		}
+	}
+}
+
+// reduction returns the late move reduction for the nth move at some depth.
+func reduction(depth, n int) int {
+	return reductions[min(depth, 63)][min(n, 63)]
+}
+
+// nullMoveReduction returns the null move depth reduction at some depth.
+func nullMoveReduction(depth int) int {
+	return 3 + depth/6
+}
+
+// lateMoveCount returns how many quiet moves are searched at some depth before
+// late move pruning applies.
+func lateMoveCount(depth int) int {
+	return 3 + depth*depth
+}
+
+// hasNonPawnMaterial returns true if the side to move has pieces other than
+// pawns and the king. Null move pruning is unsafe without them, since such
+// positions are prone to zugzwang.
+func hasNonPawnMaterial(p core.Position) bool {
+	for pt := core.Knight; pt <= core.Queen; pt++ {
+		if p.Board[core.NewPiece(p.SideToMove, pt)] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// makeNull passes the turn to the opponent. Positions before a null move
+// can't be repeated after it, so the half move clock is reset.
+func makeNull(p *core.Position) {
+	p.SideToMove = p.SideToMove.Other()
+	p.EnPassant = 0
+	p.HalfMoveClock = 0
+}
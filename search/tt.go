@@ -0,0 +1,82 @@
+package search
+
+import (
+	"sync"
+
+	"github.com/clfs/simple/core"
+)
+
+// tableSize is the number of entries in a transposition table.
+const tableSize = 1 << 16
+
+// A bound describes how an entry's score relates to the true score.
+type bound int
+
+const (
+	exact bound = iota // The score is exact.
+	lower              // The score is a lower bound.
+	upper              // The score is an upper bound.
+)
+
+// An entry is a transposition table entry.
+type entry struct {
+	key   uint64
+	depth int
+	score int
+	bound bound
+	move  core.PackedMove
+}
+
+// cutoff returns true if the entry's score can be used at the given depth
+// and window.
+func (e entry) cutoff(depth, alpha, beta int) bool {
+	if e.depth < depth {
+		return false
+	}
+	switch e.bound {
+	case lower:
+		return e.score >= beta
+	case upper:
+		return e.score <= alpha
+	default:
+		return true
+	}
+}
+
+// A slot holds a single entry. It's safe for concurrent use.
+type slot struct {
+	mu sync.Mutex
+	e  entry
+}
+
+// A table is a transposition table shared by all search threads.
+type table struct {
+	slots []slot
+}
+
+func newTable() *table {
+	return &table{slots: make([]slot, tableSize)}
+}
+
+// load returns the entry for a key, if any.
+func (t *table) load(key uint64) (entry, bool) {
+	s := &t.slots[key%tableSize]
+
+	s.mu.Lock()
+	e := s.e
+	s.mu.Unlock()
+
+	return e, e.key == key
+}
+
+// store saves an entry, replacing the existing entry unless it's for the same
+// position and was searched deeper.
+func (t *table) store(e entry) {
+	s := &t.slots[e.key%tableSize]
+
+	s.mu.Lock()
+	if s.e.key != e.key || e.depth >= s.e.depth {
+		s.e = e
+	}
+	s.mu.Unlock()
+}
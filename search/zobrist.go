@@ -0,0 +1,109 @@
+package search
+
+import (
+	"math/rand/v2"
+
+	"github.com/clfs/simple/core"
+)
+
+// Zobrist keys used to hash positions for the transposition table.
+var (
+	pieceKeys     [12][64]uint64
+	sideKey       uint64
+	castlingKeys  [4]uint64
+	enPassantKeys [8]uint64
+	checkKeys     [2][4]uint64 // Checks given by each color in Three-check.
+	pocketKeys    [2][core.King]uint64
+	promotedKeys  [64]uint64
+)
+
+func init() {
+	// A fixed seed keeps hashes, and therefore searches, reproducible.
+	r := rand.New(rand.NewPCG(0x73696d706c65, 0x7a6f6272697374))
+
+	for i := range pieceKeys {
+		for j := range pieceKeys[i] {
+			pieceKeys[i][j] = r.Uint64()
+		}
+	}
+
+	sideKey = r.Uint64()
+
+	for i := range castlingKeys {
+		castlingKeys[i] = r.Uint64()
+	}
+
+	for i := range enPassantKeys {
+		enPassantKeys[i] = r.Uint64()
+	}
+
+	for i := range checkKeys {
+		for j := range checkKeys[i] {
+			checkKeys[i][j] = r.Uint64()
+		}
+	}
+
+	for i := range pocketKeys {
+		for j := range pocketKeys[i] {
+			pocketKeys[i][j] = r.Uint64()
+		}
+	}
+
+	for i := range promotedKeys {
+		promotedKeys[i] = r.Uint64()
+	}
+}
+
+// hash returns the Zobrist hash of a position.
+func hash(p core.Position) uint64 {
+	var h uint64
+
+	for piece, bb := range p.Board {
+		for bb != 0 {
+			s := bb.First()
+			bb.Clear(s)
+			h ^= pieceKeys[piece][s]
+		}
+	}
+
+	if p.SideToMove == core.Black {
+		h ^= sideKey
+	}
+
+	if p.WhiteOO {
+		h ^= castlingKeys[0]
+	}
+	if p.WhiteOOO {
+		h ^= castlingKeys[1]
+	}
+	if p.BlackOO {
+		h ^= castlingKeys[2]
+	}
+	if p.BlackOOO {
+		h ^= castlingKeys[3]
+	}
+
+	if p.EnPassant != 0 {
+		h ^= enPassantKeys[p.EnPassant.File()]
+	}
+
+	if p.Variant == core.ThreeCheck {
+		h ^= checkKeys[0][min(p.WhiteChecks, 3)]
+		h ^= checkKeys[1][min(p.BlackChecks, 3)]
+	}
+
+	// Pocket counts are hashed by adding multiples of per-piece keys.
+	if p.Variant == core.Crazyhouse {
+		for pt := core.Pawn; pt < core.King; pt++ {
+			h += pocketKeys[0][pt] * uint64(p.WhitePocket[pt])
+			h += pocketKeys[1][pt] * uint64(p.BlackPocket[pt])
+		}
+		for bb := p.Promoted; bb != 0; {
+			s := bb.First()
+			bb.Clear(s)
+			h ^= promotedKeys[s]
+		}
+	}
+
+	return h
+}
@@ -0,0 +1,100 @@
+package tablebase
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// decompress returns the value at an index in compressed data.
+func decompress(r io.ReaderAt, d *pairsData, idx uint64) (int, error) {
+	if d.flags&flagSingleValue != 0 {
+		return d.minSymLen, nil
+	}
+
+	// The sparse index points to the value in the middle of every span of
+	// values. Start there and walk the blocks to the one holding idx.
+	k := idx / d.span
+	if k >= uint64(len(d.sparseIndex)) {
+		return 0, errors.New("index out of range")
+	}
+	block := int(d.sparseIndex[k].block)
+	offset := int(d.sparseIndex[k].offset) + int(idx%d.span) - int(d.span/2)
+
+	for offset < 0 {
+		block--
+		if block < 0 {
+			return 0, errors.New("corrupt sparse index")
+		}
+		offset += int(d.blockLength[block]) + 1
+	}
+	for block < len(d.blockLength) && offset > int(d.blockLength[block]) {
+		offset -= int(d.blockLength[block]) + 1
+		block++
+	}
+	if block >= len(d.blockLength) {
+		return 0, errors.New("corrupt sparse index")
+	}
+
+	// The block may be shorter at the end of the file. The padding is read
+	// as zeros, which are never decoded.
+	buf := make([]byte, d.blockSize+8)
+	n, err := r.ReadAt(buf[:d.blockSize], d.data+int64(block)*d.blockSize)
+	if n == 0 && err != nil {
+		return 0, err
+	}
+
+	// Decode symbols until reaching the one that expands to the value at
+	// offset. Each symbol is found by its length, since canonical codes of
+	// the same length are consecutive.
+	var (
+		sym     int
+		buf64   = binary.BigEndian.Uint64(buf)
+		bufSize = 64
+		next    = 8
+	)
+	for {
+		l := 0
+		for l < len(d.base64)-1 && buf64 < d.base64[l] {
+			l++
+		}
+		if l >= len(d.lowestSym) {
+			return 0, errors.New("invalid symbol")
+		}
+		sym = int((buf64-d.base64[l])>>(64-l-d.minSymLen)) + int(d.lowestSym[l])
+		if sym >= len(d.symLen) {
+			return 0, errors.New("invalid symbol")
+		}
+
+		if offset < d.symLen[sym]+1 {
+			break
+		}
+		offset -= d.symLen[sym] + 1
+
+		l += d.minSymLen
+		buf64 <<= l
+		bufSize -= l
+		if bufSize <= 32 {
+			if next+4 > len(buf) {
+				return 0, errors.New("block overrun")
+			}
+			bufSize += 32
+			buf64 |= uint64(binary.BigEndian.Uint32(buf[next:])) << (64 - bufSize)
+			next += 4
+		}
+	}
+
+	// Expand the symbol into the pair of symbols it stands for, until
+	// reaching a single value.
+	for d.symLen[sym] != 0 {
+		left := int(d.left[sym])
+		if offset < d.symLen[left]+1 {
+			sym = left
+		} else {
+			offset -= d.symLen[left] + 1
+			sym = int(d.right[sym])
+		}
+	}
+
+	return int(d.left[sym]), nil
+}
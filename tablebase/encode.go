@@ -0,0 +1,309 @@
+package tablebase
+
+import (
+	"slices"
+
+	"github.com/clfs/simple/core"
+)
+
+// maxPieces is the largest number of pieces in a table that can be probed.
+const maxPieces = 7
+
+// Tables used to encode positions as indices. See initEncoding.
+var (
+	mapPawns      [64]int
+	mapB1H1H7     [64]int
+	mapA1D1D4     [64]int
+	mapKK         [10][64]int
+	binomial      [maxPieces - 1][64]uint64
+	leadPawnIdx   [maxPieces - 1][64]uint64
+	leadPawnsSize [maxPieces - 1][4]uint64
+)
+
+func init() {
+	initEncoding()
+}
+
+// offA1H8 returns the distance of a square above the a1-h8 diagonal, which is
+// negative below it.
+func offA1H8(s core.Square) int {
+	return int(s.Rank()) - int(s.File())
+}
+
+func flipFile(s core.Square) core.Square { return s ^ 7 }
+func flipRank(s core.Square) core.Square { return s ^ 56 }
+
+// flipDiagonal mirrors a square in the a1-h8 diagonal.
+func flipDiagonal(s core.Square) core.Square {
+	return (s>>3 | s<<3) & 63
+}
+
+// initEncoding fills in the encoding tables.
+func initEncoding() {
+	// mapB1H1H7 maps squares below the a1-h8 diagonal to 0..27.
+	var code int
+	for s := core.A1; s <= core.H8; s++ {
+		if offA1H8(s) < 0 {
+			mapB1H1H7[s] = code
+			code++
+		}
+	}
+
+	// mapA1D1D4 maps squares in the a1-d1-d4 triangle to 0..9, with the
+	// squares on the diagonal last.
+	var diagonal []core.Square
+	code = 0
+	for _, s := range []core.Square{
+		core.A1, core.B1, core.C1, core.D1,
+		core.A2, core.B2, core.C2, core.D2,
+		core.A3, core.B3, core.C3, core.D3,
+		core.A4, core.B4, core.C4, core.D4,
+	} {
+		switch {
+		case offA1H8(s) < 0:
+			mapA1D1D4[s] = code
+			code++
+		case offA1H8(s) == 0:
+			diagonal = append(diagonal, s)
+		}
+	}
+	for _, s := range diagonal {
+		mapA1D1D4[s] = code
+		code++
+	}
+
+	// mapKK maps the 462 legal placements of two kings, where the first is
+	// in the a1-d1-d4 triangle, to 0..461. If the first king is on the a1-d4
+	// diagonal, the second isn't above the a1-h8 diagonal. Placements with
+	// both kings on the diagonal come last.
+	type placement struct {
+		idx int
+		s   core.Square
+	}
+	var bothOnDiagonal []placement
+	code = 0
+	for idx := range 10 {
+		for s1 := core.A1; s1 <= core.D4; s1++ {
+			if mapA1D1D4[s1] != idx || (idx == 0 && s1 != core.B1) {
+				continue
+			}
+			for s2 := core.A1; s2 <= core.H8; s2++ {
+				switch {
+				case kingDistance(s1, s2) <= 1:
+					// Illegal.
+				case offA1H8(s1) == 0 && offA1H8(s2) > 0:
+					// The first is on the diagonal, the second above it.
+				case offA1H8(s1) == 0 && offA1H8(s2) == 0:
+					bothOnDiagonal = append(bothOnDiagonal, placement{idx, s2})
+				default:
+					mapKK[idx][s2] = code
+					code++
+				}
+			}
+		}
+	}
+	for _, p := range bothOnDiagonal {
+		mapKK[p.idx][p.s] = code
+		code++
+	}
+
+	// binomial[k][n] is the number of ways to choose k of n elements.
+	binomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < len(binomial) && k <= n; k++ {
+			if k > 0 {
+				binomial[k][n] += binomial[k-1][n-1]
+			}
+			if k < n {
+				binomial[k][n] += binomial[k][n-1]
+			}
+		}
+	}
+
+	// mapPawns maps squares a2-h7 to 0..47, such that the pawn with the
+	// highest value is the leading pawn: the one nearest the edge, and the
+	// lowest among those on the same file. A square's value is also the
+	// number of squares left for other pawns when the leading pawn is on it.
+	available := 47
+	for leadPawns := 1; leadPawns < len(leadPawnIdx); leadPawns++ {
+		for f := core.FileA; f <= core.FileD; f++ {
+			// The index restarts for every file, since tables are split by
+			// the file of the leading pawn.
+			var idx uint64
+			for r := core.Rank2; r <= core.Rank7; r++ {
+				s := core.NewSquare(f, r)
+				if leadPawns == 1 {
+					mapPawns[s] = available
+					available--
+					mapPawns[flipFile(s)] = available
+					available--
+				}
+				leadPawnIdx[leadPawns][s] = idx
+				idx += binomial[leadPawns-1][mapPawns[s]]
+			}
+			leadPawnsSize[leadPawns][f] = idx
+		}
+	}
+}
+
+// kingDistance returns the number of king moves between two squares.
+func kingDistance(a, b core.Square) int {
+	df := int(a.File()) - int(b.File())
+	dr := int(a.Rank()) - int(b.Rank())
+	return max(df, -df, dr, -dr)
+}
+
+// comparePawns orders squares by mapPawns.
+func comparePawns(a, b core.Square) int {
+	return mapPawns[a] - mapPawns[b]
+}
+
+// A tbPiece is a piece as encoded in table files: the piece type from 1 (pawn)
+// to 6 (king), plus 8 for black.
+type tbPiece byte
+
+const tbBlack tbPiece = 8
+
+func toTBPiece(p core.Piece) tbPiece {
+	tp := tbPiece(p.Type() + 1)
+	if p.Color() == core.Black {
+		tp |= tbBlack
+	}
+	return tp
+}
+
+func (p tbPiece) isPawn() bool {
+	return p&7 == 1
+}
+
+// leadingPawns moves the square of the leading pawn to the front of squares,
+// and returns its file mirrored to files a-d. Tables with pawns are split by
+// that file.
+func leadingPawns(squares []core.Square) core.File {
+	i := 0
+	for j := range squares {
+		if comparePawns(squares[j], squares[i]) > 0 {
+			i = j
+		}
+	}
+	squares[0], squares[i] = squares[i], squares[0]
+
+	f := squares[0].File()
+	return min(f, core.FileH-f)
+}
+
+// encode returns the index of a position in a table. The pieces and squares
+// are ordered to match d.pieces, with the leading pawns, if any, first.
+func encode(d *pairsData, hasPawns, hasUniquePieces, remainingPawns bool, leadPawns int, squares []core.Square) uint64 {
+	var idx uint64
+
+	// Mirror the squares so that the leading piece is in the a1-d1-d4
+	// triangle.
+	if squares[0].File() > core.FileD {
+		for i := range squares {
+			squares[i] = flipFile(squares[i])
+		}
+	}
+
+	if hasPawns {
+		// Encode the leading pawns in ascending order of mapPawns.
+		idx = leadPawnIdx[leadPawns][squares[0]]
+		slices.SortStableFunc(squares[1:leadPawns], comparePawns)
+		for i := 1; i < leadPawns; i++ {
+			idx += binomial[i][mapPawns[squares[i]]]
+		}
+	} else {
+		// Without pawns, also mirror the leading piece below rank 5.
+		if squares[0].Rank() > core.Rank4 {
+			for i := range squares {
+				squares[i] = flipRank(squares[i])
+			}
+		}
+
+		// Mirror the first piece of the leading group that isn't on the
+		// a1-h8 diagonal to below it.
+		for i := range d.groupLen[0] {
+			if offA1H8(squares[i]) == 0 {
+				continue
+			}
+			if offA1H8(squares[i]) > 0 {
+				for j := i; j < len(squares); j++ {
+					squares[j] = flipDiagonal(squares[j])
+				}
+			}
+			break
+		}
+
+		if hasUniquePieces {
+			idx = encodeUnique(squares)
+		} else {
+			idx = uint64(mapKK[mapA1D1D4[squares[0]]][squares[1]])
+		}
+	}
+
+	idx *= d.groupIdx[0]
+
+	// Encode the remaining groups, with the squares of each group in
+	// ascending order and mapped down past the squares of earlier groups.
+	start := d.groupLen[0]
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[start : start+d.groupLen[next]]
+		slices.Sort(group)
+
+		var n uint64
+		for i, s := range group {
+			adjust := 0
+			for _, prev := range squares[:start] {
+				if s > prev {
+					adjust++
+				}
+			}
+			sq := int(s) - adjust
+			if remainingPawns {
+				sq -= 8
+			}
+			n += binomial[i+1][sq]
+		}
+
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		start += d.groupLen[next]
+	}
+
+	return idx
+}
+
+// encodeUnique encodes the leading group of three unique pieces, like the
+// kings and the rook in KRvK, without pawns.
+func encodeUnique(squares []core.Square) uint64 {
+	var adjust1, adjust2 int
+	if squares[1] > squares[0] {
+		adjust1++
+	}
+	if squares[2] > squares[0] {
+		adjust2++
+	}
+	if squares[2] > squares[1] {
+		adjust2++
+	}
+
+	s0, s1, s2 := squares[0], squares[1], squares[2]
+	r0, r1, r2 := int(s0.Rank()), int(s1.Rank()), int(s2.Rank())
+
+	var idx int
+	switch {
+	case offA1H8(s0) != 0:
+		// The first piece is below the diagonal.
+		idx = (mapA1D1D4[s0]*63+int(s1)-adjust1)*62 + int(s2) - adjust2
+	case offA1H8(s1) != 0:
+		// The first piece is on the diagonal, and the second below it.
+		idx = (6*63+r0*28+mapB1H1H7[s1])*62 + int(s2) - adjust2
+	case offA1H8(s2) != 0:
+		// The first two pieces are on the diagonal, and the third below it.
+		idx = 6*63*62 + 4*28*62 + r0*7*28 + (r1-adjust1)*28 + mapB1H1H7[s2]
+	default:
+		// All three pieces are on the diagonal.
+		idx = 6*63*62 + 4*28*62 + 4*7*28 + r0*7*6 + (r1-adjust1)*6 + (r2 - adjust2)
+	}
+	return uint64(idx)
+}
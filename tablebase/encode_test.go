@@ -0,0 +1,182 @@
+package tablebase
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/clfs/simple/core"
+)
+
+// symmetries are the transformations of the board that tables without pawns
+// treat as equivalent.
+var symmetries = []func(core.Square) core.Square{
+	func(s core.Square) core.Square { return s },
+	flipFile,
+	flipRank,
+	flipDiagonal,
+	func(s core.Square) core.Square { return flipFile(flipRank(s)) },
+	func(s core.Square) core.Square { return flipDiagonal(flipFile(s)) },
+	func(s core.Square) core.Square { return flipDiagonal(flipRank(s)) },
+	func(s core.Square) core.Square { return flipDiagonal(flipFile(flipRank(s))) },
+}
+
+func transform(squares []core.Square, f func(core.Square) core.Square) []core.Square {
+	out := make([]core.Square, len(squares))
+	for i, s := range squares {
+		out[i] = f(s)
+	}
+	return out
+}
+
+func TestMapPawns(t *testing.T) {
+	var seen [48]bool
+	for s := core.A2; s <= core.H7; s++ {
+		v := mapPawns[s]
+		if v < 0 || v >= 48 || seen[v] {
+			t.Fatalf("mapPawns[%s] = %d is out of range or repeated", s, v)
+		}
+		seen[v] = true
+	}
+
+	// The leading pawn is the one nearest the edge, then the lowest.
+	if mapPawns[core.A2] != 47 || mapPawns[core.H2] != 46 || mapPawns[core.A3] != 45 {
+		t.Errorf("got a2 %d, h2 %d, a3 %d, want 47, 46, 45",
+			mapPawns[core.A2], mapPawns[core.H2], mapPawns[core.A3])
+	}
+}
+
+func TestBinomial(t *testing.T) {
+	cases := []struct {
+		k, n int
+		want uint64
+	}{
+		{0, 0, 1},
+		{1, 63, 63},
+		{2, 62, 1891},
+		{3, 48, 17296},
+		{5, 63, 7028847},
+	}
+	for _, tc := range cases {
+		if got := binomial[tc.k][tc.n]; got != tc.want {
+			t.Errorf("binomial[%d][%d] = %d, want %d", tc.k, tc.n, got, tc.want)
+		}
+	}
+}
+
+// TestEncode_Kings checks that the 462 ways to place two kings, up to
+// symmetry, are encoded to distinct indices.
+func TestEncode_Kings(t *testing.T) {
+	d := &pairsData{groupLen: [maxPieces + 1]int{2}, groupIdx: [maxPieces + 1]uint64{1, 462}}
+
+	seen := make(map[uint64]bool)
+	for a := core.A1; a <= core.H8; a++ {
+		for b := core.A1; b <= core.H8; b++ {
+			if kingDistance(a, b) <= 1 {
+				continue
+			}
+
+			want := encode(d, false, false, false, 0, []core.Square{a, b})
+			if want >= 462 {
+				t.Fatalf("%s %s: got index %d, want less than 462", a, b, want)
+			}
+			seen[want] = true
+
+			for i, f := range symmetries {
+				if got := encode(d, false, false, false, 0, transform([]core.Square{a, b}, f)); got != want {
+					t.Fatalf("%s %s: symmetry %d: got index %d, want %d", a, b, i, got, want)
+				}
+			}
+		}
+	}
+
+	if len(seen) != 462 {
+		t.Errorf("got %d distinct indices, want 462", len(seen))
+	}
+}
+
+// TestEncode_Unique checks that placements of three unique pieces are encoded
+// to one index per placement up to symmetry.
+func TestEncode_Unique(t *testing.T) {
+	d := &pairsData{groupLen: [maxPieces + 1]int{3}, groupIdx: [maxPieces + 1]uint64{1, 31332}}
+
+	var (
+		indices = make(map[uint64]bool)
+		classes = make(map[[3]core.Square]bool)
+	)
+	for a := core.A1; a <= core.H8; a++ {
+		for b := core.A1; b <= core.H8; b++ {
+			for c := core.A1; c <= core.H8; c++ {
+				if a == b || a == c || b == c {
+					continue
+				}
+				squares := []core.Square{a, b, c}
+
+				want := encode(d, false, true, false, 0, slices.Clone(squares))
+				if want >= 31332 {
+					t.Fatalf("%v: got index %d, want less than 31332", squares, want)
+				}
+				indices[want] = true
+
+				// The class of a placement is its smallest transformation.
+				var class [3]core.Square
+				for i, f := range symmetries {
+					moved := transform(squares, f)
+					if got := encode(d, false, true, false, 0, slices.Clone(moved)); got != want {
+						t.Fatalf("%v: symmetry %d: got index %d, want %d", squares, i, got, want)
+					}
+					if i == 0 || slices.Compare(moved, class[:]) < 0 {
+						class = [3]core.Square(moved)
+					}
+				}
+				classes[class] = true
+			}
+		}
+	}
+
+	if len(indices) != len(classes) {
+		t.Errorf("got %d distinct indices for %d placements", len(indices), len(classes))
+	}
+}
+
+// TestEncode_Pawns checks KPvK placements with the pawn on files a-d, which
+// are all distinct, and their mirror images.
+func TestEncode_Pawns(t *testing.T) {
+	tbl, err := newTable(wdlTable, "KPvK", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for f := core.FileA; f <= core.FileD; f++ {
+		d := tbl.get(0, f)
+		d.pieces = [maxPieces]tbPiece{1, 6, 14}
+		tbl.setGroups(d, [2]int{0, 0xf}, f)
+
+		seen := make(map[uint64]bool)
+		for r := core.Rank2; r <= core.Rank7; r++ {
+			pawn := core.NewSquare(f, r)
+			for a := core.A1; a <= core.H8; a++ {
+				for b := core.A1; b <= core.H8; b++ {
+					if a == b || a == pawn || b == pawn {
+						continue
+					}
+
+					squares := []core.Square{pawn, a, b}
+					if got := leadingPawns(squares[:1]); got != f {
+						t.Fatalf("%v: got file %s, want %s", squares, got, f)
+					}
+
+					want := encode(d, true, true, false, 1, slices.Clone(squares))
+					if want >= d.size() || seen[want] {
+						t.Fatalf("%v: got index %d, which is out of range or repeated", squares, want)
+					}
+					seen[want] = true
+
+					mirrored := transform(squares, flipFile)
+					if got := encode(d, true, true, false, 1, mirrored); got != want {
+						t.Fatalf("%v: mirrored: got index %d, want %d", squares, got, want)
+					}
+				}
+			}
+		}
+	}
+}
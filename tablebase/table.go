@@ -0,0 +1,503 @@
+package tablebase
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/clfs/simple/core"
+)
+
+// A tableType is the type of a table.
+type tableType int
+
+const (
+	wdlTable tableType = iota
+	dtzTable
+)
+
+// File name extensions and magic numbers of each table type.
+var (
+	extensions = [...]string{wdlTable: ".rtbw", dtzTable: ".rtbz"}
+	magics     = [...][4]byte{
+		wdlTable: {0x71, 0xe8, 0x23, 0x5d},
+		dtzTable: {0xd7, 0x66, 0x0c, 0xa5},
+	}
+)
+
+// Table flags.
+const (
+	flagSTM         = 1 // DTZ: the side to move the table is for.
+	flagMapped      = 2 // DTZ: values are mapped through a map.
+	flagWinPlies    = 4 // DTZ: wins are in plies rather than moves.
+	flagLossPlies   = 8 // DTZ: losses are in plies rather than moves.
+	flagWide        = 16
+	flagSingleValue = 128 // Every position has the same value.
+)
+
+// pairsData describes the compressed data of a table for one side to move and,
+// in tables with pawns, one file of the leading pawn.
+type pairsData struct {
+	flags     byte
+	minSymLen int
+	maxSymLen int
+	numBlocks int
+	blockSize int64
+	span      uint64
+
+	lowestSym   []uint16 // Symbol of each length with the lowest value.
+	base64      []uint64 // Lowest symbol of each length, left aligned.
+	symLen      []int    // Number of values, less one, each symbol expands to.
+	left, right []uint16 // Symbols each symbol expands to.
+
+	sparseIndex []sparseEntry
+	blockLength []uint16 // Number of values, less one, in each block.
+	data        int64    // Offset of the first block in the file.
+
+	pieces   [maxPieces]tbPiece
+	groupIdx [maxPieces + 1]uint64
+	groupLen [maxPieces + 1]int
+	mapIdx   [4]int // DTZ: offsets into the map for each WDL result.
+}
+
+// A sparseEntry points to the block and offset within the block of every
+// span-th value in a table.
+type sparseEntry struct {
+	block  uint32
+	offset uint16
+}
+
+// A table is a WDL or DTZ table for a material signature.
+type table struct {
+	typ  tableType
+	name string // Material signature with white as the stronger side, like "KRvK".
+	path string
+
+	key, key2       string // Signatures with white and black as the stronger side.
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	pawnCount       [2]int // Pawns of the leading color, then of the other.
+
+	once sync.Once
+	err  error
+	f    *os.File
+	pd   [2][4]pairsData // By side to move and file.
+	dmap []byte          // DTZ: maps stored values to distances.
+}
+
+// newTable returns a table for a material signature. The file isn't read
+// until the table is first probed.
+func newTable(typ tableType, name, path string) (*table, error) {
+	white, black, ok := strings.Cut(name, "v")
+	if !ok || !validSide(white) || !validSide(black) {
+		return nil, fmt.Errorf("invalid table name: %s", name)
+	}
+
+	t := &table{
+		typ:        typ,
+		name:       name,
+		path:       path,
+		key:        name,
+		key2:       black + "v" + white,
+		pieceCount: len(white) + len(black),
+	}
+
+	for _, side := range []string{white, black} {
+		for _, c := range "QRBNP" {
+			if strings.Count(side, string(c)) == 1 {
+				t.hasUniquePieces = true
+			}
+		}
+	}
+
+	// The leading color is the side with fewer pawns, or white if they have
+	// the same number.
+	wp, bp := strings.Count(white, "P"), strings.Count(black, "P")
+	t.hasPawns = wp+bp > 0
+	if bp == 0 || (wp > 0 && bp >= wp) {
+		t.pawnCount = [2]int{wp, bp}
+	} else {
+		t.pawnCount = [2]int{bp, wp}
+	}
+
+	return t, nil
+}
+
+// validSide reports whether s is a valid list of pieces for one side.
+func validSide(s string) bool {
+	if !strings.HasPrefix(s, "K") || strings.Count(s, "K") != 1 {
+		return false
+	}
+	return strings.Trim(s, "KQRBNP") == ""
+}
+
+// get returns the pairs data for a side to move and file.
+func (t *table) get(stm int, f core.File) *pairsData {
+	if t.typ == dtzTable {
+		stm = 0
+	}
+	if !t.hasPawns {
+		f = 0
+	}
+	return &t.pd[stm][f]
+}
+
+// load opens and parses the table file, once.
+func (t *table) load() error {
+	t.once.Do(func() {
+		t.err = t.open()
+		if t.err != nil {
+			t.err = fmt.Errorf("%s: %v", t.path, t.err)
+		}
+	})
+	return t.err
+}
+
+func (t *table) open() error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	if err := t.parse(f); err != nil {
+		f.Close()
+		return err
+	}
+	t.f = f
+	return nil
+}
+
+// close closes the table file, if it's open.
+func (t *table) close() error {
+	if t.f == nil {
+		return nil
+	}
+	return t.f.Close()
+}
+
+// A cursor reads a table file sequentially.
+type cursor struct {
+	r   io.ReaderAt
+	off int64
+	err error
+}
+
+func (c *cursor) bytes(n int) []byte {
+	b := make([]byte, n)
+	if c.err != nil || n == 0 {
+		return b
+	}
+	if _, err := c.r.ReadAt(b, c.off); err != nil {
+		if err == io.EOF {
+			err = errors.New("unexpected end of file")
+		}
+		c.err = err
+	}
+	c.off += int64(n)
+	return b
+}
+
+func (c *cursor) u8() byte      { return c.bytes(1)[0] }
+func (c *cursor) u16() uint16   { return binary.LittleEndian.Uint16(c.bytes(2)) }
+func (c *cursor) u32() uint32   { return binary.LittleEndian.Uint32(c.bytes(4)) }
+func (c *cursor) align(n int64) { c.off = (c.off + n - 1) &^ (n - 1) }
+func (c *cursor) skip(n int64)  { c.off += n }
+
+// Header flags.
+const (
+	headerSplit    = 1 // The table has data for both sides to move.
+	headerHasPawns = 2
+)
+
+// parse parses the table header, leaving the compressed data in the file.
+func (t *table) parse(r io.ReaderAt) error {
+	c := &cursor{r: r}
+
+	if magic := c.bytes(4); c.err == nil && [4]byte(magic) != magics[t.typ] {
+		return errors.New("invalid magic number")
+	}
+
+	header := c.u8()
+	if c.err != nil {
+		return c.err
+	}
+	if t.hasPawns != (header&headerHasPawns != 0) {
+		return errors.New("header doesn't match the material signature")
+	}
+	if t.typ == wdlTable && (t.key != t.key2) != (header&headerSplit != 0) {
+		return errors.New("header doesn't match the material signature")
+	}
+
+	sides := 1
+	if t.typ == wdlTable && t.key != t.key2 {
+		sides = 2
+	}
+	maxFile := core.FileA
+	if t.hasPawns {
+		maxFile = core.FileD
+	}
+	pp := t.hasPawns && t.pawnCount[1] > 0 // Pawns on both sides.
+
+	for f := core.FileA; f <= maxFile; f++ {
+		b := c.u8()
+		order := [2][2]int{{int(b & 0xf), 0xf}, {int(b >> 4), 0xf}}
+		if pp {
+			b := c.u8()
+			order[0][1], order[1][1] = int(b&0xf), int(b>>4)
+		}
+
+		for k := range t.pieceCount {
+			b := c.u8()
+			for i := range sides {
+				p := tbPiece(b & 0xf)
+				if i > 0 {
+					p = tbPiece(b >> 4)
+				}
+				t.get(i, f).pieces[k] = p
+			}
+		}
+
+		for i := range sides {
+			t.setGroups(t.get(i, f), order[i], f)
+		}
+	}
+
+	c.align(2)
+
+	for f := core.FileA; f <= maxFile; f++ {
+		for i := range sides {
+			setSizes(c, t.get(i, f))
+		}
+	}
+
+	if t.typ == dtzTable {
+		t.setDTZMap(c, maxFile)
+	}
+
+	for f := core.FileA; f <= maxFile; f++ {
+		for i := range sides {
+			d := t.get(i, f)
+			d.sparseIndex = make([]sparseEntry, len(d.sparseIndex))
+			for j := range d.sparseIndex {
+				d.sparseIndex[j] = sparseEntry{block: c.u32(), offset: c.u16()}
+			}
+		}
+	}
+
+	for f := core.FileA; f <= maxFile; f++ {
+		for i := range sides {
+			d := t.get(i, f)
+			for j := range d.blockLength {
+				d.blockLength[j] = c.u16()
+			}
+		}
+	}
+
+	for f := core.FileA; f <= maxFile; f++ {
+		for i := range sides {
+			d := t.get(i, f)
+			c.align(64)
+			d.data = c.off
+			c.skip(int64(d.numBlocks) * d.blockSize)
+		}
+	}
+
+	return c.err
+}
+
+// setGroups splits the pieces of a table into groups, which are encoded
+// separately, and computes the index multiplier of each group.
+func (t *table) setGroups(d *pairsData, order [2]int, f core.File) {
+	// The first group is the leading pawns, or the kings and another unique
+	// piece, or the kings.
+	firstLen := 2
+	switch {
+	case t.hasPawns:
+		firstLen = 0
+	case t.hasUniquePieces:
+		firstLen = 3
+	}
+
+	// Pieces of the same kind, which come together in d.pieces, form the
+	// other groups.
+	n := 0
+	d.groupLen[n] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0
+
+	// The groups are combined in the order given by the table. The leading
+	// group is at order[0], and the remaining pawns, if both sides have
+	// pawns, at order[1].
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next := 1
+	freeSquares := 64 - d.groupLen[0]
+	if pp {
+		next = 2
+		freeSquares -= d.groupLen[1]
+	}
+
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		switch {
+		case k == order[0]:
+			d.groupIdx[0] = idx
+			switch {
+			case t.hasPawns:
+				idx *= leadPawnsSize[d.groupLen[0]][f]
+			case t.hasUniquePieces:
+				idx *= 31332
+			default:
+				idx *= 462
+			}
+		case k == order[1]:
+			d.groupIdx[1] = idx
+			idx *= binomial[d.groupLen[1]][48-d.groupLen[0]]
+		default:
+			d.groupIdx[next] = idx
+			idx *= binomial[d.groupLen[next]][freeSquares]
+			freeSquares -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+}
+
+// size returns the number of positions in the pairs data.
+func (d *pairsData) size() uint64 {
+	n := 0
+	for d.groupLen[n] != 0 {
+		n++
+	}
+	return d.groupIdx[n]
+}
+
+// setSizes reads the sizes and the Huffman code of compressed data.
+func setSizes(c *cursor, d *pairsData) {
+	d.flags = c.u8()
+	if d.flags&flagSingleValue != 0 {
+		d.minSymLen = int(c.u8()) // The single value.
+		return
+	}
+
+	d.blockSize = 1 << c.u8()
+	d.span = 1 << c.u8()
+	padding := int(c.u8())
+	d.numBlocks = int(c.u32())
+	d.maxSymLen = int(c.u8())
+	d.minSymLen = int(c.u8())
+	if c.err != nil || d.maxSymLen < d.minSymLen || d.maxSymLen > 64 || d.span == 0 {
+		if c.err == nil {
+			c.err = errors.New("invalid symbol lengths")
+		}
+		return
+	}
+
+	d.sparseIndex = make([]sparseEntry, (d.size()+d.span-1)/d.span)
+	d.blockLength = make([]uint16, d.numBlocks+padding)
+
+	// Canonical Huffman codes are ordered so that longer codes have lower
+	// values. base64[i] is the lowest code of length minSymLen+i, left
+	// aligned in 64 bits.
+	n := d.maxSymLen - d.minSymLen + 1
+	d.lowestSym = make([]uint16, n)
+	for i := range d.lowestSym {
+		d.lowestSym[i] = c.u16()
+	}
+	d.base64 = make([]uint64, n)
+	for i := n - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + uint64(d.lowestSym[i]) - uint64(d.lowestSym[i+1])) / 2
+	}
+	for i := range d.base64 {
+		d.base64[i] <<= 64 - i - d.minSymLen
+	}
+
+	// The data is compressed by recursive pairing: each symbol is either a
+	// value, or a pair of other symbols.
+	numSyms := int(c.u16())
+	d.left = make([]uint16, numSyms)
+	d.right = make([]uint16, numSyms)
+	for i := range numSyms {
+		b := c.bytes(3)
+		d.left[i] = uint16(b[1]&0xf)<<8 | uint16(b[0])
+		d.right[i] = uint16(b[2])<<4 | uint16(b[1]>>4)
+	}
+	if numSyms%2 == 1 {
+		c.skip(1)
+	}
+	if c.err != nil {
+		return
+	}
+
+	d.symLen = make([]int, numSyms)
+	visited := make([]bool, numSyms)
+	for s := range numSyms {
+		if !visited[s] {
+			d.symLen[s] = d.setSymLen(s, visited)
+		}
+	}
+}
+
+// leafSym marks symbols that are values rather than pairs.
+const leafSym = 0xfff
+
+// setSymLen returns the number of values, less one, a symbol expands to.
+func (d *pairsData) setSymLen(s int, visited []bool) int {
+	visited[s] = true
+	r := int(d.right[s])
+	if r == leafSym {
+		return 0
+	}
+	l := int(d.left[s])
+	if l >= len(d.symLen) || r >= len(d.symLen) {
+		return 0
+	}
+	if !visited[l] {
+		d.symLen[l] = d.setSymLen(l, visited)
+	}
+	if !visited[r] {
+		d.symLen[r] = d.setSymLen(r, visited)
+	}
+	return d.symLen[l] + d.symLen[r] + 1
+}
+
+// setDTZMap reads the maps from stored DTZ values to distances.
+func (t *table) setDTZMap(c *cursor, maxFile core.File) {
+	start := c.off
+
+	for f := core.FileA; f <= maxFile; f++ {
+		d := t.get(0, f)
+		if d.flags&flagMapped == 0 {
+			continue
+		}
+		if d.flags&flagWide != 0 {
+			c.align(2)
+			for i := range d.mapIdx {
+				d.mapIdx[i] = int((c.off-start)/2) + 1
+				c.skip(2 * int64(c.u16()))
+			}
+		} else {
+			for i := range d.mapIdx {
+				d.mapIdx[i] = int(c.off-start) + 1
+				c.skip(int64(c.u8()))
+			}
+		}
+	}
+
+	end := c.off
+	c.off = start
+	t.dmap = c.bytes(int(end - start))
+	c.align(2)
+}
@@ -0,0 +1,162 @@
+package tablebase
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewTable(t *testing.T) {
+	cases := []struct {
+		name        string
+		key2        string
+		pieceCount  int
+		hasPawns    bool
+		hasUnique   bool
+		pawnCount   [2]int
+		wantInvalid bool
+	}{
+		{name: "KQvK", key2: "KvKQ", pieceCount: 3, hasUnique: true},
+		{name: "KRRvKR", key2: "KRvKRR", pieceCount: 5, hasUnique: true},
+		{name: "KRRvKBB", key2: "KBBvKRR", pieceCount: 6},
+		{name: "KPvK", key2: "KvKP", pieceCount: 3, hasPawns: true, hasUnique: true, pawnCount: [2]int{1, 0}},
+		{name: "KPPvKP", key2: "KPvKPP", pieceCount: 5, hasPawns: true, hasUnique: true, pawnCount: [2]int{1, 2}},
+		{name: "KvK", key2: "KvK", pieceCount: 2},
+		{name: "KQK", wantInvalid: true},
+		{name: "QvK", wantInvalid: true},
+		{name: "KXvK", wantInvalid: true},
+	}
+
+	for _, tc := range cases {
+		tbl, err := newTable(wdlTable, tc.name, "")
+		if tc.wantInvalid {
+			if err == nil {
+				t.Errorf("%s: got no error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+
+		if tbl.key2 != tc.key2 || tbl.pieceCount != tc.pieceCount || tbl.hasPawns != tc.hasPawns ||
+			tbl.hasUniquePieces != tc.hasUnique || tbl.pawnCount != tc.pawnCount {
+			t.Errorf("%s: got key2 %s, %d pieces, pawns %t, unique %t, pawn count %v",
+				tc.name, tbl.key2, tbl.pieceCount, tbl.hasPawns, tbl.hasUniquePieces, tbl.pawnCount)
+		}
+	}
+}
+
+// decodeAll decodes compressed data block by block, reading codes a bit at a
+// time.
+func decodeAll(r io.ReaderAt, d *pairsData) ([]int, error) {
+	var (
+		values []int
+		expand func(sym int)
+	)
+	expand = func(sym int) {
+		if d.symLen[sym] == 0 {
+			values = append(values, int(d.left[sym]))
+			return
+		}
+		expand(int(d.left[sym]))
+		expand(int(d.right[sym]))
+	}
+
+	for block := range d.numBlocks {
+		buf := make([]byte, d.blockSize)
+		if _, err := r.ReadAt(buf, d.data+int64(block)*d.blockSize); err != nil {
+			return nil, err
+		}
+
+		end := len(values) + int(d.blockLength[block]) + 1
+		for bit := 0; len(values) < end; {
+			var code uint64
+			for l := 1; ; l++ {
+				if bit >= len(buf)*8 {
+					return nil, errors.New("block overrun")
+				}
+				code = code<<1 | uint64(buf[bit/8]>>(7-bit%8)&1)
+				bit++
+
+				i := l - d.minSymLen
+				if i < 0 || i >= len(d.base64) {
+					continue
+				}
+				if base := d.base64[i] >> (64 - l); code >= base {
+					expand(int(code-base) + int(d.lowestSym[i]))
+					break
+				}
+			}
+		}
+	}
+	return values, nil
+}
+
+// TestDecompress checks that reading values anywhere in the tables in testdata
+// matches decoding them in order.
+func TestDecompress(t *testing.T) {
+	openTestdata(t)
+
+	cases := []struct {
+		typ  tableType
+		name string
+	}{
+		{wdlTable, "KQvK"},
+		{wdlTable, "KPvK"},
+		{dtzTable, "KRvK"},
+		{dtzTable, "KPvK"},
+	}
+
+	for _, tc := range cases {
+		path := filepath.Join("testdata", tc.name+extensions[tc.typ])
+		tbl, err := newTable(tc.typ, tc.name, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tbl.load(); err != nil {
+			t.Fatal(err)
+		}
+		defer tbl.close()
+
+		for _, sides := range tbl.pd {
+			for _, d := range sides {
+				if d.flags&flagSingleValue != 0 || d.numBlocks == 0 {
+					continue
+				}
+
+				want, err := decodeAll(tbl.f, &d)
+				if err != nil {
+					t.Fatalf("%s: %v", path, err)
+				}
+				if uint64(len(want)) < d.size() {
+					t.Fatalf("%s: got %d values, want at least %d", path, len(want), d.size())
+				}
+
+				for idx := range d.size() {
+					got, err := decompress(tbl.f, &d, idx)
+					if err != nil {
+						t.Fatalf("%s: decompress(%d) error: %v", path, idx, err)
+					}
+					if got != want[idx] {
+						t.Fatalf("%s: decompress(%d) = %d, want %d", path, idx, got, want[idx])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestDecompress_SingleValue(t *testing.T) {
+	d := new(pairsData)
+	c := &cursor{r: bytes.NewReader([]byte{flagSingleValue, 7})}
+	setSizes(c, d)
+
+	v, err := decompress(nil, d, 12345)
+	if err != nil || v != 7 {
+		t.Errorf("got %d, %v, want 7, nil", v, err)
+	}
+}
@@ -0,0 +1,557 @@
+// Package tablebase implements probing of Syzygy endgame tablebases.
+//
+// WDL tables (.rtbw) store whether a position is won, drawn or lost, and DTZ
+// tables (.rtbz) store the distance to the next capture or pawn move, which
+// resets the fifty-move counter, along an optimal line. Tables with up to 7
+// pieces can be probed. Positions with castling rights are never in tables.
+package tablebase
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/movegen"
+)
+
+// A WDL is the result of a position for the side to move, assuming optimal
+// play, with the fifty-move rule.
+type WDL int
+
+// WDL constants.
+const (
+	Loss        WDL = -2 // Lost.
+	BlessedLoss WDL = -1 // Lost, but drawn by the fifty-move rule.
+	Draw        WDL = 0
+	CursedWin   WDL = 1 // Won, but drawn by the fifty-move rule.
+	Win         WDL = 2 // Won.
+)
+
+func (w WDL) String() string {
+	switch w {
+	case Loss:
+		return "loss"
+	case BlessedLoss:
+		return "blessed loss"
+	case Draw:
+		return "draw"
+	case CursedWin:
+		return "cursed win"
+	case Win:
+		return "win"
+	default:
+		return fmt.Sprintf("WDL(%d)", int(w))
+	}
+}
+
+// Errors returned when probing.
+var (
+	ErrNotFound = errors.New("table not found")
+	ErrCastling = errors.New("position has castling rights")
+)
+
+// A Tablebase is a set of tables. It's safe for concurrent use.
+type Tablebase struct {
+	tables    [2]map[string]*table // By type, then by both material keys.
+	maxPieces int
+}
+
+// Open opens the tables in a list of directories, separated by
+// os.PathListSeparator. Table files are read when they're first probed.
+func Open(path string) (*Tablebase, error) {
+	tb := &Tablebase{
+		tables: [2]map[string]*table{
+			wdlTable: make(map[string]*table),
+			dtzTable: make(map[string]*table),
+		},
+	}
+
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			for typ, ext := range extensions {
+				name, ok := strings.CutSuffix(e.Name(), ext)
+				if !ok || e.IsDir() {
+					continue
+				}
+				t, err := newTable(tableType(typ), name, filepath.Join(dir, e.Name()))
+				if err != nil || t.pieceCount > maxPieces {
+					continue // Not a table.
+				}
+				tb.tables[typ][t.key] = t
+				tb.tables[typ][t.key2] = t
+				if typ == int(wdlTable) {
+					tb.maxPieces = max(tb.maxPieces, t.pieceCount)
+				}
+			}
+		}
+	}
+
+	return tb, nil
+}
+
+// MaxPieces returns the largest number of pieces in a WDL table, or zero if
+// there are none.
+func (tb *Tablebase) MaxPieces() int {
+	return tb.maxPieces
+}
+
+// Close closes the table files.
+func (tb *Tablebase) Close() error {
+	var errs []error
+	for _, tables := range tb.tables {
+		for key, t := range tables {
+			if key == t.key {
+				errs = append(errs, t.close())
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ProbeWDL returns the result of a position, assuming the fifty-move counter
+// is zero.
+func (tb *Tablebase) ProbeWDL(p core.Position) (WDL, error) {
+	if hasCastlingRights(p) {
+		return Draw, ErrCastling
+	}
+	wdl, _, err := tb.search(p, false)
+	return wdl, err
+}
+
+// ProbeDTZ returns the number of plies to the next capture or pawn move along
+// an optimal line, assuming the fifty-move counter is zero. It's positive if
+// the side to move wins, negative if it loses, and zero in a draw. Wins and
+// losses that are drawn by the fifty-move rule are offset by 100.
+//
+// The value may be one ply too high, as tables round some distances up to
+// whole moves.
+func (tb *Tablebase) ProbeDTZ(p core.Position) (int, error) {
+	if hasCastlingRights(p) {
+		return 0, ErrCastling
+	}
+	return tb.probeDTZ(p)
+}
+
+// RootMoves returns the moves among moves that preserve the result of a
+// position, taking the fifty-move counter into account. When winning, those
+// are the moves that win in time; when drawing, the moves that draw. When
+// losing, all moves are kept unless the fifty-move rule is near, in which case
+// only the moves that lose slowest are.
+func (tb *Tablebase) RootMoves(p core.Position, moves []core.Move) ([]core.Move, error) {
+	dtz, err := tb.ProbeDTZ(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// Score each move by the DTZ of the position after it, from the point of
+	// view of the side to move.
+	scores := make([]int, len(moves))
+	for i, m := range moves {
+		child := p
+		child.Make(m)
+
+		var v int
+		switch {
+		case dtz > 0 && movegen.InCheck(child) && len(movegen.LegalMoves(child)) == 0:
+			v = 1 // Checkmate.
+		case child.HalfMoveClock != 0:
+			v, err = tb.probeDTZ(child)
+			v = -v
+			switch {
+			case v > 0:
+				v++
+			case v < 0:
+				v--
+			}
+		default:
+			var wdl WDL
+			wdl, _, err = tb.search(child, false)
+			v = -dtzBeforeZeroing(wdl)
+		}
+		if err != nil {
+			return nil, err
+		}
+		scores[i] = v
+	}
+
+	var kept []core.Move
+	switch {
+	case dtz > 0:
+		// Keep the winning moves that stay within the fifty-move rule, or
+		// only the fastest ones if none do.
+		best := 0xffff
+		for _, v := range scores {
+			if v > 0 {
+				best = min(best, v)
+			}
+		}
+		limit := best
+		if best+p.HalfMoveClock <= 99 {
+			limit = 99 - p.HalfMoveClock
+		}
+		for i, v := range scores {
+			if v > 0 && v <= limit {
+				kept = append(kept, moves[i])
+			}
+		}
+	case dtz < 0:
+		best := 0
+		for _, v := range scores {
+			best = min(best, v)
+		}
+		if -best*2+p.HalfMoveClock < 100 {
+			return moves, nil
+		}
+		for i, v := range scores {
+			if v == best {
+				kept = append(kept, moves[i])
+			}
+		}
+	default:
+		for i, v := range scores {
+			if v == 0 {
+				kept = append(kept, moves[i])
+			}
+		}
+	}
+
+	if len(kept) == 0 {
+		return moves, nil
+	}
+	return kept, nil
+}
+
+// search returns the result of a position. It searches captures first, and
+// also pawn moves if zeroing is true, since tables may store a "don't care"
+// value when the best move is one of them, and don't account for en passant.
+// It also returns whether the best move is a capture or pawn move.
+func (tb *Tablebase) search(p core.Position, zeroing bool) (WDL, bool, error) {
+	var (
+		best  = Loss
+		moves = movegen.LegalMoves(p)
+		count int
+	)
+
+	for _, m := range moves {
+		if !isCapture(p, m) && (!zeroing || !isPawnMove(p, m)) {
+			continue
+		}
+		count++
+
+		child := p
+		child.Make(m)
+		v, _, err := tb.search(child, false)
+		if err != nil {
+			return Draw, false, err
+		}
+		v = -v
+
+		if v > best {
+			best = v
+			if v >= Win {
+				return v, true, nil
+			}
+		}
+	}
+
+	// If every legal move was searched, the table isn't needed, and may be
+	// wrong if en passant is possible.
+	noMoreMoves := count > 0 && count == len(moves)
+
+	v := best
+	if !noMoreMoves {
+		n, _, err := tb.probeTable(p, wdlTable, Draw)
+		if err != nil {
+			return Draw, false, err
+		}
+		v = WDL(n)
+	}
+
+	if best >= v {
+		return best, best > Draw || noMoreMoves, nil
+	}
+	return v, false, nil
+}
+
+// probeDTZ implements ProbeDTZ.
+func (tb *Tablebase) probeDTZ(p core.Position) (int, error) {
+	wdl, zeroingBest, err := tb.search(p, true)
+	if err != nil || wdl == Draw {
+		return 0, err
+	}
+	if zeroingBest {
+		return dtzBeforeZeroing(wdl), nil
+	}
+
+	dtz, changeSTM, err := tb.probeTable(p, dtzTable, wdl)
+	if err != nil {
+		return 0, err
+	}
+	if !changeSTM {
+		if wdl == BlessedLoss || wdl == CursedWin {
+			dtz += 100
+		}
+		return dtz * sign(int(wdl)), nil
+	}
+
+	// The table only has the other side to move, so search one ply for the
+	// best move.
+	minDTZ := 0xffff
+	for _, m := range movegen.LegalMoves(p) {
+		zeroing := isCapture(p, m) || isPawnMove(p, m)
+
+		child := p
+		child.Make(m)
+
+		// For zeroing moves, the distance is known from the result of the
+		// position after it.
+		var v int
+		if zeroing {
+			var w WDL
+			w, _, err = tb.search(child, false)
+			v = -dtzBeforeZeroing(w)
+		} else {
+			v, err = tb.probeDTZ(child)
+			v = -v
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if v == 1 && movegen.InCheck(child) && len(movegen.LegalMoves(child)) == 0 {
+			minDTZ = 1 // Checkmate.
+		}
+		if !zeroing {
+			v += sign(v)
+		}
+		if v < minDTZ && sign(v) == sign(int(wdl)) {
+			minDTZ = v
+		}
+	}
+
+	// Without legal moves, the position is checkmate.
+	if minDTZ == 0xffff {
+		return -1, nil
+	}
+	return minDTZ, nil
+}
+
+// dtzBeforeZeroing returns the DTZ of a position whose best move is a capture
+// or pawn move with the given result.
+func dtzBeforeZeroing(wdl WDL) int {
+	switch wdl {
+	case Win:
+		return 1
+	case CursedWin:
+		return 101
+	case BlessedLoss:
+		return -101
+	case Loss:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// probeTable looks up a position in a table. For DTZ tables, wdl is the
+// result of the position, and it returns true if the table only stores the
+// other side to move.
+func (tb *Tablebase) probeTable(p core.Position, typ tableType, wdl WDL) (int, bool, error) {
+	occupied := p.Board.WhitePieces() | p.Board.BlackPieces()
+	if occupied.Count() == 2 {
+		return 0, false, nil // KvK.
+	}
+
+	key := signature(&p.Board, core.White)
+	t, ok := tb.tables[typ][key]
+	if !ok {
+		return 0, false, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err := t.load(); err != nil {
+		return 0, false, err
+	}
+
+	// Tables are stored with white as the stronger side, and with white to
+	// move if both sides have the same pieces. Other positions have their
+	// colors swapped and their squares flipped.
+	var (
+		flipColor   tbPiece
+		flipSquares core.Square
+		stm         int
+	)
+	if p.SideToMove == core.Black {
+		stm = 1
+	}
+	if key != t.key || (t.key == t.key2 && p.SideToMove == core.Black) {
+		flipColor, flipSquares = tbBlack, 56
+		stm ^= 1
+	}
+
+	var (
+		squares   [maxPieces]core.Square
+		pieces    [maxPieces]tbPiece
+		size      int
+		leadPawns int
+		f         core.File
+	)
+
+	// Tables with pawns are split by the file of the leading pawn, which is
+	// of the color of the first piece in the table.
+	if t.hasPawns {
+		pc := t.get(0, 0).pieces[0] ^ flipColor
+		c := core.White
+		if pc&tbBlack != 0 {
+			c = core.Black
+		}
+		b := p.Board[core.NewPiece(c, core.Pawn)]
+		occupied &^= b
+		for ; b != 0; b.Clear(b.First()) {
+			squares[size] = b.First() ^ flipSquares
+			pieces[size] = pc
+			size++
+		}
+		leadPawns = size
+		f = leadingPawns(squares[:leadPawns])
+	}
+
+	if typ == dtzTable && !t.hasSTM(stm, f) {
+		return 0, true, nil
+	}
+
+	for b := occupied; b != 0; b.Clear(b.First()) {
+		s := b.First()
+		pc, _ := p.Board.Get(s)
+		squares[size] = s ^ flipSquares
+		pieces[size] = toTBPiece(pc) ^ flipColor
+		size++
+	}
+
+	// Order the pieces like the table does.
+	d := t.get(stm, f)
+	for i := leadPawns; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+	idx := encode(d, t.hasPawns, t.hasUniquePieces, remainingPawns, leadPawns, squares[:size])
+
+	v, err := decompress(t.f, d, idx)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %v", t.path, err)
+	}
+
+	v, err = t.mapScore(f, v, wdl)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %v", t.path, err)
+	}
+	return v, false, nil
+}
+
+// hasSTM reports whether a DTZ table stores positions with a side to move.
+func (t *table) hasSTM(stm int, f core.File) bool {
+	return int(t.get(stm, f).flags&flagSTM) == stm || (t.key == t.key2 && !t.hasPawns)
+}
+
+// wdlMap maps a WDL, offset by 2, to its DTZ map.
+var wdlMap = [...]int{1, 3, 0, 2, 0}
+
+// mapScore converts a value stored in a table to a WDL, or to a DTZ in plies,
+// less one.
+func (t *table) mapScore(f core.File, v int, wdl WDL) (int, error) {
+	if t.typ == wdlTable {
+		return v - 2, nil
+	}
+
+	d := t.get(0, f)
+	if d.flags&flagMapped != 0 {
+		i := d.mapIdx[wdlMap[wdl+2]] + v
+		switch {
+		case d.flags&flagWide != 0:
+			if 2*i+2 > len(t.dmap) {
+				return 0, errors.New("invalid DTZ map")
+			}
+			v = int(binary.LittleEndian.Uint16(t.dmap[2*i:]))
+		default:
+			if i >= len(t.dmap) {
+				return 0, errors.New("invalid DTZ map")
+			}
+			v = int(t.dmap[i])
+		}
+	}
+
+	// Distances may be stored in moves rather than plies.
+	switch {
+	case wdl == Win && d.flags&flagWinPlies == 0,
+		wdl == Loss && d.flags&flagLossPlies == 0,
+		wdl == CursedWin, wdl == BlessedLoss:
+		v *= 2
+	}
+	return v + 1, nil
+}
+
+// signature returns the material signature of a board, like "KRvK", with the
+// pieces of color c first.
+func signature(b *core.Board, c core.Color) string {
+	var sb strings.Builder
+	for i, side := range []core.Color{c, c.Other()} {
+		if i > 0 {
+			sb.WriteByte('v')
+		}
+		for _, pt := range []core.PieceType{core.King, core.Queen, core.Rook, core.Bishop, core.Knight, core.Pawn} {
+			n := b[core.NewPiece(side, pt)].Count()
+			sb.WriteString(strings.Repeat(pieceLetters[pt], n))
+		}
+	}
+	return sb.String()
+}
+
+var pieceLetters = [...]string{
+	core.Pawn:   "P",
+	core.Knight: "N",
+	core.Bishop: "B",
+	core.Rook:   "R",
+	core.Queen:  "Q",
+	core.King:   "K",
+}
+
+func hasCastlingRights(p core.Position) bool {
+	return p.WhiteOO || p.WhiteOOO || p.BlackOO || p.BlackOOO
+}
+
+// isCapture reports whether a move is a capture, including en passant.
+func isCapture(p core.Position, m core.Move) bool {
+	return p.Board.IsOccupied(m.To) || (isPawnMove(p, m) && m.From.File() != m.To.File())
+}
+
+func isPawnMove(p core.Position, m core.Move) bool {
+	pc, _ := p.Board.Get(m.From)
+	return pc.Type() == core.Pawn
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	default:
+		return 0
+	}
+}
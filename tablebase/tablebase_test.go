@@ -0,0 +1,415 @@
+package tablebase
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/dtm"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/movegen"
+	"github.com/google/go-cmp/cmp"
+)
+
+// testTables are the official Syzygy tables the tests probe, which are read
+// from testdata. They're in the 3-4-5 piece set at
+// https://tablebase.lichess.ovh/tables/standard/3-4-5/. Tests that probe them
+// are skipped if they're missing.
+var testTables = []string{"KBvK", "KNvK", "KPvK", "KQvK", "KRvK"}
+
+// openTestdata opens the tables in testdata, or skips the test if they're
+// missing.
+func openTestdata(t *testing.T) *Tablebase {
+	t.Helper()
+	for _, name := range testTables {
+		for _, ext := range extensions {
+			if _, err := os.Stat(filepath.Join("testdata", name+ext)); err != nil {
+				t.Skipf("missing Syzygy table: %v", err)
+			}
+		}
+	}
+	return open(t, "testdata")
+}
+
+func open(t *testing.T, path string) *Tablebase {
+	t.Helper()
+	tb, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	t.Cleanup(func() { tb.Close() })
+	return tb
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("got no error for a missing directory")
+	}
+
+	tb := openTestdata(t)
+	if got := tb.MaxPieces(); got != 3 {
+		t.Errorf("got %d pieces, want 3", got)
+	}
+}
+
+func TestProbeWDL(t *testing.T) {
+	tb := openTestdata(t)
+
+	cases := []struct {
+		in   string
+		want WDL
+	}{
+		{"8/8/8/8/8/2k5/8/KN6 w - - 0 1", Draw},
+		{"8/8/8/8/8/2k5/8/KB6 b - - 0 1", Draw},
+		{"8/8/2k5/8/3Q4/8/8/K7 w - - 0 1", Win},
+		{"8/8/2k5/8/3Q4/8/8/K7 b - - 0 1", Loss},
+		// Black to move captures the queen.
+		{"8/8/2k5/3Q4/8/8/8/K7 b - - 0 1", Draw},
+		// Stalemate.
+		{"k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", Draw},
+		// Checkmate.
+		{"k6Q/8/1K6/8/8/8/8/8 b - - 0 1", Loss},
+		{"8/8/2k5/8/8/8/8/K6R w - - 0 1", Win},
+		// Black is the stronger side.
+		{"k7/8/8/3q4/8/2K5/8/8 b - - 0 1", Win},
+		{"k7/8/8/3q4/8/2K5/8/8 w - - 0 1", Loss},
+		{"8/8/2k5/8/8/8/8/K7 w - - 0 1", Draw},
+		// Black has the opposition.
+		{"8/4k3/8/4K3/4P3/8/8/8 w - - 0 1", Draw},
+		{"8/4k3/8/4K3/4P3/8/8/8 b - - 0 1", Loss},
+		{"8/8/8/8/4p3/4k3/8/4K3 b - - 0 1", Win},
+		// Rook's pawns draw if the defending king reaches the corner.
+		{"k7/8/8/P7/8/8/8/7K w - - 0 1", Draw},
+		// Black to move captures the pawn.
+		{"8/8/8/8/8/3k4/3P4/K7 b - - 0 1", Draw},
+		// Promoting to a queen stalemates, but promoting to a rook wins.
+		{"8/k1P5/8/2K5/8/8/8/8 w - - 0 1", Win},
+		// There's no KPvKP table, but capturing en passant wins.
+		{"8/2K5/4k3/3pP3/8/8/8/8 w - d6 0 2", Win},
+	}
+
+	for _, tc := range cases {
+		got, err := tb.ProbeWDL(fen.MustDecode(tc.in))
+		if err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestProbeWDL_Error(t *testing.T) {
+	tb := openTestdata(t)
+
+	cases := []struct {
+		in   string
+		want error
+	}{
+		{"8/8/2k5/8/8/8/8/K5RR w - - 0 1", ErrNotFound},
+		{"4k3/8/8/8/8/8/8/4K2R w K - 0 1", ErrCastling},
+		// Without en passant, the KPvKP table is needed.
+		{"8/2K5/4k3/3pP3/8/8/8/8 w - - 0 2", ErrNotFound},
+	}
+	for _, tc := range cases {
+		if _, err := tb.ProbeWDL(fen.MustDecode(tc.in)); !errors.Is(err, tc.want) {
+			t.Errorf("%q: got %v, want %v", tc.in, err, tc.want)
+		}
+	}
+}
+
+func TestProbeWDL_BadMagic(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "KRvK.rtbw"), []byte("not a table"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tb := open(t, dir)
+	if _, err := tb.ProbeWDL(fen.MustDecode("8/8/2k5/8/8/8/8/K6R w - - 0 1")); err == nil {
+		t.Error("got no error for a bad table")
+	}
+}
+
+func TestProbeDTZ(t *testing.T) {
+	tb := openTestdata(t)
+
+	cases := []struct {
+		in   string
+		want int
+	}{
+		{"8/8/8/8/8/2k5/8/KN6 w - - 0 1", 0},
+		// Mate in one, with the table for black to move.
+		{"k7/8/1K6/8/8/8/8/7Q w - - 0 1", 1},
+		{"k7/8/1K6/8/8/8/8/7R w - - 0 1", 1},
+		{"k6Q/8/1K6/8/8/8/8/8 b - - 0 1", -1},
+		{"k6R/8/1K6/8/8/8/8/8 b - - 0 1", -1},
+		// Black is the stronger side, and white must move into a mate.
+		{"7q/8/8/8/8/1k6/8/K7 w - - 0 1", -2},
+		{"7q/8/8/8/8/1k6/8/K7 b - - 0 1", 1},
+		// Capturing the queen draws.
+		{"8/8/2k5/3Q4/8/8/8/K7 b - - 0 1", 0},
+		{"k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", 0},
+		// The longest win for KRvK.
+		{"8/8/8/8/3k4/8/1R6/K7 b - - 0 1", -32},
+		// White moves its king out of the pawn's way, then pushes it.
+		{"4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", -4},
+		// Promoting to a rook.
+		{"8/k1P5/8/2K5/8/8/8/8 w - - 0 1", 1},
+		// Capturing en passant is the only capture or pawn move, and it wins.
+		{"8/2K5/4k3/3pP3/8/8/8/8 w - d6 0 2", 1},
+	}
+
+	for _, tc := range cases {
+		got, err := tb.ProbeDTZ(fen.MustDecode(tc.in))
+		if err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
+
+// TestProbe_DTM checks probes of positions with each table against package
+// dtm. Without pawns, the DTZ is the distance to mate. With them, it's
+// checked against the DTZ of the positions each move leads to.
+func TestProbe_DTM(t *testing.T) {
+	tb := openTestdata(t)
+
+	// Probe every step-th position.
+	step := 19
+	if testing.Short() {
+		step = 97
+	}
+
+	for name, piece := range map[string]core.Piece{
+		"KQvK": core.WhiteQueen,
+		"KRvK": core.WhiteRook,
+		"KPvK": core.WhitePawn,
+	} {
+		tbl, err := dtm.Generate(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, p := range samplePositions(piece, step) {
+			in := fen.Encode(p)
+
+			r, err := tbl.Probe(p)
+			if err != nil {
+				t.Fatalf("%q: %v", in, err)
+			}
+			wdl, err := tb.ProbeWDL(p)
+			if err != nil {
+				t.Fatalf("%q: %v", in, err)
+			}
+			if want := WDL(2 * r.Outcome); wdl != want {
+				t.Errorf("%q: got %s, want %s", in, wdl, want)
+			}
+
+			got, err := tb.ProbeDTZ(p)
+			if err != nil {
+				t.Fatalf("%q: %v", in, err)
+			}
+			want := r.Plies * int(r.Outcome)
+			if r.Outcome == dtm.Loss && r.Plies == 0 {
+				want = -1 // Checkmate.
+			}
+			if name == "KPvK" && r.Outcome != dtm.Draw {
+				want, err = childDTZ(tb, p)
+				if err != nil {
+					t.Fatalf("%q: %v", in, err)
+				}
+			}
+			if got != want {
+				t.Errorf("%q: got DTZ %d, want %d", in, got, want)
+			}
+		}
+	}
+}
+
+// samplePositions returns every step-th legal position with a white king, a
+// white piece and a black king, with either side to move.
+func samplePositions(piece core.Piece, step int) []core.Position {
+	pieces := []core.Piece{core.WhiteKing, piece, core.BlackKing}
+
+	var positions []core.Position
+	for i := 0; i < 2<<18; i += step {
+		p := core.Position{FullMoveNumber: 1}
+		if i>>18 == 1 {
+			p.SideToMove = core.Black
+		}
+		ok := true
+		for k, pc := range pieces {
+			sq := core.Square(i >> (6 * k) & 63)
+			if p.Board.IsOccupied(sq) || pc.Type() == core.Pawn && (sq.Rank() == core.Rank1 || sq.Rank() == core.Rank8) {
+				ok = false
+				break
+			}
+			p.Board.Set(pc, sq)
+		}
+
+		// The side that isn't to move can't be in check.
+		q := p
+		q.SideToMove = q.SideToMove.Other()
+		if ok && !movegen.InCheck(q) {
+			positions = append(positions, p)
+		}
+	}
+	return positions
+}
+
+// childDTZ returns the DTZ of a won or lost position from the results and DTZ
+// of the positions after each move.
+func childDTZ(tb *Tablebase, p core.Position) (int, error) {
+	moves := movegen.LegalMoves(p)
+	if len(moves) == 0 {
+		return -1, nil
+	}
+
+	var best int
+	for _, m := range moves {
+		child := p
+		child.Make(m)
+
+		var v int
+		switch {
+		case isCapture(p, m) || isPawnMove(p, m):
+			wdl, err := tb.ProbeWDL(child)
+			if err != nil {
+				return 0, err
+			}
+			v = -sign(int(wdl))
+		case movegen.InCheck(child) && len(movegen.LegalMoves(child)) == 0:
+			v = 1
+		default:
+			dtz, err := tb.ProbeDTZ(child)
+			if err != nil {
+				return 0, err
+			}
+			v = -dtz + sign(-dtz)
+		}
+
+		// The winner takes the fastest win, and the loser the slowest loss.
+		switch {
+		case v > 0 && (best <= 0 || v < best):
+			best = v
+		case v < 0 && best <= 0 && v < best:
+			best = v
+		}
+	}
+	return best, nil
+}
+
+func TestRootMoves(t *testing.T) {
+	tb := openTestdata(t)
+	tbl, err := dtm.Generate("KQvK")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := fen.MustDecode("8/8/2k5/8/3Q4/8/8/K7 w - - 0 1")
+	moves := movegen.LegalMoves(p)
+
+	got, err := tb.RootMoves(p, moves)
+	if err != nil {
+		t.Fatalf("RootMoves() error: %v", err)
+	}
+
+	// Moves that let black capture the queen or stalemate are dropped.
+	var want []core.Move
+	for _, m := range moves {
+		child := p
+		child.Make(m)
+		if r, _ := tbl.Probe(child); r.Outcome == dtm.Loss {
+			want = append(want, m)
+		}
+	}
+	if len(want) == len(moves) {
+		t.Fatal("every move wins")
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRootMoves_Promotion(t *testing.T) {
+	tb := openTestdata(t)
+
+	p := fen.MustDecode("8/k1P5/8/2K5/8/8/8/8 w - - 0 1")
+	got, err := tb.RootMoves(p, movegen.LegalMoves(p))
+	if err != nil {
+		t.Fatalf("RootMoves() error: %v", err)
+	}
+
+	var names []string
+	for _, m := range got {
+		names = append(names, pcn.Encode(m))
+	}
+	if !slices.Contains(names, "c7c8r") {
+		t.Errorf("got %v, want c7c8r", names)
+	}
+	for _, s := range []string{"c7c8q", "c7c8b", "c7c8n"} {
+		if slices.Contains(names, s) {
+			t.Errorf("got %v, want no %s", names, s)
+		}
+	}
+}
+
+func TestRootMoves_FiftyMoveRule(t *testing.T) {
+	tb := openTestdata(t)
+	tbl, err := dtm.Generate("KRvK")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Near the fifty-move rule, only the fastest mates are kept, which
+	// without pawns are those with the shortest distance to mate.
+	p := fen.MustDecode("8/8/8/8/3k4/8/8/R5K1 w - - 99 60")
+	moves := movegen.LegalMoves(p)
+
+	got, err := tb.RootMoves(p, moves)
+	if err != nil {
+		t.Fatalf("RootMoves() error: %v", err)
+	}
+
+	var (
+		want    []core.Move
+		fastest = 0xff
+	)
+	for _, m := range moves {
+		child := p
+		child.Make(m)
+		r, _ := tbl.Probe(child)
+		switch {
+		case r.Outcome != dtm.Loss:
+		case r.Plies < fastest:
+			want, fastest = []core.Move{m}, r.Plies
+		case r.Plies == fastest:
+			want = append(want, m)
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRootMoves_Draw(t *testing.T) {
+	tb := openTestdata(t)
+
+	p := fen.MustDecode("8/8/8/8/8/2k5/8/KN6 w - - 0 1")
+	moves := movegen.LegalMoves(p)
+
+	got, err := tb.RootMoves(p, moves)
+	if err != nil {
+		t.Fatalf("RootMoves() error: %v", err)
+	}
+	if diff := cmp.Diff(moves, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,679 @@
+// Package uci implements the engine side of the Universal Chess Interface
+// (UCI) protocol.
+package uci
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clfs/simple/book"
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/movegen"
+	"github.com/clfs/simple/search"
+	"github.com/clfs/simple/search/mcts"
+	"github.com/clfs/simple/tablebase"
+)
+
+// Engine identification.
+const (
+	Name   = "simple"
+	Author = "the simple authors"
+)
+
+// Option limits.
+const (
+	maxThreads  = 256
+	maxMultiPV  = 256
+	maxContempt = 100
+	maxProbe    = 100 // Maximum SyzygyProbeDepth.
+
+	// The Elo ratings of the weakest and strongest limited skill levels.
+	// They're rough estimates.
+	minElo = 800
+	maxElo = 2000
+)
+
+// Search backends.
+const (
+	alphaBeta  = "alphabeta"
+	monteCarlo = "mcts"
+)
+
+// Variants, by their UCI_Variant names.
+var variants = []struct {
+	name    string
+	variant core.Variant
+	start   string // The starting FEN, if it isn't the standard one.
+}{
+	{"chess", core.Standard, ""},
+	{"kingofthehill", core.KingOfTheHill, ""},
+	{"3check", core.ThreeCheck, ""},
+	{"crazyhouse", core.Crazyhouse, ""},
+	{"atomic", core.Atomic, ""},
+	{"antichess", core.Antichess, ""},
+	{"horde", core.Horde, fen.HordeStarting},
+	{"racingkings", core.RacingKings, fen.RacingKingsStarting},
+}
+
+// An engine holds the state of a UCI session.
+type engine struct {
+	mu sync.Mutex // Guards w.
+	w  io.Writer
+
+	start core.Position // Position before moves.
+	moves []core.Move   // Moves played from start.
+	pos   core.Position // Position after moves.
+
+	opts          search.Options
+	ponder        bool // Whether to report ponder moves.
+	limitStrength bool // Whether to limit strength to elo.
+	elo           int
+	chess960      bool // Whether castling moves are the king taking its rook.
+	variant       core.Variant
+	backend       string
+	tree          *mcts.Searcher // Keeps the MCTS tree between searches.
+	book          *book.Book     // Opening book, if any.
+	rng           *rand.Rand     // Picks book moves.
+	tb            *tablebase.Tablebase
+
+	cancel context.CancelFunc // Stops the current search, if any.
+	done   chan struct{}      // Closed when the current search finishes.
+	hit    chan struct{}      // Closed on "ponderhit", if pondering.
+}
+
+// Run reads UCI commands from r and writes responses to w, until it reads the
+// "quit" command or reaches the end of r.
+func Run(r io.Reader, w io.Writer) error {
+	e := &engine{
+		w:       w,
+		start:   core.NewPosition(),
+		pos:     core.NewPosition(),
+		elo:     minElo,
+		backend: alphaBeta,
+		tree:    new(mcts.Searcher),
+		rng:     rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0)),
+	}
+	defer func() {
+		if e.tb != nil {
+			e.tb.Close()
+		}
+	}()
+	defer e.stop()
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if !e.handle(s.Text()) {
+			return nil
+		}
+	}
+	return s.Err()
+}
+
+// printf writes a line of output.
+func (e *engine) printf(format string, a ...any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprintf(e.w, format+"\n", a...)
+}
+
+// handle handles a single command. It returns false if the session is over.
+func (e *engine) handle(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "uci":
+		e.printf("id name %s", Name)
+		e.printf("id author %s", Author)
+		e.printf("option name Threads type spin default 1 min 1 max %d", maxThreads)
+		e.printf("option name MultiPV type spin default 1 min 1 max %d", maxMultiPV)
+		e.printf("option name Ponder type check default false")
+		e.printf("option name Contempt type spin default 0 min %d max %d", -maxContempt, maxContempt)
+		e.printf("option name Skill Level type spin default %d min 1 max %d", search.MaxSkill, search.MaxSkill)
+		e.printf("option name UCI_LimitStrength type check default false")
+		e.printf("option name UCI_Elo type spin default %d min %d max %d", minElo, minElo, maxElo)
+		e.printf("option name UCI_Chess960 type check default false")
+		var vars strings.Builder
+		for _, v := range variants {
+			fmt.Fprintf(&vars, " var %s", v.name)
+		}
+		e.printf("option name UCI_Variant type combo default chess%s", vars.String())
+		e.printf("option name Backend type combo default %s var %s var %s", alphaBeta, alphaBeta, monteCarlo)
+		e.printf("option name BookFile type string default <empty>")
+		e.printf("option name SyzygyPath type string default <empty>")
+		e.printf("option name SyzygyProbeDepth type spin default 1 min 1 max %d", maxProbe)
+		e.printf("uciok")
+	case "isready":
+		e.printf("readyok")
+	case "setoption":
+		e.setOption(args)
+	case "ucinewgame":
+		e.stop()
+		e.start, e.moves, e.pos = core.NewPosition(), nil, core.NewPosition()
+		e.tree.Reset()
+	case "position":
+		e.stop()
+		if err := e.position(args); err != nil {
+			e.printf("info string %v", err)
+		}
+	case "go":
+		e.stop()
+		e.goSearch(args)
+	case "ponderhit":
+		if e.hit != nil {
+			close(e.hit)
+			e.hit = nil
+		}
+	case "stop":
+		e.stop()
+	case "quit":
+		return false
+	default:
+		e.printf("info string unknown command: %s", cmd)
+	}
+
+	return true
+}
+
+// stop stops the current search, if any, and waits for it to finish.
+func (e *engine) stop() {
+	if e.cancel == nil {
+		return
+	}
+	e.cancel()
+	<-e.done
+	e.cancel, e.done, e.hit = nil, nil, nil
+}
+
+// setOption handles the "setoption" command.
+func (e *engine) setOption(args []string) {
+	// setoption name <id> [value <x>]
+	var name, value []string
+	for i, arg := range args {
+		if arg == "value" {
+			value = args[i+1:]
+			break
+		}
+		if i > 0 || arg != "name" {
+			name = append(name, arg)
+		}
+	}
+
+	id := strings.Join(name, " ")
+	v := strings.Join(value, " ")
+
+	spin := func(lo, hi int) (int, bool) {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < lo || n > hi {
+			e.printf("info string invalid value for %s: %s", id, v)
+			return 0, false
+		}
+		return n, true
+	}
+
+	check := func() (bool, bool) {
+		switch v {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		default:
+			e.printf("info string invalid value for %s: %s", id, v)
+			return false, false
+		}
+	}
+
+	switch strings.ToLower(id) {
+	case "threads":
+		if n, ok := spin(1, maxThreads); ok {
+			e.opts.Threads = n
+		}
+	case "multipv":
+		if n, ok := spin(1, maxMultiPV); ok {
+			e.opts.MultiPV = n
+		}
+	case "ponder":
+		if b, ok := check(); ok {
+			e.ponder = b
+		}
+	case "contempt":
+		if n, ok := spin(-maxContempt, maxContempt); ok {
+			e.opts.Contempt = n
+		}
+	case "skill level":
+		if n, ok := spin(1, search.MaxSkill); ok {
+			e.opts.Skill = n
+		}
+	case "uci_limitstrength":
+		if b, ok := check(); ok {
+			e.limitStrength = b
+		}
+	case "uci_elo":
+		if n, ok := spin(minElo, maxElo); ok {
+			e.elo = n
+		}
+	case "uci_chess960":
+		if b, ok := check(); ok {
+			e.chess960 = b
+		}
+	case "uci_variant":
+		found := false
+		for _, x := range variants {
+			if x.name == v {
+				e.variant, found = x.variant, true
+			}
+		}
+		if !found {
+			e.printf("info string invalid value for %s: %s", id, v)
+		}
+	case "backend":
+		if v == alphaBeta || v == monteCarlo {
+			e.backend = v
+		} else {
+			e.printf("info string invalid value for %s: %s", id, v)
+		}
+	case "bookfile":
+		if v == "" || v == "<empty>" {
+			e.book = nil
+			break
+		}
+		b, err := book.Open(v)
+		if err != nil {
+			e.printf("info string %v", err)
+			break
+		}
+		e.book = b
+	case "syzygypath":
+		if e.tb != nil {
+			e.tb.Close()
+			e.tb, e.opts.Tablebase = nil, nil
+		}
+		if v == "" || v == "<empty>" {
+			break
+		}
+		tb, err := tablebase.Open(v)
+		if err != nil {
+			e.printf("info string %v", err)
+			break
+		}
+		e.tb, e.opts.Tablebase = tb, tb
+		e.printf("info string found tablebases with up to %d pieces", tb.MaxPieces())
+	case "syzygyprobedepth":
+		if n, ok := spin(1, maxProbe); ok {
+			e.opts.ProbeDepth = n
+		}
+	default:
+		e.printf("info string unknown option: %s", id)
+	}
+}
+
+// startingPosition returns the starting position of the variant being played.
+func (e *engine) startingPosition() core.Position {
+	for _, v := range variants {
+		if v.variant == e.variant && v.start != "" {
+			return fen.MustDecode(v.start)
+		}
+	}
+	return core.NewPosition()
+}
+
+// position handles the "position" command.
+func (e *engine) position(args []string) error {
+	// position [fen <fenstring> | startpos] [moves <move1> ... <movei>]
+	if len(args) == 0 {
+		return errors.New("missing position")
+	}
+
+	var (
+		p     core.Position
+		moves []core.Move
+	)
+
+	switch args[0] {
+	case "startpos":
+		p = e.startingPosition()
+		args = args[1:]
+	case "fen":
+		end := slices.Index(args, "moves")
+		if end == -1 {
+			end = len(args)
+		}
+		var err error
+		p, err = fen.Decode(strings.Join(args[1:end], " "))
+		if err != nil {
+			return fmt.Errorf("invalid FEN: %v", err)
+		}
+		args = args[end:]
+	default:
+		return fmt.Errorf("invalid position: %s", args[0])
+	}
+
+	if e.chess960 {
+		p.SetChess960()
+	}
+	if e.variant != core.Standard {
+		p.Variant = e.variant
+	}
+	// Validity depends on the variant, like how many kings there are.
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("invalid FEN: %v", err)
+	}
+	start := p
+
+	if len(args) > 0 && args[0] == "moves" {
+		args = args[1:]
+	} else {
+		args = nil
+	}
+
+	for _, s := range args {
+		m, err := pcn.Decode(s)
+		if err != nil {
+			return fmt.Errorf("invalid move: %v", err)
+		}
+		if !slices.Contains(movegen.LegalMoves(p), m) {
+			return fmt.Errorf("illegal move: %s", s)
+		}
+		p.Make(m)
+		moves = append(moves, m)
+	}
+
+	e.start, e.moves, e.pos = start, moves, p
+	return nil
+}
+
+// eloSkill returns the skill level for an Elo rating.
+func eloSkill(elo int) int {
+	return 1 + (elo-minElo)*(search.MaxSkill-2)/(maxElo-minElo)
+}
+
+// limits are the search limits given to the "go" command.
+type limits struct {
+	depth       int
+	nodes       int
+	mate        int
+	moveTime    time.Duration
+	wtime       time.Duration
+	btime       time.Duration
+	winc, binc  time.Duration
+	movesToGo   int
+	infinite    bool
+	ponder      bool
+	searchMoves []core.Move
+}
+
+// parseLimits parses the arguments to the "go" command. Unknown limits and
+// invalid values are skipped.
+func parseLimits(args []string) limits {
+	var l limits
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "infinite":
+			l.infinite = true
+			continue
+		case "ponder":
+			l.ponder = true
+			continue
+		case "searchmoves":
+			// The moves run until the next limit.
+			for ; i+1 < len(args); i++ {
+				m, err := pcn.Decode(args[i+1])
+				if err != nil {
+					break
+				}
+				l.searchMoves = append(l.searchMoves, m)
+			}
+			continue
+		}
+
+		if i+1 >= len(args) {
+			break
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			continue
+		}
+		ms := time.Duration(n) * time.Millisecond
+
+		switch args[i] {
+		case "depth":
+			l.depth = n
+		case "nodes":
+			l.nodes = n
+		case "mate":
+			l.mate = n
+		case "movetime":
+			l.moveTime = ms
+		case "wtime":
+			l.wtime = ms
+		case "btime":
+			l.btime = ms
+		case "winc":
+			l.winc = ms
+		case "binc":
+			l.binc = ms
+		case "movestogo":
+			l.movesToGo = n
+		default:
+			continue
+		}
+		i++
+	}
+
+	return l
+}
+
+// defaultMovesToGo is the assumed number of moves left in the game when the
+// GUI doesn't say.
+const defaultMovesToGo = 30
+
+// budget returns how long to search for, or zero if there's no time limit.
+func (l limits) budget(c core.Color) time.Duration {
+	if l.infinite {
+		return 0
+	}
+	if l.moveTime > 0 {
+		return l.moveTime
+	}
+
+	remaining, inc := l.wtime, l.winc
+	if c == core.Black {
+		remaining, inc = l.btime, l.binc
+	}
+	if remaining <= 0 {
+		return 0
+	}
+
+	movesToGo := l.movesToGo
+	if movesToGo <= 0 {
+		movesToGo = defaultMovesToGo
+	}
+
+	return min(remaining/time.Duration(movesToGo)+inc/2, remaining/2)
+}
+
+// goSearch handles the "go" command by starting a search, or by playing a
+// book move.
+func (e *engine) goSearch(args []string) {
+	l := parseLimits(args)
+
+	// Play from the book if possible, unless the GUI wants a search that
+	// reports its progress or is limited to some moves. Books only cover
+	// standard chess.
+	if e.book != nil && e.pos.Variant == core.Standard && !l.ponder && !l.infinite && l.mate == 0 && l.searchMoves == nil {
+		if m, ok := e.book.Pick(e.pos, e.rng); ok {
+			e.printf("info string book move")
+			e.printf("bestmove %s", pcn.Encode(m))
+			return
+		}
+	}
+
+	opts := e.opts
+	opts.Depth = l.depth
+	opts.Nodes = l.nodes
+	opts.Mate = l.mate
+	opts.SearchMoves = l.searchMoves
+	opts.MoveTime = l.budget(e.pos.SideToMove)
+	opts.Seed = uint64(time.Now().UnixNano())
+	if e.limitStrength {
+		opts.Skill = eloSkill(e.elo)
+	}
+
+	history := e.history()
+	opts.History = history
+
+	pos := e.pos
+	run := runFunc(func(ctx context.Context, info chan<- search.Info) error {
+		return search.Run(ctx, pos, opts, info)
+	})
+	if e.backend == monteCarlo {
+		tree := e.tree
+		run = func(ctx context.Context, info chan<- search.Info) error {
+			return tree.Run(ctx, pos, opts, info)
+		}
+	}
+
+	// When pondering, the last move is the opponent's expected reply. The
+	// time limit starts on "ponderhit", and a ponder miss ends with "stop".
+	// Without a move to ponder on, or with the mcts backend, the position is
+	// searched normally, but the best move isn't reported until then either.
+	if l.ponder {
+		hit := make(chan struct{})
+		if len(e.moves) > 0 && e.backend != monteCarlo {
+			var (
+				n    = len(e.moves) - 1
+				prev = history[n]
+				move = e.moves[n]
+			)
+			opts.History = history[:n]
+			run = func(ctx context.Context, info chan<- search.Info) error {
+				return search.Ponder(ctx, prev, move, opts, hit, info)
+			}
+		} else {
+			normal := run
+			run = func(ctx context.Context, info chan<- search.Info) error {
+				err := normal(ctx, info)
+				select {
+				case <-hit:
+				case <-ctx.Done():
+				}
+				return err
+			}
+		}
+		e.hit = hit
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	e.cancel = cancel
+	e.done = make(chan struct{})
+
+	go e.search(ctx, e.pos, run, l.infinite, e.ponder, e.done)
+}
+
+// history returns the positions before each move played.
+func (e *engine) history() []core.Position {
+	var (
+		positions []core.Position
+		p         = e.start
+	)
+	for _, m := range e.moves {
+		positions = append(positions, p)
+		p.Make(m)
+	}
+	return positions
+}
+
+// A runFunc runs a search, like search.Run.
+type runFunc func(ctx context.Context, info chan<- search.Info) error
+
+// search runs a search of p and reports its progress and result.
+func (e *engine) search(ctx context.Context, p core.Position, run runFunc, infinite, ponder bool, done chan<- struct{}) {
+	defer close(done)
+
+	var (
+		info = make(chan search.Info)
+		errc = make(chan error, 1)
+		best search.Info
+	)
+
+	go func() {
+		errc <- run(ctx, info)
+	}()
+
+	// Fall back to any legal move if the search stops before finishing an
+	// iteration.
+	if moves := movegen.LegalMoves(p); len(moves) > 0 {
+		best.Move = moves[0]
+	}
+
+	for {
+		select {
+		case i := <-info:
+			e.printf("%s", formatInfo(i))
+			if i.Rank == 1 {
+				best = i
+			}
+		case err := <-errc:
+			if errors.Is(err, search.ErrNoLegalMoves) {
+				e.printf("bestmove 0000")
+				return
+			}
+			if errors.Is(err, search.ErrNoMate) {
+				e.printf("info string %v", err)
+			}
+			// An infinite search must not report a move until it's stopped.
+			if err == nil && infinite {
+				<-ctx.Done()
+			}
+			if m, ok := best.PonderMove(); ok && ponder {
+				e.printf("bestmove %s ponder %s", pcn.Encode(best.Move), pcn.Encode(m))
+			} else {
+				e.printf("bestmove %s", pcn.Encode(best.Move))
+			}
+			return
+		}
+	}
+}
+
+// formatInfo formats search information as an "info" command.
+func formatInfo(i search.Info) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "info depth %d multipv %d score %s nodes %d nps %d time %d",
+		i.Depth, i.Rank, formatScore(i.Score), i.Nodes, i.NPS(), i.Time.Milliseconds())
+	if i.TBHits > 0 {
+		fmt.Fprintf(&b, " tbhits %d", i.TBHits)
+	}
+	b.WriteString(" pv")
+	for _, m := range i.PV {
+		b.WriteByte(' ')
+		b.WriteString(pcn.Encode(m))
+	}
+
+	return b.String()
+}
+
+// formatScore formats a score as "cp <x>" or "mate <y>".
+func formatScore(score int) string {
+	if n, ok := search.MateIn(score); ok {
+		return fmt.Sprintf("mate %d", n)
+	}
+	return fmt.Sprintf("cp %d", score)
+}
@@ -0,0 +1,591 @@
+package uci
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clfs/simple/book"
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/search"
+	"github.com/google/go-cmp/cmp"
+)
+
+// A session is a UCI session for testing.
+type session struct {
+	t    *testing.T
+	in   *io.PipeWriter
+	out  *bufio.Scanner
+	errc chan error
+}
+
+func newSession(t *testing.T) *session {
+	t.Helper()
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	s := &session{
+		t:    t,
+		in:   inW,
+		out:  bufio.NewScanner(outR),
+		errc: make(chan error, 1),
+	}
+
+	go func() {
+		s.errc <- Run(inR, outW)
+		outW.Close()
+	}()
+
+	t.Cleanup(s.close)
+
+	return s
+}
+
+// send sends a command.
+func (s *session) send(cmd string) {
+	s.t.Helper()
+	if _, err := io.WriteString(s.in, cmd+"\n"); err != nil {
+		s.t.Fatalf("send %q: %v", cmd, err)
+	}
+}
+
+// expect reads lines until one starts with prefix, and returns all lines read.
+func (s *session) expect(prefix string) []string {
+	s.t.Helper()
+	var lines []string
+	for s.out.Scan() {
+		lines = append(lines, s.out.Text())
+		if strings.HasPrefix(s.out.Text(), prefix) {
+			return lines
+		}
+	}
+	s.t.Fatalf("no line starting with %q in %q", prefix, lines)
+	return nil
+}
+
+// close ends the session and checks that Run returned without error.
+func (s *session) close() {
+	s.in.Close()
+	go func() {
+		for s.out.Scan() {
+		}
+	}()
+	if err := <-s.errc; err != nil {
+		s.t.Errorf("Run() error: %v", err)
+	}
+}
+
+func TestRun_UCI(t *testing.T) {
+	s := newSession(t)
+	s.send("uci")
+	got := s.expect("uciok")
+	want := []string{
+		"id name simple",
+		"id author the simple authors",
+		"option name Threads type spin default 1 min 1 max 256",
+		"option name MultiPV type spin default 1 min 1 max 256",
+		"option name Ponder type check default false",
+		"option name Contempt type spin default 0 min -100 max 100",
+		"option name Skill Level type spin default 20 min 1 max 20",
+		"option name UCI_LimitStrength type check default false",
+		"option name UCI_Elo type spin default 800 min 800 max 2000",
+		"option name UCI_Chess960 type check default false",
+		"option name UCI_Variant type combo default chess var chess var kingofthehill var 3check var crazyhouse var atomic var antichess var horde var racingkings",
+		"option name Backend type combo default alphabeta var alphabeta var mcts",
+		"option name BookFile type string default <empty>",
+		"option name SyzygyPath type string default <empty>",
+		"option name SyzygyProbeDepth type spin default 1 min 1 max 100",
+		"uciok",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	s.send("isready")
+	s.expect("readyok")
+}
+
+func TestRun_GoDepth(t *testing.T) {
+	s := newSession(t)
+	s.send("position fen 4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+	s.send("go depth 2")
+	got := s.expect("bestmove")
+	if last := got[len(got)-1]; last != "bestmove d2d5" {
+		t.Errorf("got %q, want %q", last, "bestmove d2d5")
+	}
+	if !strings.HasPrefix(got[0], "info depth 1 multipv 1 score cp") {
+		t.Errorf("got %q, want info line", got[0])
+	}
+}
+
+func TestRun_GoLimits(t *testing.T) {
+	s := newSession(t)
+	s.send("position fen 4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+	s.send("go nodes 5000")
+	if last := s.expect("bestmove"); last[len(last)-1] != "bestmove d2d5" {
+		t.Errorf("got %q, want %q", last[len(last)-1], "bestmove d2d5")
+	}
+
+	// d2d5 wins the queen, but it isn't one of the moves searched.
+	s.send("go depth 3 searchmoves e1f1 e1f2")
+	got := s.expect("bestmove")
+	if last := got[len(got)-1]; last != "bestmove e1f1" && last != "bestmove e1f2" {
+		t.Errorf("got %q, want e1f1 or e1f2", last)
+	}
+
+	// Unknown limits don't stop the search.
+	s.send("go depth 2 bogus 7")
+	if last := s.expect("bestmove"); last[len(last)-1] != "bestmove d2d5" {
+		t.Errorf("got %q, want %q", last[len(last)-1], "bestmove d2d5")
+	}
+
+	s.send("go searchmoves a1a2")
+	if last := s.expect("bestmove"); last[len(last)-1] != "bestmove 0000" {
+		t.Errorf("got %q, want %q", last[len(last)-1], "bestmove 0000")
+	}
+}
+
+func TestRun_MultiPV(t *testing.T) {
+	s := newSession(t)
+	s.send("setoption name MultiPV value 3")
+	s.send("position startpos moves e2e4 e7e5")
+	s.send("go depth 2")
+	got := s.expect("bestmove")
+
+	var ranks []string
+	for _, line := range got {
+		if strings.HasPrefix(line, "info depth 2 ") {
+			ranks = append(ranks, strings.Fields(line)[4])
+		}
+	}
+	if diff := cmp.Diff([]string{"1", "2", "3"}, ranks); diff != "" {
+		t.Errorf("multipv mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRun_Stop(t *testing.T) {
+	s := newSession(t)
+	s.send("go infinite")
+	time.Sleep(50 * time.Millisecond)
+	s.send("stop")
+	got := s.expect("bestmove")
+	if last := got[len(got)-1]; !strings.HasPrefix(last, "bestmove ") || last == "bestmove 0000" {
+		t.Errorf("got %q, want a best move", last)
+	}
+}
+
+func TestRun_Ponder(t *testing.T) {
+	s := newSession(t)
+	s.send("setoption name Ponder value true")
+	s.send("position startpos moves e2e4 e7e5")
+	s.send("go ponder depth 2 wtime 1000 btime 1000")
+
+	// The search must not finish before "ponderhit".
+	s.send("isready")
+	for _, line := range s.expect("readyok") {
+		if strings.HasPrefix(line, "bestmove") {
+			t.Fatalf("got %q before ponderhit", line)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	s.send("ponderhit")
+	got := s.expect("bestmove")
+	last := strings.Fields(got[len(got)-1])
+	if len(last) != 4 || last[2] != "ponder" {
+		t.Errorf("got %q, want a best move and a ponder move", got[len(got)-1])
+	}
+}
+
+func TestRun_PonderMiss(t *testing.T) {
+	s := newSession(t)
+	s.send("position startpos moves e2e4")
+	s.send("go ponder")
+	time.Sleep(50 * time.Millisecond)
+	s.send("stop")
+	got := s.expect("bestmove")
+	if last := got[len(got)-1]; strings.Contains(last, "ponder") {
+		t.Errorf("got %q, want no ponder move with Ponder disabled", last)
+	}
+
+	// Without a move to ponder on, the position is searched normally, but
+	// the best move still waits for "stop".
+	s.send("position startpos")
+	s.send("go ponder depth 1")
+	s.send("isready")
+	for _, line := range s.expect("readyok") {
+		if strings.HasPrefix(line, "bestmove") {
+			t.Fatalf("got %q before stop", line)
+		}
+	}
+	s.send("stop")
+	got = s.expect("bestmove")
+	if last := got[len(got)-1]; !strings.HasPrefix(last, "bestmove ") || last == "bestmove 0000" {
+		t.Errorf("got %q, want a best move", last)
+	}
+}
+
+func TestRun_NoLegalMoves(t *testing.T) {
+	s := newSession(t)
+	s.send("position fen 7k/5Q2/6K1/8/8/8/8/8 b - - 0 1")
+	s.send("go depth 1")
+	got := s.expect("bestmove")
+	if last := got[len(got)-1]; last != "bestmove 0000" {
+		t.Errorf("got %q, want %q", last, "bestmove 0000")
+	}
+}
+
+func TestRun_GoMate(t *testing.T) {
+	s := newSession(t)
+	s.send("position fen 6k1/8/8/8/8/8/R7/1R4K1 w - - 0 1")
+	s.send("go mate 2")
+	got := s.expect("bestmove")
+	if !strings.HasPrefix(got[0], "info depth 3 multipv 1 score mate 2 ") {
+		t.Errorf("got %q, want mate in 2", got[0])
+	}
+	if last := got[len(got)-1]; !strings.HasPrefix(last, "bestmove ") || last == "bestmove 0000" {
+		t.Errorf("got %q, want a best move", last)
+	}
+
+	s.send("position startpos")
+	s.send("go mate 1")
+	got = s.expect("bestmove")
+	if want := "info string no mate found"; got[0] != want {
+		t.Errorf("got %q, want %q", got[0], want)
+	}
+}
+
+func TestRun_Chess960(t *testing.T) {
+	s := newSession(t)
+	s.send("position startpos moves e2e4 e7e5 g1f3 b8c6 f1c4 g8f6 e1h1")
+	if got := s.expect("info string"); got[0] != "info string illegal move: e1h1" {
+		t.Errorf("got %q, want an error", got[0])
+	}
+
+	s.send("setoption name UCI_Chess960 value true")
+	s.send("position fen 1r4kr/pppppppp/8/8/8/8/PPPPPPPP/1R4KR w HBhb - 0 1 moves g1h1")
+	s.send("go depth 1")
+	got := s.expect("bestmove")
+	if last := got[len(got)-1]; !strings.HasPrefix(last, "bestmove ") || last == "bestmove 0000" {
+		t.Errorf("got %q, want a best move", last)
+	}
+
+	s.send("position startpos moves e2e4 e7e5 g1f3 b8c6 f1c4 g8f6 e1h1")
+	s.send("isready")
+	for _, line := range s.expect("readyok") {
+		if strings.HasPrefix(line, "info string") {
+			t.Errorf("got %q, want no error", line)
+		}
+	}
+}
+
+func TestRun_Variant(t *testing.T) {
+	s := newSession(t)
+	s.send("setoption name UCI_Variant value kingofthehill")
+	s.send("position fen 8/8/8/8/8/4k3/8/4K3 b - - 0 1")
+	s.send("go depth 2")
+	got := s.expect("bestmove")
+	if !strings.Contains(got[len(got)-2], " score mate 1 ") {
+		t.Errorf("got %q, want mate in 1", got[len(got)-2])
+	}
+
+	s.send("setoption name UCI_Variant value crazyhouse")
+	s.send("position fen 6k1/5ppp/8/8/8/8/8/6K1[R] w - - 0 1 moves R@a1 g8h8")
+	s.send("go depth 2")
+	if got := s.expect("bestmove"); got[len(got)-1] != "bestmove a1a8" {
+		t.Errorf("got %q, want bestmove a1a8", got[len(got)-1])
+	}
+
+	// Antichess positions can have no kings.
+	s.send("setoption name UCI_Variant value antichess")
+	s.send("position fen 8/8/8/8/8/8/1r6/R7 w - - 0 1")
+	s.send("go depth 3")
+	if got := s.expect("bestmove"); got[len(got)-1] != "bestmove a1a2" {
+		t.Errorf("got %q, want bestmove a1a2", got[len(got)-1])
+	}
+
+	// Horde starts from its own position.
+	s.send("setoption name UCI_Variant value horde")
+	s.send("position startpos moves a4a5")
+	s.send("go depth 1")
+	if got := s.expect("bestmove"); got[len(got)-1] == "bestmove (none)" {
+		t.Errorf("got %q, want a move", got[len(got)-1])
+	}
+
+	s.send("setoption name UCI_Variant value minishogi")
+	if got := s.expect("info string"); got[0] != "info string invalid value for UCI_Variant: minishogi" {
+		t.Errorf("got %q, want an error", got[0])
+	}
+}
+
+func TestRun_Backend(t *testing.T) {
+	s := newSession(t)
+	s.send("setoption name Backend value mcts")
+	s.send("position fen 4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+	s.send("go depth 5")
+	got := s.expect("bestmove")
+	if last := got[len(got)-1]; last != "bestmove d2d5" {
+		t.Errorf("got %q, want %q", last, "bestmove d2d5")
+	}
+
+	// The mcts backend searches the position normally when pondering, and
+	// the best move waits for "ponderhit".
+	s.send("position startpos moves e2e4")
+	s.send("go ponder depth 1")
+	s.send("isready")
+	for _, line := range s.expect("readyok") {
+		if strings.HasPrefix(line, "bestmove") {
+			t.Fatalf("got %q before ponderhit", line)
+		}
+	}
+	s.send("ponderhit")
+	got = s.expect("bestmove")
+	if last := got[len(got)-1]; !strings.HasPrefix(last, "bestmove ") || last == "bestmove 0000" {
+		t.Errorf("got %q, want a best move", last)
+	}
+
+	s.send("setoption name Backend value minimax")
+	if got := s.expect("info string"); got[0] != "info string invalid value for Backend: minimax" {
+		t.Errorf("got %q, want an error", got[0])
+	}
+}
+
+func TestRun_BookFile(t *testing.T) {
+	p := core.NewPosition()
+	b := book.New([]book.Entry{
+		{Key: p.PolyglotKey(), Move: book.EncodeMove(p, pcn.MustDecode("c2c4")), Weight: 1},
+	})
+
+	name := filepath.Join(t.TempDir(), "book.bin")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newSession(t)
+	s.send("setoption name BookFile value " + name)
+	s.send("position startpos")
+	s.send("go depth 1")
+	if got := s.expect("bestmove"); got[len(got)-1] != "bestmove c2c4" {
+		t.Errorf("got %q, want the book move", got)
+	}
+
+	// Positions not in the book are searched.
+	s.send("position startpos moves c2c4")
+	s.send("go depth 1")
+	if got := s.expect("bestmove"); !strings.HasPrefix(got[0], "info depth 1") {
+		t.Errorf("got %q, want a search", got)
+	}
+
+	s.send("setoption name BookFile value <empty>")
+	s.send("position startpos")
+	s.send("go depth 1")
+	if got := s.expect("bestmove"); !strings.HasPrefix(got[0], "info depth 1") {
+		t.Errorf("got %q, want a search without the book", got)
+	}
+
+	s.send("setoption name BookFile value " + filepath.Join(t.TempDir(), "missing.bin"))
+	if got := s.expect("info string"); !strings.Contains(got[0], "missing.bin") {
+		t.Errorf("got %q, want an error", got[0])
+	}
+}
+
+func TestRun_SyzygyPath(t *testing.T) {
+	s := newSession(t)
+	s.send("setoption name SyzygyProbeDepth value 0")
+	if got := s.expect("info string"); got[0] != "info string invalid value for SyzygyProbeDepth: 0" {
+		t.Errorf("got %q, want an error", got[0])
+	}
+
+	s.send("setoption name SyzygyPath value " + filepath.Join(t.TempDir(), "missing"))
+	if got := s.expect("info string"); !strings.Contains(got[0], "missing") {
+		t.Errorf("got %q, want an error", got[0])
+	}
+
+	// The official Syzygy tables aren't always in the tablebase testdata.
+	dir := filepath.Join("..", "tablebase", "testdata")
+	if _, err := os.Stat(filepath.Join(dir, "KNvK.rtbw")); err != nil {
+		t.Skipf("missing Syzygy table: %v", err)
+	}
+	s.send("setoption name SyzygyPath value " + dir)
+	if got := s.expect("info string"); got[0] != "info string found tablebases with up to 3 pieces" {
+		t.Errorf("got %q, want the number of pieces", got[0])
+	}
+
+	s.send("position fen 8/8/8/8/8/2k5/8/K5N1 w - - 0 1")
+	s.send("go depth 1")
+	if got := s.expect("bestmove"); !strings.Contains(got[0], " tbhits 1 ") {
+		t.Errorf("got %q, want a tablebase hit", got)
+	}
+}
+
+func TestFormatScore(t *testing.T) {
+	cases := []struct {
+		in   int
+		want string
+	}{
+		{0, "cp 0"},
+		{-35, "cp -35"},
+		{search.Mate - 1, "mate 1"},
+		{search.Mate - 5, "mate 3"},
+		{-search.Mate + 4, "mate -2"},
+	}
+
+	for _, tc := range cases {
+		if got := formatScore(tc.in); got != tc.want {
+			t.Errorf("formatScore(%d) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestRun_LimitStrength(t *testing.T) {
+	for _, opts := range [][]string{
+		{"setoption name Skill Level value 1"},
+		{"setoption name UCI_LimitStrength value true", "setoption name UCI_Elo value 1200"},
+	} {
+		s := newSession(t)
+		for _, cmd := range opts {
+			s.send(cmd)
+		}
+		s.send("go")
+		got := s.expect("bestmove")
+		if last := got[len(got)-1]; !strings.HasPrefix(last, "bestmove ") || last == "bestmove 0000" {
+			t.Errorf("%q: got %q, want a best move", opts, last)
+		}
+	}
+}
+
+func TestEloSkill(t *testing.T) {
+	cases := []struct {
+		in   int
+		want int
+	}{
+		{minElo, 1},
+		{1400, 10},
+		{maxElo, search.MaxSkill - 1},
+	}
+
+	for _, tc := range cases {
+		if got := eloSkill(tc.in); got != tc.want {
+			t.Errorf("eloSkill(%d) = %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestEngine_Position(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "startpos", want: fen.Starting},
+		{in: "startpos moves e2e4", want: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+		{in: "fen 4k3/8/8/8/8/8/8/4K2R w K - 0 1 moves e1g1", want: "4k3/8/8/8/8/8/8/5RK1 b - - 1 1"},
+		{in: "startpos moves e2e5", wantErr: true},
+		{in: "fen 8/8/8 w - - 0 1", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		e := &engine{pos: core.NewPosition()}
+		err := e.position(strings.Fields(tc.in))
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%q: got no error", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: error: %v", tc.in, err)
+			continue
+		}
+		if got := fen.Encode(e.pos); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestEngine_History(t *testing.T) {
+	e := &engine{pos: core.NewPosition()}
+	if err := e.position(strings.Fields("startpos moves e2e4 e7e5")); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, p := range e.history() {
+		got = append(got, fen.Encode(p))
+	}
+	want := []string{
+		fen.Starting,
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("history mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLimits_Budget(t *testing.T) {
+	cases := []struct {
+		in    string
+		color core.Color
+		want  time.Duration
+	}{
+		{"infinite", core.White, 0},
+		{"depth 5", core.White, 0},
+		{"mate 3", core.White, 0},
+		{"movetime 500", core.Black, 500 * time.Millisecond},
+		{"wtime 60000 btime 30000", core.White, 2 * time.Second},
+		{"wtime 60000 btime 30000", core.Black, time.Second},
+		{"wtime 60000 winc 1000 movestogo 10", core.White, 6500 * time.Millisecond},
+		{"wtime 1000 movestogo 1", core.White, 500 * time.Millisecond},
+	}
+
+	for _, tc := range cases {
+		l := parseLimits(strings.Fields(tc.in))
+		if got := l.budget(tc.color); got != tc.want {
+			t.Errorf("%q for %s: got %v, want %v", tc.in, tc.color, got, tc.want)
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	cases := []struct {
+		in   string
+		want limits
+	}{
+		{"", limits{}},
+		{"depth 5 nodes 1000", limits{depth: 5, nodes: 1000}},
+		{
+			"searchmoves e2e4 d2d4 wtime 1000",
+			limits{searchMoves: []core.Move{pcn.MustDecode("e2e4"), pcn.MustDecode("d2d4")}, wtime: time.Second},
+		},
+		{"infinite searchmoves g1f3", limits{infinite: true, searchMoves: []core.Move{pcn.MustDecode("g1f3")}}},
+		{"searchmoves", limits{}},
+		// Unknown limits and invalid values are skipped.
+		{"bogus 5 depth 3", limits{depth: 3}},
+		{"bogus depth 3", limits{depth: 3}},
+		{"depth x movetime 100", limits{moveTime: 100 * time.Millisecond}},
+		{"depth", limits{}},
+		{"ponder nodes", limits{ponder: true}},
+	}
+
+	for _, tc := range cases {
+		got := parseLimits(strings.Fields(tc.in))
+		if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(limits{})); diff != "" {
+			t.Errorf("%q: mismatch (-want +got):\n%s", tc.in, diff)
+		}
+	}
+}
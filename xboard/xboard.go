@@ -0,0 +1,566 @@
+// Package xboard implements the engine side of the Chess Engine Communication
+// Protocol (CECP), version 2, used by XBoard, WinBoard and many chess servers.
+//
+// Moves are in pure coordinate notation, except that castling in Chess960 is
+// "O-O" or "O-O-O". Mate scores in thinking output are 100000 plus the number
+// of moves to mate, or negated when getting mated.
+package xboard
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/encoding/fen"
+	"github.com/clfs/simple/encoding/pcn"
+	"github.com/clfs/simple/movegen"
+	"github.com/clfs/simple/search"
+)
+
+// Name is the engine name reported to the interface.
+const Name = "simple"
+
+// Variants, by their CECP names.
+var variants = []struct {
+	name     string
+	variant  core.Variant
+	chess960 bool
+	start    string // The starting FEN, if it isn't the standard one.
+}{
+	{"normal", core.Standard, false, ""},
+	{"fischerandom", core.Standard, true, ""},
+	{"kingofthehill", core.KingOfTheHill, false, ""},
+	{"3check", core.ThreeCheck, false, ""},
+	{"crazyhouse", core.Crazyhouse, false, ""},
+	{"atomic", core.Atomic, false, ""},
+	{"giveaway", core.Antichess, false, ""},
+	{"horde", core.Horde, false, fen.HordeStarting},
+	{"racingkings", core.RacingKings, false, fen.RacingKingsStarting},
+}
+
+// mateScore is the thinking output score of a mate in zero moves.
+const mateScore = 100000
+
+// A level is a time control, set by the "level" command.
+type level struct {
+	moves int           // Moves per time control, or zero for the whole game.
+	base  time.Duration // Time per time control.
+	inc   time.Duration // Time added after each move.
+}
+
+// defaultLevel is the time control until the interface sets one, which is
+// also the default of XBoard.
+var defaultLevel = level{moves: 40, base: 5 * time.Minute}
+
+// defaultMovesToGo is the assumed number of moves left in the game when the
+// time control is for the whole game.
+const defaultMovesToGo = 30
+
+// An engine holds the state of a CECP session.
+type engine struct {
+	mu sync.Mutex // Guards w, and the game while searching.
+	w  io.Writer
+
+	variant  int           // Index into variants.
+	start    core.Position // Position before moves.
+	moves    []core.Move   // Moves played from start.
+	pos      core.Position // Position after moves.
+	side     core.Color    // Side the engine plays.
+	force    bool          // Whether the engine plays neither side.
+	analyze  bool          // Whether the engine is in analyze mode.
+	post     bool          // Whether to show thinking output.
+	level    level
+	clock    time.Duration // Time left on the engine's clock.
+	moveTime time.Duration // Exact time per move, or zero.
+	depth    int           // Maximum search depth, or zero.
+
+	cancel  context.CancelFunc // Stops the current search, if any.
+	done    chan struct{}      // Closed when the current search finishes.
+	aborted bool               // Whether the current search must not move.
+}
+
+// Run reads CECP commands from r and writes responses to w, until it reads
+// the "quit" command or reaches the end of r.
+func Run(r io.Reader, w io.Writer) error {
+	e := &engine{w: w, level: defaultLevel}
+	e.newGame()
+	defer e.abort()
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if !e.handle(s.Text()) {
+			return nil
+		}
+	}
+	return s.Err()
+}
+
+// printf writes a line of output.
+func (e *engine) printf(format string, a ...any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprintf(e.w, format+"\n", a...)
+}
+
+// newGame handles the "new" command, which also sets the variant back to
+// normal chess.
+func (e *engine) newGame() {
+	e.variant = 0
+	e.reset(core.NewPosition())
+	e.side, e.force = core.Black, false
+	e.clock, e.depth = e.level.base, 0
+}
+
+// reset starts the game over from a position.
+func (e *engine) reset(p core.Position) {
+	e.start, e.moves, e.pos = p, nil, p
+}
+
+// handle handles a single command. It returns false if the session is over.
+func (e *engine) handle(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "xboard", "accepted", "rejected", "random", "hard", "easy", "computer",
+		"name", "rating", "ics", "draw", "hint", "bk", ".", "otim":
+		// These don't affect the engine. Only its own clock is used for time
+		// management, so the opponent's is ignored.
+	case "protover":
+		var names []string
+		for _, v := range variants {
+			names = append(names, v.name)
+		}
+		e.printf("feature done=0")
+		e.printf(`feature myname="%s" ping=1 setboard=1 usermove=1 playother=1 time=1 analyze=1 `+
+			`colors=0 san=0 draw=0 sigint=0 sigterm=0 reuse=1 variants="%s"`, Name, strings.Join(names, ","))
+		e.printf("feature done=1")
+	case "new":
+		e.abort()
+		e.newGame()
+	case "variant":
+		e.abort()
+		if err := e.setVariant(args); err != nil {
+			e.printf("Error (%v): %s", err, line)
+		}
+	case "force":
+		e.abort()
+		e.force = true
+	case "go":
+		e.abort()
+		e.force, e.side = false, e.pos.SideToMove
+		e.think()
+	case "playother":
+		e.abort()
+		e.force, e.side = false, e.pos.SideToMove.Other()
+	case "usermove":
+		e.abort()
+		if len(args) != 1 {
+			e.printf("Error (missing move): %s", line)
+			break
+		}
+		if !e.userMove(args[0]) {
+			e.printf("Illegal move: %s", args[0])
+			break
+		}
+		if e.analyze || (!e.force && e.pos.SideToMove == e.side) {
+			e.think()
+		}
+	case "?":
+		if !e.analyze {
+			e.moveNow()
+		}
+	case "time":
+		if n, ok := e.centiseconds(line, args); ok {
+			e.clock = n
+		}
+	case "level":
+		if err := e.setLevel(args); err != nil {
+			e.printf("Error (%v): %s", err, line)
+		}
+	case "st":
+		n, err := strconv.Atoi(strings.Join(args, ""))
+		if err != nil || n < 1 {
+			e.printf("Error (invalid time): %s", line)
+			break
+		}
+		e.moveTime = time.Duration(n) * time.Second
+	case "sd":
+		n, err := strconv.Atoi(strings.Join(args, ""))
+		if err != nil || n < 1 {
+			e.printf("Error (invalid depth): %s", line)
+			break
+		}
+		e.depth = n
+	case "post":
+		e.post = true
+	case "nopost":
+		e.post = false
+	case "analyze":
+		e.abort()
+		e.analyze = true
+		e.think()
+	case "exit":
+		e.abort()
+		e.analyze = false
+	case "undo", "remove":
+		e.abort()
+		n := 1
+		if cmd == "remove" {
+			n = 2
+		}
+		if err := e.takeBack(n); err != nil {
+			e.printf("Error (%v): %s", err, line)
+		}
+		if e.analyze {
+			e.think()
+		}
+	case "setboard":
+		e.abort()
+		if err := e.setBoard(strings.Join(args, " ")); err != nil {
+			e.printf("Error (%v): %s", err, line)
+		}
+		if e.analyze {
+			e.think()
+		}
+	case "result":
+		// The game is over, so don't move until a new one starts.
+		e.abort()
+		e.force = true
+	case "ping":
+		e.printf("pong %s", strings.Join(args, " "))
+	case "quit":
+		return false
+	default:
+		e.printf("Error (unknown command): %s", cmd)
+	}
+
+	return true
+}
+
+// setVariant handles the "variant" command.
+func (e *engine) setVariant(args []string) error {
+	name := strings.Join(args, " ")
+	for i, v := range variants {
+		if v.name == name {
+			e.variant = i
+			e.reset(e.startingPosition())
+			return nil
+		}
+	}
+	return errors.New("unsupported variant")
+}
+
+// startingPosition returns the starting position of the variant being played.
+func (e *engine) startingPosition() core.Position {
+	v := variants[e.variant]
+	p := core.NewPosition()
+	if v.start != "" {
+		p = fen.MustDecode(v.start)
+	}
+	if v.chess960 {
+		p.SetChess960()
+	}
+	p.Variant = v.variant
+	return p
+}
+
+// setBoard handles the "setboard" command.
+func (e *engine) setBoard(s string) error {
+	p, err := fen.Decode(s)
+	if err != nil {
+		return errors.New("illegal position")
+	}
+	v := variants[e.variant]
+	if v.chess960 {
+		p.SetChess960()
+	}
+	if v.variant != core.Standard {
+		p.Variant = v.variant
+	}
+	// Validity depends on the variant, like how many kings there are.
+	if err := p.Validate(); err != nil {
+		return errors.New("illegal position")
+	}
+	e.reset(p)
+	return nil
+}
+
+// userMove plays a move by the opponent, or any move in force or analyze
+// mode. It returns false if the move is illegal.
+func (e *engine) userMove(s string) bool {
+	m, ok := decodeMove(e.pos, s)
+	if !ok || !slices.Contains(movegen.LegalMoves(e.pos), m) {
+		return false
+	}
+	if result, over := e.play(m); over {
+		e.printf("%s", result)
+	}
+	return true
+}
+
+// play plays a move. If it ends a game that isn't being analyzed, it returns
+// the result to report.
+func (e *engine) play(m core.Move) (string, bool) {
+	e.pos.Make(m)
+	e.moves = append(e.moves, m)
+	if e.analyze {
+		return "", false
+	}
+	o, over := movegen.GameOver(e.pos)
+	return formatResult(e.pos.SideToMove, o), over
+}
+
+// takeBack handles the "undo" and "remove" commands.
+func (e *engine) takeBack(n int) error {
+	if n > len(e.moves) {
+		return errors.New("no moves to undo")
+	}
+	moves := e.moves[:len(e.moves)-n]
+	e.pos = e.start
+	for _, m := range moves {
+		e.pos.Make(m)
+	}
+	e.moves = moves
+	return nil
+}
+
+// centiseconds parses the argument of the "time" command.
+func (e *engine) centiseconds(line string, args []string) (time.Duration, bool) {
+	n, err := strconv.Atoi(strings.Join(args, ""))
+	if err != nil || n < 0 {
+		e.printf("Error (invalid time): %s", line)
+		return 0, false
+	}
+	return time.Duration(n) * 10 * time.Millisecond, true
+}
+
+// setLevel handles the "level" command.
+func (e *engine) setLevel(args []string) error {
+	// level MPS BASE INC, where BASE is in minutes, or minutes and seconds
+	// like 0:30, and INC is in seconds.
+	if len(args) != 3 {
+		return errors.New("invalid time control")
+	}
+	moves, err := strconv.Atoi(args[0])
+	if err != nil || moves < 0 {
+		return errors.New("invalid time control")
+	}
+	minutes, seconds, _ := strings.Cut(args[1], ":")
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 {
+		return errors.New("invalid time control")
+	}
+	base := time.Duration(m) * time.Minute
+	if seconds != "" {
+		s, err := strconv.Atoi(seconds)
+		if err != nil || s < 0 || s > 59 {
+			return errors.New("invalid time control")
+		}
+		base += time.Duration(s) * time.Second
+	}
+	inc, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || inc < 0 {
+		return errors.New("invalid time control")
+	}
+	e.level = level{moves: moves, base: base, inc: time.Duration(inc * float64(time.Second))}
+	e.clock, e.moveTime = base, 0
+	return nil
+}
+
+// budget returns how long to think about a move for.
+func (e *engine) budget() time.Duration {
+	if e.moveTime > 0 {
+		return e.moveTime
+	}
+	if e.clock <= 0 {
+		// Out of time, so move as soon as possible.
+		return time.Millisecond
+	}
+	movesToGo := defaultMovesToGo
+	if e.level.moves > 0 {
+		movesToGo = e.level.moves - (e.pos.FullMoveNumber-1)%e.level.moves
+	}
+	return min(e.clock/time.Duration(movesToGo)+e.level.inc/2, e.clock/2)
+}
+
+// think starts a search of the current position, which plays a move when it
+// finishes unless in analyze mode, where it runs until stopped.
+func (e *engine) think() {
+	opts := search.Options{
+		Depth: e.depth,
+		Seed:  uint64(time.Now().UnixNano()),
+	}
+	if !e.analyze {
+		opts.MoveTime = e.budget()
+	}
+
+	var positions []core.Position
+	p := e.start
+	for _, m := range e.moves {
+		positions = append(positions, p)
+		p.Make(m)
+	}
+	opts.History = positions
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel, e.done, e.aborted = cancel, make(chan struct{}), false
+
+	go e.search(ctx, e.pos, opts, e.analyze, e.analyze || e.post, e.done)
+}
+
+// abort stops the current search, if any, without playing a move.
+func (e *engine) abort() {
+	if e.cancel == nil {
+		return
+	}
+	e.mu.Lock()
+	e.aborted = true
+	e.mu.Unlock()
+	e.moveNow()
+}
+
+// moveNow stops the current search, if any, which plays the best move found
+// unless aborted or in analyze mode, and waits for it to finish.
+func (e *engine) moveNow() {
+	if e.cancel == nil {
+		return
+	}
+	e.cancel()
+	<-e.done
+	e.cancel, e.done = nil, nil
+}
+
+// search searches p, shows thinking output if post is true, and plays the best
+// move unless analyze is true.
+func (e *engine) search(ctx context.Context, p core.Position, opts search.Options, analyze, post bool, done chan<- struct{}) {
+	defer close(done)
+
+	var (
+		info = make(chan search.Info)
+		errc = make(chan error, 1)
+		best search.Info
+	)
+
+	go func() {
+		errc <- search.Run(ctx, p, opts, info)
+	}()
+
+	// Fall back to any legal move if the search stops before finishing an
+	// iteration.
+	if moves := movegen.LegalMoves(p); len(moves) > 0 {
+		best.Move = moves[0]
+	}
+
+	for {
+		select {
+		case i := <-info:
+			if i.Rank != 1 {
+				continue
+			}
+			best = i
+			if post {
+				e.printf("%s", formatThinking(p, i))
+			}
+		case err := <-errc:
+			// Analysis runs until it's stopped.
+			if err == nil && analyze {
+				<-ctx.Done()
+			}
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			if analyze || e.aborted || errors.Is(err, search.ErrNoLegalMoves) {
+				return
+			}
+			fmt.Fprintf(e.w, "move %s\n", encodeMove(p, best.Move))
+			if result, over := e.play(best.Move); over {
+				fmt.Fprintln(e.w, result)
+			}
+			return
+		}
+	}
+}
+
+// formatThinking formats search information as thinking output.
+func formatThinking(p core.Position, i search.Info) string {
+	score := i.Score
+	if n, ok := search.MateIn(i.Score); ok {
+		score = mateScore + n
+		if n < 0 {
+			score = -mateScore + n
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d %d %d %d", i.Depth, score, i.Time.Milliseconds()/10, i.Nodes)
+	for _, m := range i.PV {
+		b.WriteByte(' ')
+		b.WriteString(encodeMove(p, m))
+		p.Make(m)
+	}
+	return b.String()
+}
+
+// formatResult formats the result of a game that's over, given the outcome
+// for the side to move.
+func formatResult(c core.Color, o movegen.Outcome) string {
+	switch {
+	case o == movegen.Draw:
+		return "1/2-1/2 {Draw}"
+	case (o == movegen.Win) == (c == core.White):
+		return "1-0 {White wins}"
+	default:
+		return "0-1 {Black wins}"
+	}
+}
+
+// isCastle returns true if m is a castling move in a Chess960 position, where
+// the king takes its own rook.
+func isCastle(p core.Position, m core.Move) bool {
+	if !p.Chess960 || m.Drop {
+		return false
+	}
+	king, _ := p.Board.Get(m.From)
+	rook, _ := p.Board.Get(m.To)
+	return king == core.NewPiece(p.SideToMove, core.King) && rook == core.NewPiece(p.SideToMove, core.Rook)
+}
+
+// encodeMove encodes a move, with Chess960 castling as "O-O" or "O-O-O".
+func encodeMove(p core.Position, m core.Move) string {
+	if isCastle(p, m) {
+		if m.To.File() > m.From.File() {
+			return "O-O"
+		}
+		return "O-O-O"
+	}
+	return pcn.Encode(m)
+}
+
+// decodeMove decodes a move, accepting Chess960 castling as "O-O" or "O-O-O".
+// It returns false if the move is invalid, or if it's castling and there's no
+// legal castling move.
+func decodeMove(p core.Position, s string) (core.Move, bool) {
+	kingside := s == "O-O" || s == "0-0"
+	if !kingside && s != "O-O-O" && s != "0-0-0" {
+		m, err := pcn.Decode(s)
+		return m, err == nil
+	}
+	for _, m := range movegen.LegalMoves(p) {
+		if isCastle(p, m) && (m.To.File() > m.From.File()) == kingside {
+			return m, true
+		}
+	}
+	return core.Move{}, false
+}
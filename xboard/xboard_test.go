@@ -0,0 +1,291 @@
+package xboard
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clfs/simple/core"
+	"github.com/clfs/simple/search"
+	"github.com/google/go-cmp/cmp"
+)
+
+// A session is a CECP session for testing. Commands are sent from their own
+// goroutine, so the engine never blocks on output while a test is sending.
+type session struct {
+	t    *testing.T
+	in   chan string
+	out  *bufio.Scanner
+	errc chan error
+}
+
+func newSession(t *testing.T) *session {
+	t.Helper()
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	s := &session{
+		t:    t,
+		in:   make(chan string, 100),
+		out:  bufio.NewScanner(outR),
+		errc: make(chan error, 1),
+	}
+
+	go func() {
+		for cmd := range s.in {
+			io.WriteString(inW, cmd+"\n")
+		}
+		inW.Close()
+	}()
+
+	go func() {
+		s.errc <- Run(inR, outW)
+		outW.Close()
+	}()
+
+	t.Cleanup(s.close)
+
+	return s
+}
+
+// send sends a command.
+func (s *session) send(cmd string) {
+	s.in <- cmd
+}
+
+// expect reads lines until one starts with prefix, and returns all lines read.
+func (s *session) expect(prefix string) []string {
+	s.t.Helper()
+	var lines []string
+	for s.out.Scan() {
+		lines = append(lines, s.out.Text())
+		if strings.HasPrefix(s.out.Text(), prefix) {
+			return lines
+		}
+	}
+	s.t.Fatalf("no line starting with %q in %q", prefix, lines)
+	return nil
+}
+
+// close ends the session and checks that Run returned without error.
+func (s *session) close() {
+	close(s.in)
+	go func() {
+		for s.out.Scan() {
+		}
+	}()
+	if err := <-s.errc; err != nil {
+		s.t.Errorf("Run() error: %v", err)
+	}
+}
+
+func TestRun_Protover(t *testing.T) {
+	s := newSession(t)
+	s.send("xboard")
+	s.send("protover 2")
+	got := s.expect("feature done=1")
+	want := []string{
+		"feature done=0",
+		`feature myname="simple" ping=1 setboard=1 usermove=1 playother=1 time=1 analyze=1 colors=0 san=0 draw=0 sigint=0 sigterm=0 reuse=1 variants="normal,fischerandom,kingofthehill,3check,crazyhouse,atomic,giveaway,horde,racingkings"`,
+		"feature done=1",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	s.send("ping 7")
+	s.expect("pong 7")
+}
+
+func TestRun_Game(t *testing.T) {
+	s := newSession(t)
+	s.send("new")
+	s.send("sd 2")
+	s.send("post")
+	s.send("usermove e2e4")
+	got := s.expect("move ")
+	if !strings.HasPrefix(got[0], "1 ") {
+		t.Errorf("got %q, want thinking output", got[0])
+	}
+	reply := strings.TrimPrefix(got[len(got)-1], "move ")
+
+	// The engine's reply is part of the game, so it can be taken back.
+	s.send("force")
+	s.send("remove")
+	s.send("usermove e2e4")
+	s.send("usermove " + reply)
+	s.send("ping 1")
+	if got := s.expect("pong"); len(got) != 1 {
+		t.Errorf("got %q, want only pong", got)
+	}
+}
+
+func TestRun_Mate(t *testing.T) {
+	s := newSession(t)
+	s.send("setboard 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1")
+	s.send("sd 3")
+	s.send("post")
+	s.send("go")
+	got := s.expect("1-0")
+	want := []string{"move a1a8", "1-0 {White wins}"}
+	if diff := cmp.Diff(want, got[len(got)-2:]); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if score := strings.Fields(got[0])[1]; score != "100001" {
+		t.Errorf("got score %s, want 100001", score)
+	}
+}
+
+func TestRun_PlayOther(t *testing.T) {
+	s := newSession(t)
+	s.send("new")
+	s.send("force")
+	s.send("usermove e2e4")
+	s.send("playother")
+	s.send("sd 1")
+	s.send("usermove e7e5")
+	// The engine plays white now, and nothing happened until black moved.
+	got := s.expect("move ")
+	if len(got) != 1 {
+		t.Errorf("got %q, want only a move", got)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	cases := []struct {
+		cmd  string
+		want string
+	}{
+		{"usermove e2e5", "Illegal move: e2e5"},
+		{"usermove O-O", "Illegal move: O-O"},
+		{"usermove", "Error (missing move): usermove"},
+		{"setboard 8/8/8/8/8/8/8/8 w - - 0 1", "Error (illegal position): setboard 8/8/8/8/8/8/8/8 w - - 0 1"},
+		{"variant shatranj", "Error (unsupported variant): variant shatranj"},
+		{"undo", "Error (no moves to undo): undo"},
+		{"level 40 x 0", "Error (invalid time control): level 40 x 0"},
+		{"sd 0", "Error (invalid depth): sd 0"},
+		{"foo", "Error (unknown command): foo"},
+	}
+	s := newSession(t)
+	for _, tc := range cases {
+		s.send(tc.cmd)
+		if got := s.expect(""); got[0] != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.cmd, got[0], tc.want)
+		}
+	}
+}
+
+func TestRun_Analyze(t *testing.T) {
+	s := newSession(t)
+	s.send("new")
+	s.send("force")
+	s.send("analyze")
+	s.expect("1 ")
+	s.send("usermove e2e4")
+	s.send("exit")
+	s.send("ping 2")
+	for _, line := range s.expect("pong 2") {
+		if strings.HasPrefix(line, "move") {
+			t.Errorf("got %q in analyze mode", line)
+		}
+	}
+}
+
+func TestRun_Chess960(t *testing.T) {
+	s := newSession(t)
+	s.send("variant fischerandom")
+	s.send("setboard r5k1/5ppp/8/8/8/8/8/1K5R w H - 0 1")
+	s.send("force")
+	s.send("usermove O-O")
+	s.send("setboard 6k1/8/8/8/8/8/8/R3K3 w Q - 0 1")
+	s.send("usermove e1c1")
+	s.send("ping 3")
+	got := s.expect("pong 3")
+	want := []string{"Illegal move: e1c1", "pong 3"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRun_Variant(t *testing.T) {
+	s := newSession(t)
+	s.send("variant horde")
+	s.send("force")
+	// Horde starts from its own position, with white pawns on f5 and d4.
+	s.send("usermove f5f6")
+	s.send("usermove e7e5")
+	s.send("usermove d4e5")
+	s.send("ping 4")
+	if got := s.expect("pong 4"); len(got) != 1 {
+		t.Errorf("got %q, want only pong", got)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	cases := []struct {
+		args []string
+		want level
+	}{
+		{[]string{"40", "5", "0"}, level{moves: 40, base: 5 * time.Minute}},
+		{[]string{"0", "2", "12"}, level{base: 2 * time.Minute, inc: 12 * time.Second}},
+		{[]string{"0", "0:30", "0.5"}, level{base: 30 * time.Second, inc: 500 * time.Millisecond}},
+	}
+	for _, tc := range cases {
+		var e engine
+		if err := e.setLevel(tc.args); err != nil {
+			t.Errorf("%q: error: %v", tc.args, err)
+			continue
+		}
+		if e.level != tc.want {
+			t.Errorf("%q: got %+v, want %+v", tc.args, e.level, tc.want)
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	cases := []struct {
+		level    level
+		clock    time.Duration
+		moveTime time.Duration
+		fmn      int
+		want     time.Duration
+	}{
+		{level{moves: 40}, 40 * time.Second, 0, 1, time.Second},
+		{level{moves: 40}, 3 * time.Second, 0, 38, time.Second},
+		{level{inc: 2 * time.Second}, 30 * time.Second, 0, 1, 2 * time.Second},
+		{level{}, 4 * time.Second, 0, 1, 133333333},
+		{level{}, 0, 0, 1, time.Millisecond},
+		{level{}, time.Minute, 3 * time.Second, 1, 3 * time.Second},
+	}
+	for _, tc := range cases {
+		e := engine{level: tc.level, clock: tc.clock, moveTime: tc.moveTime}
+		e.pos.FullMoveNumber = tc.fmn
+		if got := e.budget(); got != tc.want {
+			t.Errorf("%+v: got %v, want %v", tc, got, tc.want)
+		}
+	}
+}
+
+func TestFormatThinking(t *testing.T) {
+	p := core.NewPosition()
+	cases := []struct {
+		in   search.Info
+		want string
+	}{
+		{
+			search.Info{Depth: 2, Score: 25, Time: 1234 * time.Millisecond, Nodes: 100,
+				PV: []core.Move{{From: core.E2, To: core.E4}, {From: core.E7, To: core.E5}}},
+			"2 25 123 100 e2e4 e7e5",
+		},
+		{search.Info{Depth: 3, Score: search.Mate - 3}, "3 100002 0 0"},
+		{search.Info{Depth: 3, Score: -search.Mate + 2}, "3 -100001 0 0"},
+	}
+	for _, tc := range cases {
+		if got := formatThinking(p, tc.in); got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+}